@@ -96,6 +96,16 @@ var listADSettingsCmd = &cobra.Command{
 			params["next_token"] = nextToken
 		}
 
+		// follow next_token
+		paging, err := GetListPaging(cmd)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if paging.IsRequested() {
+			RunListAllPages("ListADSettings", params, paging, "AD Settings not found")
+		}
+
 		// JSONify
 		jsonParams, err := json.Marshal(params)
 		if err != nil {
@@ -124,7 +134,7 @@ var listADSettingsCmd = &cobra.Command{
 				os.Exit(5)
 			}
 
-			fmt.Println("\n" + retStr + "\n")
+			PrintResponse(cmd, retStr)
 
 			// make a decision on what to exit with
 			retMap := JsonStrToMap(retStr)
@@ -330,6 +340,7 @@ func init() {
 	listADSettingsCmd.Flags().StringP("next-token", "n", "",
 		"Token from which subsequent AD settings would "+
 			"be listed")
+	AddListPagingFlags(listADSettingsCmd)
 
 	// Commmand: update-ad-setting
 	var updateADSettingsCommand = &cobra.Command{
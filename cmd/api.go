@@ -37,6 +37,10 @@ const (
 	ContentTypeJSON = "application/json"
 )
 
+// InsecureBannerOutput is where the insecure request banner is written.
+// Commands printing a Secret value on stdout for piping send it to stderr.
+var InsecureBannerOutput io.Writer = os.Stdout
+
 func GetTLSConfig(caCertPool *x509.CertPool) *tls.Config {
 	return &tls.Config{
 		InsecureSkipVerify: true, // Skip default verification
@@ -66,6 +70,33 @@ func GetTLSConfig(caCertPool *x509.CertPool) *tls.Config {
 	}
 }
 
+// newHTTPClient returns the client for Vault API requests, verifying the
+// Vault certificate against cacert if given
+func newHTTPClient(cacert string) *http.Client {
+	tr := &http.Transport{}
+	if cacert != "" {
+		// Create a CA certificate pool and add cacert to it
+		caCert, err := os.ReadFile(cacert)
+		if err != nil {
+			fmt.Println("Error reading CA Certificate: ", err)
+			os.Exit(1)
+		}
+
+		caCertPool := x509.NewCertPool()
+		caCertPool.AppendCertsFromPEM(caCert)
+		tr.TLSClientConfig = GetTLSConfig(caCertPool)
+	} else {
+		fmt.Fprintln(InsecureBannerOutput, "\n###############################################################################\n" +
+			"Insecure request. Entrust Vault certificate not verified. \n" +
+			"It is strongly recommended to verify the same by specifying CA \n" +
+			"Certificate, using the --cacert option, to mitigate Man-in-the-middle attack\n" +
+			"###############################################################################")
+		tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	return &http.Client{Transport: tr}
+}
+
 // DoPostFormData sends an API request
 func DoPostFormData(endpoint string,
 	cacert string,
@@ -124,29 +155,7 @@ func DoPostFormData(endpoint string,
 		request.Header.Set(header, value)
 	}
 
-	client := &http.Client{}
-	tr := &http.Transport{}
-	if cacert != "" {
-		// Create a CA certificate pool and add cacert to it
-		caCert, err := os.ReadFile(cacert)
-		if err != nil {
-			fmt.Println("Error reading CA Certificate: ", err)
-			os.Exit(1)
-		}
-
-		caCertPool := x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM(caCert)
-		tr.TLSClientConfig = GetTLSConfig(caCertPool)
-	} else {
-		fmt.Println("\n###############################################################################\n" +
-			"Insecure request. Entrust Vault certificate not verified. \n" +
-			"It is strongly recommended to verify the same by specifying CA \n" +
-			"Certificate, using the --cacert option, to mitigate Man-in-the-middle attack\n" +
-			"###############################################################################")
-		tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-	}
-
-	client.Transport = tr
+	client := newHTTPClient(cacert)
 	ret := map[string]interface{}{}
 	response, err := client.Do(request)
 	if err != nil {
@@ -191,29 +200,7 @@ func DoGet(endpoint string,
 		request.Header.Set(header, value)
 	}
 
-	client := &http.Client{}
-	tr := &http.Transport{}
-	if cacert != "" {
-		// Create a CA certificate pool and add cacert to it
-		caCert, err := os.ReadFile(cacert)
-		if err != nil {
-			fmt.Println("Error reading CA Certificate: ", err)
-			os.Exit(1)
-		}
-
-		caCertPool := x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM(caCert)
-		tr.TLSClientConfig = GetTLSConfig(caCertPool)
-	} else {
-		fmt.Println("\n###############################################################################\n" +
-			"Insecure request. Entrust Vault certificate not verified. \n" +
-			"It is strongly recommended to verify the same by specifying CA \n" +
-			"Certificate, using the --cacert option, to mitigate Man-in-the-middle attack\n" +
-			"###############################################################################")
-		tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-	}
-
-	client.Transport = tr
+	client := newHTTPClient(cacert)
 	ret := map[string]interface{}{}
 	response, err := client.Do(request)
 	if err != nil {
@@ -250,29 +237,7 @@ func DoDelete(endpoint string,
 		request.Header.Set(header, value)
 	}
 
-	client := &http.Client{}
-	tr := &http.Transport{}
-	if cacert != "" {
-		// Create a CA certificate pool and add cacert to it
-		caCert, err := os.ReadFile(cacert)
-		if err != nil {
-			fmt.Println("Error reading CA Certificate: ", err)
-			os.Exit(1)
-		}
-
-		caCertPool := x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM(caCert)
-		tr.TLSClientConfig = GetTLSConfig(caCertPool)
-	} else {
-		fmt.Println("\n###############################################################################\n" +
-			"Insecure request. Entrust Vault certificate not verified. \n" +
-			"It is strongly recommended to verify the same by specifying CA \n" +
-			"Certificate, using the --cacert option, to mitigate Man-in-the-middle attack\n" +
-			"###############################################################################")
-		tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-	}
-
-	client.Transport = tr
+	client := newHTTPClient(cacert)
 	ret := map[string]interface{}{}
 	response, err := client.Do(request)
 	if err != nil {
@@ -307,28 +272,7 @@ func DoPatch(endpoint string,
 		request.Header.Set(header, value)
 	}
 
-	client := &http.Client{}
-	tr := &http.Transport{}
-	if cacert != "" {
-		caCert, err := os.ReadFile(cacert)
-		if err != nil {
-			fmt.Println("Error reading CA Certificate: ", err)
-			os.Exit(1)
-		}
-
-		caCertPool := x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM(caCert)
-		tr.TLSClientConfig = GetTLSConfig(caCertPool)
-	} else {
-		fmt.Println("\n###############################################################################\n" +
-			"Insecure request. Entrust Vault certificate not verified. \n" +
-			"It is strongly recommended to verify the same by specifying CA \n" +
-			"Certificate, using the --cacert option, to mitigate Man-in-the-middle attack\n" +
-			"###############################################################################")
-		tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-	}
-
-	client.Transport = tr
+	client := newHTTPClient(cacert)
 	ret := map[string]interface{}{}
 	response, err := client.Do(request)
 	if err != nil {
@@ -363,29 +307,7 @@ func DoPost(endpoint string,
 		request.Header.Set(header, value)
 	}
 
-	client := &http.Client{}
-	tr := &http.Transport{}
-	if cacert != "" {
-		// Create a CA certificate pool and add cacert to it
-		caCert, err := os.ReadFile(cacert)
-		if err != nil {
-			fmt.Println("Error reading CA Certificate: ", err)
-			os.Exit(1)
-		}
-
-		caCertPool := x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM(caCert)
-		tr.TLSClientConfig = GetTLSConfig(caCertPool)
-	} else {
-		fmt.Println("\n###############################################################################\n" +
-			"Insecure request. Entrust Vault certificate not verified. \n" +
-			"It is strongly recommended to verify the same by specifying CA \n" +
-			"Certificate, using the --cacert option, to mitigate Man-in-the-middle attack\n" +
-			"###############################################################################")
-		tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-	}
-
-	client.Transport = tr
+	client := newHTTPClient(cacert)
 	ret := map[string]interface{}{}
 	response, err := client.Do(request)
 	if err != nil {
@@ -407,6 +329,32 @@ func DoPost(endpoint string,
 	}
 }
 
+// DoPostStream sends an API request with the body read from a stream, for
+// payloads too large to hold in memory. A contentLength of -1 means unknown.
+// The response is returned unread, the caller must close its body.
+func DoPostStream(endpoint string,
+	cacert string,
+	headers map[string]string,
+	body io.Reader,
+	contentLength int64,
+	contentType string) (*http.Response, error) {
+	request, err := http.NewRequest("POST", endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	request.ContentLength = contentLength
+
+	// close connection once done
+	request.Close = true
+	request.Header.Set("Content-Type", contentType)
+	for header, value := range headers {
+		request.Header.Set(header, value)
+	}
+
+	client := newHTTPClient(cacert)
+	return client.Do(request)
+}
+
 // APIError contains error details of API request failure
 type APIError struct {
 	RequestURL     string
@@ -460,29 +408,7 @@ func DoPost2(endpoint string,
 		request.Header.Set(header, value)
 	}
 
-	client := &http.Client{}
-	tr := &http.Transport{}
-	if cacert != "" {
-		// Create a CA certificate pool and add cacert to it
-		caCert, err := os.ReadFile(cacert)
-		if err != nil {
-			fmt.Println("Error reading CA Certificate: ", err)
-			os.Exit(1)
-		}
-
-		caCertPool := x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM(caCert)
-		tr.TLSClientConfig = GetTLSConfig(caCertPool)
-	} else {
-		fmt.Println("\n###############################################################################\n" +
-			"Insecure request. Entrust Vault certificate not verified. \n" +
-			"It is strongly recommended to verify the same by specifying CA \n" +
-			"Certificate, using the --cacert option, to mitigate Man-in-the-middle attack\n" +
-			"###############################################################################")
-		tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-	}
-
-	client.Transport = tr
+	client := newHTTPClient(cacert)
 	response, err := client.Do(request)
 	if err != nil {
 		return nil, err
@@ -524,29 +450,7 @@ func DoDownload(endpoint string,
 		request.Header.Set(header, value)
 	}
 
-	client := &http.Client{}
-	tr := &http.Transport{}
-	if cacert != "" {
-		// Create a CA certificate pool and add cacert to it
-		caCert, err := os.ReadFile(cacert)
-		if err != nil {
-			fmt.Println("Error reading CA Certificate: ", err)
-			os.Exit(1)
-		}
-
-		caCertPool := x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM(caCert)
-		tr.TLSClientConfig = GetTLSConfig(caCertPool)
-	} else {
-		fmt.Println("\n###############################################################################\n" +
-			"Insecure request. Entrust Vault certificate not verified. \n" +
-			"It is strongly recommended to verify the same by specifying CA \n" +
-			"Certificate, using the --cacert option, to mitigate Man-in-the-middle attack\n" +
-			"###############################################################################")
-		tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-	}
-
-	client.Transport = tr
+	client := newHTTPClient(cacert)
 	response, err := client.Do(request)
 	if err != nil {
 		return "", err
@@ -592,29 +496,7 @@ func DoGetDownload(endpoint string,
 		request.Header.Set(string(header), value)
 	}
 
-	client := &http.Client{}
-	tr := &http.Transport{}
-	if cacert != "" {
-		// Create a CA certificate pool and add cacert to it
-		caCert, err := os.ReadFile(cacert)
-		if err != nil {
-			fmt.Println("Error reading CA Certificate: ", err)
-			os.Exit(1)
-		}
-
-		caCertPool := x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM(caCert)
-		tr.TLSClientConfig = GetTLSConfig(caCertPool)
-	} else {
-		fmt.Println("\n###############################################################################\n" +
-			"Insecure request. Entrust Vault certificate not verified. \n" +
-			"It is strongly recommended to verify the same by specifying CA \n" +
-			"Certificate, using the --cacert option, to mitigate Man-in-the-middle attack\n" +
-			"###############################################################################")
-		tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-	}
-
-	client.Transport = tr
+	client := newHTTPClient(cacert)
 	response, err := client.Do(request)
 	if err != nil {
 		return "", err
@@ -648,6 +530,30 @@ func DoGetDownload(endpoint string,
 	}
 }
 
+// DoGetRaw sends an API request and returns the response body as is
+func DoGetRaw(endpoint string,
+	cacert string,
+	headers map[string]string) ([]byte, error) {
+	request, _ := http.NewRequest("GET", endpoint, nil)
+	// close connection once done
+	request.Close = true
+
+	for header, value := range headers {
+		request.Header.Set(header, value)
+	}
+
+	client := newHTTPClient(cacert)
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, newAPIError(endpoint, response)
+	}
+	return io.ReadAll(response.Body)
+}
+
 func GetValueFromKVString(kvString string, key string) (string, error) {
 	regex := fmt.Sprintf("(%s)=([a-z]+)", key)
 	re := regexp.MustCompile(regex)
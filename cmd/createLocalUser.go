@@ -82,7 +82,7 @@ var createLocalUserCmd = &cobra.Command{
 		   os.Exit(5)
 	       }
 
-	       fmt.Println("\n" + retStr + "\n")
+	       PrintResponse(cmd, retStr)
 
 	       // make a decision on what to exit with
 	       retMap := JsonStrToMap(retStr)
@@ -71,7 +71,7 @@ var deleteUserCmd = &cobra.Command{
 	    	// make a decision on what to exit with
 	    	retMap := JsonStrToMap(retStr)
 	    	if _, present := retMap["error"]; present {
-				fmt.Println("\n" + retStr + "\n")
+				PrintResponse(cmd, retStr)
 				os.Exit(3)
 	    	} else {
 				fmt.Println("Local user successfully deleted for with username/ID", user, "\n")
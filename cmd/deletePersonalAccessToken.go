@@ -61,10 +61,10 @@ var deletePersonalAccessTokenCmd = &cobra.Command{
 
 			retMap := JsonStrToMap(retStr)
 			if _, present := retMap["error"]; present {
-				fmt.Println("\n" + retStr + "\n")
+				PrintResponse(cmd, retStr)
 				os.Exit(3)
 			} else {
-				fmt.Println("\n" + retStr + "\n")
+				PrintResponse(cmd, retStr)
 				os.Exit(0)
 			}
 		}
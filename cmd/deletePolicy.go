@@ -71,7 +71,7 @@ var deletePolicyCmd = &cobra.Command{
 				fmt.Println("\nPolicy deleted successfully\n")
 				os.Exit(0)
 			} else {
-				fmt.Println("\n" + retStr + "\n")
+				PrintResponse(cmd, retStr)
 
 				// make a decision on what to exit with
 				retMap := JsonStrToMap(retStr)
@@ -67,7 +67,7 @@ var getADUserCmd = &cobra.Command{
                 os.Exit(5)
             }
 
-            fmt.Println("\n" + retStr + "\n")
+            PrintResponse(cmd, retStr)
 
             // make a decision on what to exit with
             retMap := JsonStrToMap(retStr)
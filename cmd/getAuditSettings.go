@@ -62,7 +62,7 @@ var getAuditSettingsCmd = &cobra.Command{
                 os.Exit(5)
             }
 
-            fmt.Println("\n" + retStr + "\n")
+            PrintResponse(cmd, retStr)
 
             // make a decision on what to exit with
             retMap := JsonStrToMap(retStr)
@@ -70,10 +70,10 @@ var getLocalUserCmd = &cobra.Command{
 	    	// make a decision on what to exit with
 	    	retMap := JsonStrToMap(retStr)
 	    	if _, present := retMap["error"]; present {
-				fmt.Println("\n" + retStr + "\n")
+				PrintResponse(cmd, retStr)
 				os.Exit(3)
 	    	} else {
-				fmt.Println("\n" + retStr + "\n")
+				PrintResponse(cmd, retStr)
 				os.Exit(0)
 	    	}
         }
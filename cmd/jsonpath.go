@@ -0,0 +1,151 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonPathWildcard is the [*] step, matching every element of an array or
+// every value of an object
+type jsonPathWildcard struct{}
+
+// ParseJSONPath parses the subset of JSONPath pasmcli supports: $.a.b,
+// .a.b[0], .a["key.with.dots"] and .items[*].name. Surrounding braces, as in
+// {.items[*].name}, are accepted. Returns string keys, int indexes and
+// wildcards.
+func ParseJSONPath(path string) ([]interface{}, error) {
+	invalid := func(reason string) ([]interface{}, error) {
+		return nil, fmt.Errorf("Invalid path %q - %s", path, reason)
+	}
+
+	rest := strings.TrimSpace(path)
+	if strings.HasPrefix(rest, "{") && strings.HasSuffix(rest, "}") {
+		rest = strings.TrimSpace(rest[1 : len(rest)-1])
+	}
+	rest = strings.TrimPrefix(rest, "$")
+	if rest == "" || rest == "." {
+		// the whole document
+		return []interface{}{}, nil
+	}
+	if rest[0] != '.' && rest[0] != '[' {
+		rest = "." + rest
+	}
+
+	steps := []interface{}{}
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return invalid("empty key")
+			}
+			if rest[:end] == "*" {
+				steps = append(steps, jsonPathWildcard{})
+			} else {
+				steps = append(steps, rest[:end])
+			}
+			rest = rest[end:]
+		case '[':
+			// a quoted key may itself contain ] or .
+			if len(rest) > 1 && (rest[1] == '"' || rest[1] == '\'') {
+				quote := rest[1]
+				closing := strings.IndexByte(rest[2:], quote)
+				if closing == -1 || !strings.HasPrefix(rest[2+closing+1:], "]") {
+					return invalid("unterminated quoted key")
+				}
+				steps = append(steps, rest[2:2+closing])
+				rest = rest[2+closing+2:]
+				continue
+			}
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				return invalid("missing ]")
+			}
+			inside := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			if inside == "*" {
+				steps = append(steps, jsonPathWildcard{})
+				continue
+			}
+			index, err := strconv.Atoi(inside)
+			if err != nil || index < 0 {
+				return invalid(fmt.Sprintf("bad index [%s]", inside))
+			}
+			steps = append(steps, index)
+		default:
+			return invalid(fmt.Sprintf("unexpected %q", rest[0]))
+		}
+	}
+	return steps, nil
+}
+
+// LookupJSONPath returns every value the path leads to in a decoded JSON
+// document, none if the path doesn't exist
+func LookupJSONPath(value interface{}, steps []interface{}) []interface{} {
+	if len(steps) == 0 {
+		return []interface{}{value}
+	}
+
+	matches := []interface{}{}
+	switch step := steps[0].(type) {
+	case string:
+		if object, isObject := value.(map[string]interface{}); isObject {
+			if next, present := object[step]; present {
+				matches = LookupJSONPath(next, steps[1:])
+			}
+		}
+	case int:
+		if array, isArray := value.([]interface{}); isArray && step < len(array) {
+			matches = LookupJSONPath(array[step], steps[1:])
+		}
+	case jsonPathWildcard:
+		switch container := value.(type) {
+		case []interface{}:
+			for _, next := range container {
+				matches = append(matches, LookupJSONPath(next, steps[1:])...)
+			}
+		case map[string]interface{}:
+			keys := make([]string, 0, len(container))
+			for key := range container {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				matches = append(matches, LookupJSONPath(container[key], steps[1:])...)
+			}
+		}
+	}
+	return matches
+}
+
+// HasJSONPathWildcard tells if the path can match more than one value
+func HasJSONPathWildcard(steps []interface{}) bool {
+	for _, step := range steps {
+		if _, isWildcard := step.(jsonPathWildcard); isWildcard {
+			return true
+		}
+	}
+	return false
+}
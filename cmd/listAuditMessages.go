@@ -50,6 +50,15 @@ type listAuditMessagesResponse struct {
 func printAuditMessages(data []byte, cmd *cobra.Command) {
 	flags := cmd.Flags()
 
+	// --output formats
+	if format := GetOutputFormat(cmd); format != "" {
+		if err := PrintFormatted(os.Stdout, format, "", string(data)); err != nil {
+			fmt.Printf("\nError formatting output - %v\n", err)
+			os.Exit(4)
+		}
+		return
+	}
+
 	// JSON output
 	if ok, _ := flags.GetBool(listAuditOptionJSONOutput); ok {
 		dst := &bytes.Buffer{}
@@ -138,6 +147,35 @@ func listAuditMessageAPI(cmd *cobra.Command, args []string) {
 		params["next_token"] = nextToken
 	}
 
+	// follow next_token
+	paging, err := GetListPaging(cmd)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if paging.IsRequested() {
+		if paging.NDJSON {
+			RunListAllPages("ListAuditMessages", params, paging, "Audit messages not found")
+		}
+		messages := []interface{}{}
+		_, nextToken, err := ListAllPages("ListAuditMessages", params, paging,
+			func(item interface{}) error {
+				messages = append(messages, item)
+				return nil
+			})
+		ExitOnListError(err, "Audit messages not found")
+		data, err := json.Marshal(map[string]interface{}{"audit_messages": messages})
+		if err != nil {
+			fmt.Println("Error building JSON output: ", err)
+			os.Exit(4)
+		}
+		printAuditMessages(data, cmd)
+		if nextToken != "" {
+			fmt.Fprintf(os.Stderr, "More items available, continue with --next-token %s\n", nextToken)
+		}
+		os.Exit(0)
+	}
+
 	// JSONify
 	jsonParams, err := json.Marshal(params)
 	if err != nil {
@@ -190,8 +228,11 @@ func init() {
 	listAuditMessagesCmd.Flags().StringP("next-token", "n", "",
 		"Token from which subsequent Audit "+
 			"messages would be listed")
+	AddListPagingFlags(listAuditMessagesCmd)
 	listAuditMessagesCmd.Flags().BoolP(listAuditOptionJSONOutput, "j", false,
 		"Show JSON formatted audit messages")
+	listAuditMessagesCmd.Flags().MarkDeprecated(listAuditOptionJSONOutput,
+		"use -o json")
 	listAuditMessagesCmd.Flags().BoolP(listAuditOptionIncludeInfo, "i", false,
 		"Show Additional Information if available")
 	listAuditMessagesCmd.Flags().BoolP(listAuditOptionLocalTime, "t", false,
@@ -62,6 +62,16 @@ var listLocalUsersCmd = &cobra.Command{
             params["next_token"] = nextToken
         }
 
+        // follow next_token
+        paging, err := GetListPaging(cmd)
+        if err != nil {
+            fmt.Println(err)
+            os.Exit(1)
+        }
+        if paging.IsRequested() {
+            RunListAllPages("ListLocalUsers", params, paging, "Action denied")
+        }
+
         // JSONify
         jsonParams, err := json.Marshal(params)
         if (err != nil) {
@@ -93,10 +103,10 @@ var listLocalUsersCmd = &cobra.Command{
 	    	// make a decision on what to exit with
 	    	retMap := JsonStrToMap(retStr)
 	    	if _, present := retMap["error"]; present {
-				fmt.Println("\n" + retStr + "\n")
+				PrintResponse(cmd, retStr)
 				os.Exit(3)
 	    	} else {
-				fmt.Println("\n" + retStr + "\n")
+				PrintResponse(cmd, retStr)
 				os.Exit(0)
 	    	}
         }
@@ -118,4 +128,5 @@ func init() {
 	listLocalUsersCmd.Flags().StringP("next-token", "n", "",
 								"Token from which subsequent Users would " +
 								"be listed")
+	AddListPagingFlags(listLocalUsersCmd)
 }
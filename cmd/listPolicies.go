@@ -61,6 +61,16 @@ var listPoliciesCmd = &cobra.Command{
             params["next_token"] = nextToken
         }
 
+        // follow next_token
+        paging, err := GetListPaging(cmd)
+        if err != nil {
+            fmt.Println(err)
+            os.Exit(1)
+        }
+        if paging.IsRequested() {
+            RunListAllPages("ListPolicies", params, paging, "Policies not found")
+        }
+
         // JSONify
         jsonParams, err := json.Marshal(params)
         if (err != nil) {
@@ -89,7 +99,7 @@ var listPoliciesCmd = &cobra.Command{
                 os.Exit(5)
             }
 
-            fmt.Println("\n" + retStr + "\n")
+            PrintResponse(cmd, retStr)
 
             // make a decision on what to exit with
             retMap := JsonStrToMap(retStr)
@@ -117,4 +127,5 @@ func init() {
     listPoliciesCmd.Flags().StringP("next-token", "n", "",
                                  "Token from which subsequent Policies would " +
                                  "be listed")
+    AddListPagingFlags(listPoliciesCmd)
 }
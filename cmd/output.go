@@ -0,0 +1,477 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	OutputOption       = "output"
+	OutputFormatOption = "output-format"
+
+	OutputFormatJSON       = "json"
+	OutputFormatYAML       = "yaml"
+	OutputFormatTable      = "table"
+	OutputFormatWide       = "wide"
+	OutputFormatCSV        = "csv"
+	OutputFormatNDJSON     = "ndjson"
+	OutputFormatJSONPath   = "jsonpath="
+	OutputFormatGoTemplate = "go-template="
+
+	// nested values are cut short in table output
+	outputTableMaxCellWidth = 50
+)
+
+// OutputColumn is a column of table, wide and csv output. Path is a JSON
+// path into each item.
+type OutputColumn struct {
+	Header string
+	Path   string
+	Wide   bool
+}
+
+// outputColumns defines the columns of each kind of listed item
+var outputColumns = map[string][]OutputColumn{
+	"boxes": {
+		{Header: "ID", Path: "box_id"},
+		{Header: "NAME", Path: "name"},
+		{Header: "DESCRIPTION", Path: "description"},
+		{Header: "REVISION", Path: "revision", Wide: true},
+		{Header: "EXCLUSIVE CHECKOUT", Path: "exclusive_checkout", Wide: true},
+		{Header: "MAX VERSIONS", Path: "max_secret_versions", Wide: true},
+		{Header: "TAGS", Path: "tags", Wide: true},
+	},
+	"secrets": {
+		{Header: "ID", Path: "secret_id"},
+		{Header: "NAME", Path: "name"},
+		{Header: "BOX", Path: "box_id"},
+		{Header: "TYPE", Path: "secret_subtype_info.type"},
+		{Header: "VERSION", Path: "current_version"},
+		{Header: "REVISION", Path: "revision", Wide: true},
+		{Header: "EXPIRES", Path: "expires_at", Wide: true},
+		{Header: "DESCRIPTION", Path: "desc", Wide: true},
+		{Header: "TAGS", Path: "tags", Wide: true},
+	},
+	"leases": {
+		{Header: "LEASE", Path: "lease_id"},
+		{Header: "BOX", Path: "box_id"},
+		{Header: "SECRET", Path: "secret_id"},
+		{Header: "USER", Path: "user"},
+		{Header: "EXPIRES", Path: "expires_at"},
+		{Header: "VERSION", Path: "version", Wide: true},
+		{Header: "RENEWABLE", Path: "renewable", Wide: true},
+	},
+	"policies": {
+		{Header: "ID", Path: "policy_id"},
+		{Header: "NAME", Path: "name"},
+		{Header: "ROLE", Path: "role"},
+		{Header: "REVISION", Path: "revision"},
+		{Header: "DESCRIPTION", Path: "desc", Wide: true},
+		{Header: "PRINCIPALS", Path: "principals", Wide: true},
+		{Header: "RESOURCES", Path: "resources", Wide: true},
+	},
+	"users": {
+		{Header: "USERNAME", Path: "username"},
+		{Header: "NAME", Path: "name"},
+		{Header: "EMAIL", Path: "email"},
+		{Header: "STATE", Path: "account_state"},
+		{Header: "REVISION", Path: "revision", Wide: true},
+	},
+	"tokens": {
+		{Header: "NAME", Path: "name"},
+		{Header: "DESCRIPTION", Path: "description"},
+		{Header: "EXPIRES", Path: "expiry"},
+		{Header: "REVISION", Path: "revision", Wide: true},
+	},
+	"rotation_jobs": {
+		{Header: "ID", Path: "job_id"},
+		{Header: "BOX", Path: "box_id"},
+	},
+	"results": {
+		{Header: "SECRET ID", Path: "secret_id"},
+		{Header: "NAME", Path: "name"},
+		{Header: "STATUS", Path: "status"},
+		{Header: "ATTEMPTS", Path: "attempts", Wide: true},
+		{Header: "ERROR", Path: "error"},
+	},
+	"matches": {
+		{Header: "BOX", Path: "box"},
+		{Header: "SECRET", Path: "name"},
+		{Header: "TYPE", Path: "type"},
+		{Header: "UPDATED", Path: "updated_at"},
+		{Header: "BOX ID", Path: "box_id", Wide: true},
+		{Header: "SECRET ID", Path: "secret_id", Wide: true},
+		{Header: "MATCHED", Path: "matched", Wide: true},
+	},
+	"drift": {
+		{Header: "BOX", Path: "box"},
+		{Header: "SECRET", Path: "secret"},
+		{Header: "STATUS", Path: "status"},
+		{Header: "DETAIL", Path: "detail"},
+	},
+	"differences": {
+		{Header: "SECTION", Path: "section"},
+		{Header: "ITEM", Path: "item"},
+		{Header: "CHANGE", Path: "change"},
+		{Header: "FROM", Path: "from"},
+		{Header: "TO", Path: "to"},
+	},
+	"mappings": {
+		{Header: "SOURCE", Path: "source"},
+		{Header: "BOX", Path: "box"},
+		{Header: "SECRET", Path: "secret"},
+		{Header: "TYPE", Path: "type"},
+		{Header: "STATUS", Path: "status"},
+		{Header: "DETAIL", Path: "detail"},
+	},
+	"csv_errors": {
+		{Header: "LINE", Path: "line"},
+		{Header: "COLUMN", Path: "column"},
+		{Header: "ERROR", Path: "error"},
+	},
+}
+
+// outputKinds maps list response keys to the kinds of outputColumns
+var outputKinds = map[string]string{
+	"boxes":                  "boxes",
+	"secrets":                "secrets",
+	"leases":                 "leases",
+	"checkouts":              "leases",
+	"policies":               "policies",
+	"users":                  "users",
+	"local_users":            "users",
+	"personal_access_tokens": "tokens",
+	"tokens":                 "tokens",
+	"jobs":                   "rotation_jobs",
+	"rotation_jobs":          "rotation_jobs",
+	"results":                "results",
+	"matches":                "matches",
+	"drift":                  "drift",
+	"differences":            "differences",
+	"mappings":               "mappings",
+	"csv_errors":             "csv_errors",
+}
+
+// outputCommandKinds maps commands returning a single item to its kind
+var outputCommandKinds = map[string]string{
+	"create-box":                   "boxes",
+	"get-box":                      "boxes",
+	"update-box":                   "boxes",
+	"get-secret-metadata":          "secrets",
+	"update-secret":                "secrets",
+	"get-lease":                    "leases",
+	"get-policy":                   "policies",
+	"create-policy":                "policies",
+	"update-policy":                "policies",
+	"get-local-user":               "users",
+	"create-local-user":            "users",
+	"update-local-user":            "users",
+	"get-personal-access-token":    "tokens",
+	"create-personal-access-token": "tokens",
+	"create-rotation-job":          "rotation_jobs",
+}
+
+// outputFormatValue validates the output format while flags are parsed, before
+// any request is sent
+type outputFormatValue struct {
+	format string
+}
+
+func (v *outputFormatValue) String() string {
+	return v.format
+}
+
+func (v *outputFormatValue) Set(format string) error {
+	switch {
+	case format == OutputFormatJSON, format == OutputFormatYAML,
+		format == OutputFormatTable, format == OutputFormatWide,
+		format == OutputFormatCSV, format == OutputFormatNDJSON:
+	case strings.HasPrefix(format, OutputFormatJSONPath):
+		if _, err := ParseJSONPath(strings.TrimPrefix(format, OutputFormatJSONPath)); err != nil {
+			return err
+		}
+	case strings.HasPrefix(format, OutputFormatGoTemplate):
+		_, err := template.New("output").Parse(strings.TrimPrefix(format, OutputFormatGoTemplate))
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("expected one of json, yaml, table, wide, csv, ndjson, " +
+			"jsonpath=PATH or go-template=TEMPLATE")
+	}
+	v.format = format
+	return nil
+}
+
+func (v *outputFormatValue) Type() string {
+	return "format"
+}
+
+// AddOutputFlags adds -o/--output to cmd and all its subcommands. Commands
+// which already use --output for something else, e.g. a file path, get the
+// format as --output-format instead. The -o shorthand is left out where a
+// command already uses it for something else.
+func AddOutputFlags(cmd *cobra.Command) {
+	for _, child := range cmd.Commands() {
+		AddOutputFlags(child)
+	}
+	name := OutputOption
+	if cmd.Flags().Lookup(name) != nil {
+		name = OutputFormatOption
+	}
+	shorthand := "o"
+	if cmd.Flags().ShorthandLookup(shorthand) != nil {
+		shorthand = ""
+	}
+	cmd.Flags().VarP(&outputFormatValue{}, name, shorthand,
+		"Output format: json, yaml, table, wide, csv, ndjson, jsonpath=PATH or "+
+			"go-template=TEMPLATE. Default is the JSON response as returned.")
+}
+
+// GetOutputFormat returns the -o format, empty if not given
+func GetOutputFormat(cmd *cobra.Command) string {
+	for _, name := range []string{OutputOption, OutputFormatOption} {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil {
+			continue
+		}
+		if format, isFormat := flag.Value.(*outputFormatValue); isFormat {
+			return format.String()
+		}
+	}
+	return ""
+}
+
+// PrintResponse prints an API response in the -o/--output format of cmd
+func PrintResponse(cmd *cobra.Command, retStr string) {
+	format := GetOutputFormat(cmd)
+	kind := outputCommandKinds[cmd.Name()]
+	if err := PrintFormatted(os.Stdout, format, kind, retStr); err != nil {
+		fmt.Printf("\nError formatting output - %v\n", err)
+		os.Exit(4)
+	}
+}
+
+// PrintFormatted writes an API response in the given format. Without a
+// format, or for empty and error responses, the response is printed as is.
+// kind selects the table columns for single items, lists are recognized by
+// the key they are returned under.
+func PrintFormatted(out io.Writer, format string, kind string, retStr string) error {
+	var response interface{}
+	if format == "" || strings.TrimSpace(retStr) == "" ||
+		json.Unmarshal([]byte(retStr), &response) != nil {
+		_, err := fmt.Fprintln(out, "\n"+retStr+"\n")
+		return err
+	}
+	if responseMap, isMap := response.(map[string]interface{}); isMap {
+		if _, present := responseMap["error"]; present {
+			_, err := fmt.Fprintln(out, "\n"+retStr+"\n")
+			return err
+		}
+	}
+
+	switch {
+	case format == OutputFormatJSON:
+		return writeOutputJSON(out, response, true)
+	case format == OutputFormatYAML:
+		encoder := yaml.NewEncoder(out)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(response); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case format == OutputFormatNDJSON:
+		items, isList := outputItems(response)
+		if !isList {
+			items = []interface{}{response}
+		}
+		for _, item := range items {
+			if err := writeOutputJSON(out, item, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	case strings.HasPrefix(format, OutputFormatJSONPath):
+		steps, err := ParseJSONPath(strings.TrimPrefix(format, OutputFormatJSONPath))
+		if err != nil {
+			return err
+		}
+		for _, match := range LookupJSONPath(response, steps) {
+			if str, isString := match.(string); isString {
+				_, err = fmt.Fprintln(out, str)
+			} else {
+				err = writeOutputJSON(out, match, false)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case strings.HasPrefix(format, OutputFormatGoTemplate):
+		tmpl, err := template.New("output").Parse(strings.TrimPrefix(format, OutputFormatGoTemplate))
+		if err != nil {
+			return err
+		}
+		return tmpl.Execute(out, response)
+	}
+
+	// table, wide and csv
+	items, isList := outputItems(response)
+	if isList {
+		key, _ := ListItemsFromResponse(response.(map[string]interface{}))
+		if listKind, known := outputKinds[key]; known {
+			kind = listKind
+		}
+	} else {
+		items = []interface{}{response}
+	}
+	columns := outputColumnsFor(kind, items, format != OutputFormatTable)
+
+	rows := [][]string{}
+	for _, item := range items {
+		row := []string{}
+		for _, column := range columns {
+			row = append(row, outputCell(item, column.Path, format == OutputFormatTable))
+		}
+		rows = append(rows, row)
+	}
+
+	headers := []string{}
+	for _, column := range columns {
+		headers = append(headers, column.Header)
+	}
+	if format == OutputFormatCSV {
+		writer := csv.NewWriter(out)
+		writer.Write(headers)
+		writer.WriteAll(rows)
+		return writer.Error()
+	}
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+	return writer.Flush()
+}
+
+func writeOutputJSON(out io.Writer, value interface{}, indent bool) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+	if indent {
+		encoder.SetIndent("", "  ")
+	}
+	return encoder.Encode(value)
+}
+
+// outputItems returns the items of a list response
+func outputItems(response interface{}) ([]interface{}, bool) {
+	responseMap, isMap := response.(map[string]interface{})
+	if !isMap {
+		if items, isArray := response.([]interface{}); isArray {
+			return items, true
+		}
+		return nil, false
+	}
+	key, items := ListItemsFromResponse(responseMap)
+	return items, key != ""
+}
+
+// outputColumnsFor returns the columns defined for kind, or for unknown
+// kinds one column per top level key of the items
+func outputColumnsFor(kind string, items []interface{}, wide bool) []OutputColumn {
+	columns := []OutputColumn{}
+	if defined, known := outputColumns[kind]; known {
+		for _, column := range defined {
+			if wide || !column.Wide {
+				columns = append(columns, column)
+			}
+		}
+		return columns
+	}
+
+	keys := map[string]bool{}
+	for _, item := range items {
+		if itemMap, isMap := item.(map[string]interface{}); isMap {
+			for key := range itemMap {
+				keys[key] = true
+			}
+		} else {
+			keys[""] = true
+		}
+	}
+	sortedKeys := make([]string, 0, len(keys))
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+	for _, key := range sortedKeys {
+		if key == "" {
+			columns = append(columns, OutputColumn{Header: "VALUE", Path: "$"})
+			continue
+		}
+		columns = append(columns, OutputColumn{
+			Header: strings.ToUpper(strings.ReplaceAll(key, "_", " ")),
+			Path:   "['" + key + "']",
+		})
+	}
+	return columns
+}
+
+// outputCell formats the value at path in item for table and csv output
+func outputCell(item interface{}, path string, short bool) string {
+	steps, err := ParseJSONPath(path)
+	if err != nil {
+		return ""
+	}
+	matches := LookupJSONPath(item, steps)
+	if len(matches) == 0 || matches[0] == nil {
+		return ""
+	}
+
+	var cell string
+	switch value := matches[0].(type) {
+	case string:
+		cell = value
+	case float64:
+		cell = strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		cell = strconv.FormatBool(value)
+	default:
+		buffer := &bytes.Buffer{}
+		writeOutputJSON(buffer, value, false)
+		cell = strings.TrimSpace(buffer.String())
+	}
+	cell = strings.ReplaceAll(cell, "\n", " ")
+	if runes := []rune(cell); short && len(runes) > outputTableMaxCellWidth {
+		cell = string(runes[:outputTableMaxCellWidth-3]) + "..."
+	}
+	return cell
+}
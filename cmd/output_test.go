@@ -0,0 +1,54 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestOutputCellTruncatesRunes(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		short bool
+		want  string
+	}{
+		{name: "short enough", value: "db-box", short: true, want: "db-box"},
+		{name: "ascii", value: strings.Repeat("a", 60), short: true,
+			want: strings.Repeat("a", 47) + "..."},
+		{name: "multi-byte", value: strings.Repeat("é", 60), short: true,
+			want: strings.Repeat("é", 47) + "..."},
+		{name: "multi-byte fitting in runes", value: strings.Repeat("日", 50), short: true,
+			want: strings.Repeat("日", 50)},
+		{name: "wide output is not truncated", value: strings.Repeat("é", 60), short: false,
+			want: strings.Repeat("é", 60)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			item := map[string]interface{}{"name": test.value}
+			got := outputCell(item, "['name']", test.short)
+			if got != test.want {
+				t.Errorf("outputCell() = %q, want %q", got, test.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("outputCell() = %q is not valid UTF-8", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	listOptionAll    = "all"
+	listOptionLimit  = "limit"
+	listOptionNDJSON = "ndjson"
+)
+
+// errListNotFound is returned when the server has nothing to list
+var errListNotFound = errors.New("not found")
+
+// listResponseError carries a list response which reported an error
+type listResponseError struct {
+	Response string
+}
+
+func (e listResponseError) Error() string {
+	return e.Response
+}
+
+// ListPaging tells how many pages of a list command to fetch and how to
+// print them
+type ListPaging struct {
+	All    bool
+	Limit  int
+	NDJSON bool
+
+	cmd *cobra.Command // prints the merged pages in its --output format
+}
+
+// AddListPagingFlags adds --all, --limit and --ndjson to a list command
+// which takes --max-items and --next-token
+func AddListPagingFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool(listOptionAll, false,
+		"Follow next_token and list all items. --max-items sets the page size.")
+	cmd.Flags().Int(listOptionLimit, 0,
+		"List at most this many items, following next_token as needed")
+	cmd.Flags().Bool(listOptionNDJSON, false,
+		"Print one JSON item per line as pages arrive, instead of a single JSON document")
+}
+
+// GetListPaging reads the paging flags of cmd. -o ndjson is the same as
+// --ndjson.
+func GetListPaging(cmd *cobra.Command) (ListPaging, error) {
+	var paging ListPaging
+	flags := cmd.Flags()
+	paging.All, _ = flags.GetBool(listOptionAll)
+	paging.Limit, _ = flags.GetInt(listOptionLimit)
+	paging.NDJSON, _ = flags.GetBool(listOptionNDJSON)
+	paging.NDJSON = paging.NDJSON || GetOutputFormat(cmd) == OutputFormatNDJSON
+	paging.cmd = cmd
+	if paging.Limit < 0 {
+		return paging, fmt.Errorf("--%s cannot be negative", listOptionLimit)
+	}
+	return paging, nil
+}
+
+// IsRequested tells if the list must go through ListAllPages
+func (p ListPaging) IsRequested() bool {
+	return p.All || p.Limit > 0 || p.NDJSON
+}
+
+// ListAllPages posts action with params, following next_token while paging
+// asks for more items, and passes each item to emit as it arrives. Only one
+// page is held in memory at a time. Returns the key the items were listed
+// under and, when stopping before the last page, the token to resume from.
+func ListAllPages(action string,
+	params map[string]interface{},
+	paging ListPaging,
+	emit func(item interface{}) error) (string, string, error) {
+
+	pageParams := map[string]interface{}{}
+	for key, value := range params {
+		pageParams[key] = value
+	}
+
+	pageSize, _ := params["max_items"].(int)
+	itemsKey := ""
+	count := 0
+	for {
+		// ask for no more than the limit leaves, so the page ends where the
+		// listing stops and its next_token resumes right after it
+		if remaining := paging.Limit - count; paging.Limit > 0 &&
+			(pageSize <= 0 || remaining < pageSize) {
+			pageParams["max_items"] = remaining
+		}
+		jsonParams, err := json.Marshal(pageParams)
+		if err != nil {
+			return itemsKey, "", err
+		}
+
+		endpoint := GetEndPoint("", "1.0", action)
+		ret, err := DoPost(endpoint,
+			GetCACertFile(),
+			AuthTokenKV(),
+			jsonParams,
+			"application/json")
+		if err != nil {
+			return itemsKey, "", err
+		}
+		retStr := ret["data"].(*bytes.Buffer).String()
+		retStatus := ret["status"].(int)
+		if retStr == "" && retStatus == 404 {
+			return itemsKey, "", errListNotFound
+		}
+		retMap := JsonStrToMap(retStr)
+		if _, present := retMap["error"]; present {
+			return itemsKey, "", listResponseError{Response: retStr}
+		}
+
+		key, items := ListItemsFromResponse(retMap)
+		if itemsKey == "" {
+			itemsKey = key
+		}
+		nextToken, _ := retMap["next_token"].(string)
+		for _, item := range items {
+			if paging.Limit > 0 && count == paging.Limit {
+				// the page is larger than asked for. Resuming from
+				// nextToken would skip the rest of it, so resume from
+				// the token of the page instead, repeating some items
+				pageToken, _ := pageParams["next_token"].(string)
+				return itemsKey, pageToken, nil
+			}
+			if err := emit(item); err != nil {
+				return itemsKey, "", err
+			}
+			count += 1
+		}
+
+		limitReached := paging.Limit > 0 && count >= paging.Limit
+		if nextToken == "" || len(items) == 0 || limitReached ||
+			(!paging.All && paging.Limit == 0) {
+			return itemsKey, nextToken, nil
+		}
+		pageParams["next_token"] = nextToken
+	}
+}
+
+// RunListAllPages lists all the pages paging asks for and prints them, either
+// as a single JSON document merging the pages or as NDJSON, then exits
+func RunListAllPages(action string,
+	params map[string]interface{},
+	paging ListPaging,
+	notFoundMessage string) {
+
+	items := []interface{}{}
+	writer := bufio.NewWriter(os.Stdout)
+	emit := func(item interface{}) error {
+		if !paging.NDJSON {
+			items = append(items, item)
+			return nil
+		}
+		line, err := json.Marshal(item)
+		if err == nil {
+			line = append(line, '\n')
+			_, err = writer.Write(line)
+		}
+		return err
+	}
+
+	itemsKey, nextToken, err := ListAllPages(action, params, paging, emit)
+	writer.Flush()
+	ExitOnListError(err, notFoundMessage)
+
+	if !paging.NDJSON {
+		if itemsKey == "" {
+			itemsKey = "items"
+		}
+		merged := map[string]interface{}{itemsKey: items}
+		if nextToken != "" {
+			merged["next_token"] = nextToken
+		}
+		out, err := JSONMarshalIndent(merged)
+		if err != nil {
+			fmt.Println("Error building JSON output: ", err)
+			os.Exit(4)
+		}
+		PrintResponse(paging.cmd, string(bytes.TrimSpace(out)))
+	} else if nextToken != "" {
+		fmt.Fprintf(os.Stderr, "More items available, continue with --next-token %s\n", nextToken)
+	}
+	os.Exit(0)
+}
+
+// ExitOnListError exits the way list commands do if err is set
+func ExitOnListError(err error, notFoundMessage string) {
+	var responseError listResponseError
+	switch {
+	case err == nil:
+		return
+	case errors.Is(err, errListNotFound):
+		fmt.Printf("\n%s\n\n", notFoundMessage)
+		os.Exit(5)
+	case errors.As(err, &responseError):
+		fmt.Printf("\n%s\n\n", responseError.Response)
+		os.Exit(3)
+	}
+	fmt.Printf("\nHTTP request failed: %s\n", err)
+	os.Exit(4)
+}
@@ -62,7 +62,8 @@ var renewCmd = &cobra.Command{
         tokenFile, err = SaveAccessToken(tokenFile,
                                                respData.Token,
                                                GetServer(),
-                                               GetCACertFile())
+                                               GetCACertFile(),
+                                               respData.Expiration)
         if err != nil {
             fmt.Printf("\nError saving access token to %s - %v\n", tokenFile, err)
             os.Exit(4)
@@ -90,7 +90,7 @@ var updateAuditSettingsCmd = &cobra.Command{
 				fmt.Println("\nUpdate successful\n")
 				os.Exit(0)
 			} else {
-				fmt.Println("\n" + retStr + "\n")
+				PrintResponse(cmd, retStr)
 				// make a decision on what to exit with
 				retMap := JsonStrToMap(retStr)
 				if _, present := retMap["error"]; present {
@@ -25,6 +25,7 @@ import (
 	"os"
 	"path/filepath"
 	"math/big"
+	"sort"
     "bytes"
 	"time"
 	"crypto/aes"
@@ -39,11 +40,12 @@ type tokenInfo struct {
 	AccessToken string `json:"access_token"`
 	Server      string `json:"server"`
 	CACertFile  string `json:"cacert_file"`
+	ExpiresAt   string `json:"expires_at,omitempty"`
 }
 
 var gTokenInfo tokenInfo
 
-func SaveAccessToken(tokenFile, accessToken, server, caCertFile string) (string, error) {
+func SaveAccessToken(tokenFile, accessToken, server, caCertFile, expiresAt string) (string, error) {
 	if tokenFile == "" {
 		tokenDir, err := GetDataDir()
 		if err != nil {
@@ -55,7 +57,8 @@ func SaveAccessToken(tokenFile, accessToken, server, caCertFile string) (string,
 	info := tokenInfo{
 		AccessToken: accessToken,
 		Server:      server,
-		CACertFile:  caCertFile}
+		CACertFile:  caCertFile,
+		ExpiresAt:   expiresAt}
 
 	file, err := os.Create(tokenFile)
 	if err != nil {
@@ -74,22 +77,41 @@ func LoadAccessToken(tokenFile string) (string, error) {
 		tokenFile = filepath.Join(tokenDir, DefaultTokenFilename)
 	}
 
-	file, err := os.Open(tokenFile)
+	info, err := loadTokenInfo(tokenFile)
 	if err != nil {
 		return tokenFile, err
 	}
+	gTokenInfo = info
+	return tokenFile, nil
+}
+
+// loadTokenInfo reads a token file without making it the current one
+func loadTokenInfo(tokenFile string) (tokenInfo, error) {
+	var info tokenInfo
+	file, err := os.Open(tokenFile)
+	if err != nil {
+		return info, err
+	}
 	defer file.Close()
 
-	err = json.NewDecoder(file).Decode(&gTokenInfo)
+	err = json.NewDecoder(file).Decode(&info)
 	if err != nil {
-		return tokenFile, err
+		return info, err
 	}
 
-	if gTokenInfo.AccessToken == "" || gTokenInfo.Server == "" {
-		return tokenFile, fmt.Errorf("Invalid or corrupt Token File - access_token or server is missing")
+	if info.AccessToken == "" || info.Server == "" {
+		return info, fmt.Errorf("Invalid or corrupt Token File - access_token or server is missing")
 	}
 
-	return tokenFile, nil
+	return info, nil
+}
+
+// useTokenInfo makes info the current token and returns the previous one,
+// for commands talking to more than one Vault
+func useTokenInfo(info tokenInfo) tokenInfo {
+	previous := gTokenInfo
+	gTokenInfo = info
+	return previous
 }
 
 func GetAccessToken() string {
@@ -104,6 +126,16 @@ func GetCACertFile() string {
 	return gTokenInfo.CACertFile
 }
 
+// GetAccessTokenExpiration returns when the login session of the access token
+// expires, or the zero time if the token file does not record it
+func GetAccessTokenExpiration() time.Time {
+	expiration, err := time.Parse(time.RFC3339, gTokenInfo.ExpiresAt)
+	if err != nil {
+		return time.Time{}
+	}
+	return expiration
+}
+
 func JsonStrToMap(jsonStr string) map[string]interface{} {
 	var jsonMap map[string]interface{}
 	err := json.Unmarshal([]byte(jsonStr), &jsonMap)
@@ -213,6 +245,24 @@ func AESGCMDecrypt(b64CipherText string, b64Key string, b64Nonce string,
 	return string(plaintext)
 }
 
+// ListItemsFromResponse returns the items of a List API response along with
+// the key they are kept under (e.g. "boxes", "leases"). That key differs per
+// API, but it is the only array in the response.
+func ListItemsFromResponse(retMap map[string]interface{}) (string, []interface{}) {
+	keys := make([]string, 0, len(retMap))
+	for key := range retMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if items, ok := retMap[key].([]interface{}); ok {
+			return key, items
+		}
+	}
+	return "", nil
+}
+
 func KeyExists(kvMap map[string]interface{}, key string) bool {
 	val, ok := kvMap[key]
 	return ok && val != nil
@@ -36,6 +36,10 @@ type leaseInfo struct {
 	ExpiresAt string `json:"expires_at"`
 	Renewable string `json:"renewable"`
 	Version   int    `json:"version"`
+	// set when checkout-secret --ttl started a helper to check in the
+	// Secret automatically
+	AutoCheckinAt  string `json:"auto_checkin_at,omitempty"`
+	AutoCheckinPid int    `json:"auto_checkin_pid,omitempty"`
 }
 
 type proxyPortInfo struct {
@@ -124,30 +128,110 @@ func SaveLeaseInfo(leaseFile string,
 	return leaseFile, json.NewEncoder(file).Encode(&info)
 }
 
-func GetLeaseId(LeaseFile string) (string, error) {
+func LoadLeaseInfo(LeaseFile string) (leaseInfo, error) {
+	var lInfo leaseInfo
 	file, err := os.Open(LeaseFile)
 	if err != nil {
-		return "", err
+		return lInfo, err
 	}
 	defer file.Close()
 
-	var lInfo leaseInfo
 	err = json.NewDecoder(file).Decode(&lInfo)
 	if err != nil {
-		return "", err
+		return lInfo, err
 	}
 
 	if lInfo.LeaseId == "" {
-		return "", fmt.Errorf("Invalid or corrupt lease file - missing lease_id")
+		return lInfo, fmt.Errorf("Invalid or corrupt lease file - missing lease_id")
 	}
 
+	return lInfo, nil
+}
+
+func GetLeaseId(LeaseFile string) (string, error) {
+	lInfo, err := LoadLeaseInfo(LeaseFile)
+	if err != nil {
+		return "", err
+	}
 	return lInfo.LeaseId, nil
 }
 
+// SetLeaseAutoCheckin records in the lease file when the Secret is checked in
+// automatically and the pid of the helper process doing it
+func SetLeaseAutoCheckin(LeaseFile string, checkinAt string, pid int) error {
+	lInfo, err := LoadLeaseInfo(LeaseFile)
+	if err != nil {
+		return err
+	}
+	lInfo.AutoCheckinAt = checkinAt
+	lInfo.AutoCheckinPid = pid
+	return replaceLeaseInfo(LeaseFile, lInfo)
+}
+
+// replaceLeaseInfo writes lInfo to a temporary file next to LeaseFile and
+// renames it over LeaseFile, so readers never see a partly written lease
+func replaceLeaseInfo(LeaseFile string, lInfo leaseInfo) error {
+	file, err := os.CreateTemp(filepath.Dir(LeaseFile), ".vault_lease_*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err := json.NewEncoder(file).Encode(&lInfo); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), LeaseFile)
+}
+
 func AuthTokenKV() map[string]string {
 	return map[string]string{"X-VAULT-AUTH": GetAccessToken()}
 }
 
+// postVaultAPI posts params to the given Vault API action and returns the
+// decoded JSON response along with the HTTP status code. An "error" in the
+// response is returned as error.
+func postVaultAPI(action string, params map[string]interface{}) (map[string]interface{}, int, error) {
+	jsonParams, err := json.Marshal(params)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Error building JSON request: %v", err)
+	}
+
+	endpoint := GetEndPoint("", "1.0", action)
+	ret, err := DoPost(endpoint,
+		GetCACertFile(),
+		AuthTokenKV(),
+		jsonParams,
+		"application/json")
+	if err != nil {
+		return nil, 0, fmt.Errorf("HTTP request failed: %s", err)
+	}
+
+	// type assertion
+	retBytes := ret["data"].(*bytes.Buffer)
+	retStatus := ret["status"].(int)
+	retStr := retBytes.String()
+
+	retMap := map[string]interface{}{}
+	if retStr == "" {
+		if retStatus == 404 {
+			return retMap, retStatus, fmt.Errorf("%s: not found", action)
+		}
+		return retMap, retStatus, nil
+	}
+
+	if err := json.Unmarshal([]byte(retStr), &retMap); err != nil {
+		return nil, retStatus, fmt.Errorf("Error parsing %s response: %v", action, err)
+	}
+	if retVal, present := retMap["error"]; present {
+		return retMap, retStatus, fmt.Errorf("%v", retVal)
+	}
+	return retMap, retStatus, nil
+}
+
 // TODO: Refactor import csv command to make use of this function. To be done post 10.2
 func uploadCsv(params map[string]interface{}) {
 	jsonParams, err := json.Marshal(params)
@@ -0,0 +1,305 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	AutoCheckinLogFilename     = "auto_checkin.log"
+	autoCheckinPollInterval    = 5 * time.Second
+	autoCheckinOptionAt        = "at"
+	autoCheckinOptionLeaseFile = "lease-file"
+)
+
+func checkinLease(leaseId string) error {
+	params := map[string]interface{}{}
+	params["lease_id"] = leaseId
+
+	_, retStatus, err := postVaultAPI("CheckinSecret", params)
+	if err != nil {
+		return err
+	}
+	if retStatus != 204 && retStatus != 200 {
+		return fmt.Errorf("Unexpected response status %d", retStatus)
+	}
+	return nil
+}
+
+// startAutoCheckin starts a detached pasmcli process, which checks in the
+// lease saved in leaseFile once ttl elapses
+func startAutoCheckin(leaseFile string, ttl time.Duration) (time.Time, error) {
+	checkinAt := time.Now().Add(ttl)
+
+	executable, err := os.Executable()
+	if err != nil {
+		return checkinAt, err
+	}
+	leaseFile, err = filepath.Abs(leaseFile)
+	if err != nil {
+		return checkinAt, err
+	}
+
+	args := []string{"auto-checkin",
+		"--" + autoCheckinOptionLeaseFile, leaseFile,
+		"--" + autoCheckinOptionAt, checkinAt.UTC().Format(time.RFC3339)}
+	if gAccessTokenFile != "" {
+		tokenFile, err := filepath.Abs(gAccessTokenFile)
+		if err != nil {
+			return checkinAt, err
+		}
+		args = append(args, "--"+loginOptionTokenFile, tokenFile)
+	}
+
+	vaultDataDir, err := GetDataDir()
+	if err != nil {
+		return checkinAt, err
+	}
+	logFile, err := os.OpenFile(filepath.Join(vaultDataDir, AutoCheckinLogFilename),
+		os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return checkinAt, err
+	}
+	defer logFile.Close()
+
+	// the helper reads the lease file as soon as it starts, so record the
+	// check in before starting it
+	at := checkinAt.UTC().Format(time.RFC3339)
+	if err := SetLeaseAutoCheckin(leaseFile, at, 0); err != nil {
+		return checkinAt, err
+	}
+
+	helper := exec.Command(executable, args...)
+	helper.Stdout = logFile
+	helper.Stderr = logFile
+	helper.SysProcAttr = detachedProcAttr()
+	if err := helper.Start(); err != nil {
+		SetLeaseAutoCheckin(leaseFile, "", 0)
+		return checkinAt, err
+	}
+
+	err = SetLeaseAutoCheckin(leaseFile, at, helper.Process.Pid)
+	helper.Process.Release()
+	return checkinAt, err
+}
+
+func autoCheckinLog(format string, a ...interface{}) {
+	fmt.Printf("%s %s\n", time.Now().Format(time.RFC3339), fmt.Sprintf(format, a...))
+}
+
+// autoCheckin runs detached, started by checkout-secret --ttl. It exits early
+// if the lease file goes away or holds another lease, i.e. checkin-now checked
+// in the Secret already. Errors reading the lease file are retried.
+func autoCheckin(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+	leaseFile, _ := flags.GetString(autoCheckinOptionLeaseFile)
+	at, _ := flags.GetString(autoCheckinOptionAt)
+
+	checkinAt, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		autoCheckinLog("Invalid check in time %q - %v", at, err)
+		os.Exit(1)
+	}
+
+	var lInfo leaseInfo
+	for {
+		current, err := LoadLeaseInfo(leaseFile)
+		switch {
+		case os.IsNotExist(err):
+			autoCheckinLog("Lease file %s is gone, the Secret is already checked in", leaseFile)
+			os.Exit(0)
+		case err != nil:
+			autoCheckinLog("Error reading lease file %s, retrying - %v", leaseFile, err)
+		case lInfo.LeaseId == "":
+			lInfo = current
+		case current.LeaseId != lInfo.LeaseId:
+			autoCheckinLog("Lease %s is already checked in", lInfo.LeaseId)
+			os.Exit(0)
+		}
+		if !time.Now().Before(checkinAt) {
+			break
+		}
+
+		wait := time.Until(checkinAt)
+		if wait > autoCheckinPollInterval {
+			wait = autoCheckinPollInterval
+		}
+		time.Sleep(wait)
+	}
+	if lInfo.LeaseId == "" {
+		autoCheckinLog("Unable to read lease file %s, the Secret is not checked in", leaseFile)
+		os.Exit(1)
+	}
+
+	if sessionEnd := GetAccessTokenExpiration(); !sessionEnd.IsZero() && sessionEnd.Before(time.Now()) {
+		autoCheckinLog("The login session expired at %s, unable to check in lease %s of "+
+			"Secret %s in Box %s. Login again and run checkin-now to check it in.",
+			sessionEnd.Format(time.RFC3339), lInfo.LeaseId, lInfo.SecretId, lInfo.BoxId)
+		os.Exit(3)
+	}
+	err = checkinLease(lInfo.LeaseId)
+	if err != nil {
+		autoCheckinLog("Error checking in lease %s of Secret %s in Box %s - %v. "+
+			"Run checkin-now to check it in.", lInfo.LeaseId, lInfo.SecretId, lInfo.BoxId, err)
+		os.Exit(3)
+	}
+	os.Remove(leaseFile)
+	autoCheckinLog("Checked in lease %s of Secret %s in Box %s",
+		lInfo.LeaseId, lInfo.SecretId, lInfo.BoxId)
+	os.Exit(0)
+}
+
+// pendingAutoCheckinLeaseFiles returns the lease files in the data directory
+// which still have an automatic check in pending
+func pendingAutoCheckinLeaseFiles() ([]string, error) {
+	vaultDataDir, err := GetDataDir()
+	if err != nil {
+		return nil, err
+	}
+
+	leaseFiles, err := filepath.Glob(filepath.Join(vaultDataDir, "vault_lease_*.txt"))
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []string{}
+	for _, leaseFile := range leaseFiles {
+		lInfo, err := LoadLeaseInfo(leaseFile)
+		if err == nil && lInfo.AutoCheckinAt != "" {
+			pending = append(pending, leaseFile)
+		}
+	}
+	return pending, nil
+}
+
+func checkinNow(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+
+	var leaseFiles []string
+	var err error
+	switch {
+	case flags.Changed("lease-file"):
+		if flags.Changed("boxid") || flags.Changed("secretid") || flags.Changed("version") {
+			fmt.Println("Cannot specify both \"lease-file\" & Secret identifiers" +
+				"(\"boxid\", \"secretid\", \"version\")")
+			os.Exit(1)
+		}
+		leaseFile, _ := flags.GetString("lease-file")
+		leaseFiles = []string{leaseFile}
+	case flags.Changed("boxid") || flags.Changed("secretid"):
+		if !(flags.Changed("boxid") && flags.Changed("secretid")) {
+			fmt.Println("Please specify both \"boxid\" and \"secretid\" of the Secret")
+			os.Exit(1)
+		}
+		boxId, _ := flags.GetString("boxid")
+		secretId, _ := flags.GetString("secretid")
+		versionVal := -1
+		if flags.Changed("version") {
+			versionVal, _ = flags.GetInt("version")
+		}
+		leaseFile, err := GetLeaseFilePath(boxId, secretId, versionVal)
+		if err != nil {
+			fmt.Println("Error getting lease file path: " + err.Error())
+			os.Exit(1)
+		}
+		leaseFiles = []string{leaseFile}
+	default:
+		leaseFiles, err = pendingAutoCheckinLeaseFiles()
+		if err != nil {
+			fmt.Println("Error listing lease files: " + err.Error())
+			os.Exit(1)
+		}
+		if len(leaseFiles) == 0 {
+			fmt.Printf("\nNo Secrets pending automatic check in\n\n")
+			os.Exit(0)
+		}
+	}
+
+	failed := false
+	for _, leaseFile := range leaseFiles {
+		lInfo, err := LoadLeaseInfo(leaseFile)
+		if err != nil {
+			fmt.Printf("\nError reading lease file %s - %v\n", leaseFile, err)
+			failed = true
+			continue
+		}
+
+		err = checkinLease(lInfo.LeaseId)
+		if err != nil {
+			fmt.Printf("\nError checking in Secret %s in Box %s - %v\n",
+				lInfo.SecretId, lInfo.BoxId, err)
+			failed = true
+			continue
+		}
+
+		// removing the lease file also stops the auto-checkin helper
+		os.Remove(leaseFile)
+		fmt.Printf("\nChecked in Secret %s in Box %s\n", lInfo.SecretId, lInfo.BoxId)
+	}
+	fmt.Println()
+
+	if failed {
+		os.Exit(3)
+	}
+	os.Exit(0)
+}
+
+// autoCheckinCmd is started in the background by checkout-secret --ttl
+var autoCheckinCmd = &cobra.Command{
+	Use:    "auto-checkin",
+	Short:  "Check in a Secret once its checkout TTL elapses",
+	Hidden: true,
+	Run:    autoCheckin,
+}
+
+// checkinNowCmd represents the checkin-now command
+var checkinNowCmd = &cobra.Command{
+	Use:   "checkin-now",
+	Short: "Check in Secrets checked out with --ttl without waiting for the TTL to elapse",
+	Long: `Check in Secrets checked out with --ttl without waiting for the TTL to elapse.
+
+Without any options, every Secret still pending automatic check in is checked in.`,
+	Run: checkinNow,
+}
+
+func init() {
+	rootCmd.AddCommand(autoCheckinCmd)
+	autoCheckinCmd.Flags().String(autoCheckinOptionLeaseFile, "",
+		"Lease file of the Secret to check in")
+	autoCheckinCmd.Flags().String(autoCheckinOptionAt, "",
+		"Time to check in the Secret at, in RFC 3339 format")
+	autoCheckinCmd.MarkFlagRequired(autoCheckinOptionLeaseFile)
+	autoCheckinCmd.MarkFlagRequired(autoCheckinOptionAt)
+
+	rootCmd.AddCommand(checkinNowCmd)
+	acceptSecretRef(checkinNowCmd)
+	checkinNowCmd.Flags().StringP("lease-file", "f", "",
+		"Lease file of the Secret to check in")
+	checkinNowCmd.Flags().StringP("boxid", "b", "",
+		"Box id or name of the Secret to check in")
+	checkinNowCmd.Flags().StringP("secretid", "s", "",
+		"Secret id or name of the Secret to check in")
+	checkinNowCmd.Flags().IntP("version", "v", 0,
+		"Version of the Secret to check in, if provided during checkout")
+}
@@ -0,0 +1,760 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"cli/getpasswd"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	backupFormat = 1
+
+	restoreConflictSkip      = "skip"
+	restoreConflictOverwrite = "overwrite"
+	restoreConflictRename    = "rename"
+)
+
+// backupArchive is what a backup holds, before compression and encryption
+type backupArchive struct {
+	Format    int                      `json:"format"`
+	CreatedAt string                   `json:"created_at"`
+	Boxes     []backupBox              `json:"boxes"`
+	Policies  []map[string]interface{} `json:"policies"`
+}
+
+type backupBox struct {
+	Box     map[string]interface{} `json:"box"`
+	Secrets []backupSecret         `json:"secrets"`
+}
+
+type backupSecret struct {
+	Metadata map[string]interface{} `json:"metadata"`
+	// index in Versions of the current version
+	Current  int             `json:"current"`
+	Versions []backupVersion `json:"versions"`
+}
+
+// backupVersion is one Secret version, file content kept base64 encoded
+type backupVersion struct {
+	Version int         `json:"version"`
+	Data    interface{} `json:"data,omitempty"`
+	File    string      `json:"file,omitempty"`
+	Sha256  string      `json:"sha256,omitempty"`
+}
+
+// restoredSecret is a Secret restored, checked by the verification pass
+type restoredSecret struct {
+	Name     string
+	BoxId    string
+	SecretId string
+	// the version number of each backed up version in the restored Secret
+	Versions []int
+	Backup   backupSecret
+}
+
+// secretVersionNumbers lists the version numbers of a Secret, sorted
+func secretVersionNumbers(boxId string, secretId string) ([]int, error) {
+	params := map[string]interface{}{}
+	params["box_id"] = boxId
+	params["secret_id"] = secretId
+	retMap, _, err := postVaultAPI("ListSecretVersions", params)
+	if err != nil {
+		return nil, err
+	}
+	versions := []int{}
+	items, _ := retMap["versions"].([]interface{})
+	for _, item := range items {
+		itemMap, _ := item.(map[string]interface{})
+		if value, isNumber := itemMap["version"].(float64); isNumber {
+			versions = append(versions, int(value))
+		}
+	}
+	sort.Ints(versions)
+	return versions, nil
+}
+
+// backupSecretVersions reads the current, or all the versions of a Secret
+func backupSecretVersions(metadata map[string]interface{}, allVersions bool) (backupSecret, error) {
+	backup := backupSecret{Metadata: metadata}
+	boxId, _ := metadata["box_id"].(string)
+	secretId, _ := metadata["secret_id"].(string)
+	current := 0
+	if value, isNumber := metadata["current_version"].(float64); isNumber {
+		current = int(value)
+	}
+
+	versions := []int{current}
+	if allVersions {
+		var err error
+		if versions, err = secretVersionNumbers(boxId, secretId); err != nil {
+			return backup, fmt.Errorf("Unable to list versions of Secret %s - %v", secretId, err)
+		}
+	}
+	for index, version := range versions {
+		fetched, err := fetchSecretVersion(boxId, secretId, version)
+		if err != nil {
+			return backup, err
+		}
+		backed := backupVersion{Version: version, Data: fetched.Data}
+		if fetched.isFile() {
+			backed.File, err = fetched.Spool.encoded()
+			backed.Sha256 = fetched.Sum
+			fetched.Spool.Close()
+			if err != nil {
+				return backup, fmt.Errorf("Unable to read version %d of file Secret %s - %v",
+					version, secretId, err)
+			}
+		}
+		if version == current {
+			backup.Current = index
+		}
+		backup.Versions = append(backup.Versions, backed)
+	}
+	return backup, nil
+}
+
+// secretData turns a backed up version back into the data to store
+func (v backupVersion) secretData() (secretVersion, error) {
+	if v.Sha256 == "" {
+		return secretVersion{Data: v.Data}, nil
+	}
+	spool, err := newSecretSpool()
+	if err != nil {
+		return secretVersion{}, err
+	}
+	if _, err := io.WriteString(spool.file, v.File); err != nil {
+		spool.Close()
+		return secretVersion{}, err
+	}
+	size := int64(len(strings.TrimRight(v.File, "="))) * 3 / 4
+	return secretVersion{Spool: spool, Size: size, Sum: v.Sha256}, nil
+}
+
+// matches tells if a version read from the Vault is the backed up one
+func (v backupVersion) matches(fetched secretVersion) bool {
+	if v.Sha256 != "" || fetched.isFile() {
+		return v.Sha256 == fetched.Sum
+	}
+	return reflect.DeepEqual(normalizeManifestValue(v.Data), fetched.Data)
+}
+
+// readPassphrase reads a passphrase from the file, or prompts for it,
+// twice when confirm is set
+func readPassphrase(path string, confirm bool) (string, error) {
+	if path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		passphrase := strings.TrimRight(string(content), "\r\n")
+		if passphrase == "" {
+			return "", fmt.Errorf("%s is empty", path)
+		}
+		return passphrase, nil
+	}
+	fmt.Fprintf(os.Stderr, "Passphrase: ")
+	passphrase := getpasswd.ReadPassword()
+	fmt.Fprintf(os.Stderr, "\n")
+	if passphrase == "" {
+		return "", fmt.Errorf("empty passphrase")
+	}
+	if confirm {
+		fmt.Fprintf(os.Stderr, "Confirm passphrase: ")
+		again := getpasswd.ReadPassword()
+		fmt.Fprintf(os.Stderr, "\n")
+		if again != passphrase {
+			return "", fmt.Errorf("passphrases don't match")
+		}
+	}
+	return passphrase, nil
+}
+
+// restoredName returns the first of NAME-restored, NAME-restored-2 ...
+// for which exists is false
+func restoredName(name string, exists func(string) bool) string {
+	candidate := name + "-restored"
+	for suffix := 2; exists(candidate); suffix += 1 {
+		candidate = fmt.Sprintf("%s-restored-%d", name, suffix)
+	}
+	return candidate
+}
+
+// restoreSecretVersions stores the backed up versions, in order, in a new
+// Secret, or in an existing one when secretId is set, then makes the backed
+// up current version current. Returns the Secret id and version numbers.
+func restoreSecretVersions(boxId string, name string, secretId string,
+	backup backupSecret) (string, []int, error) {
+
+	params := map[string]interface{}{}
+	for _, field := range copiedSecretFields {
+		if value, present := backup.Metadata[field]; present && value != nil {
+			params[field] = value
+		}
+	}
+	tags, _ := backup.Metadata["tags"].(map[string]interface{})
+
+	base := 0
+	if secretId != "" {
+		existing, err := secretVersionNumbers(boxId, secretId)
+		if err != nil {
+			return secretId, nil, fmt.Errorf("Unable to list versions - %v", err)
+		}
+		if len(existing) != 0 {
+			base = existing[len(existing)-1]
+		}
+	}
+
+	versions := []int{}
+	for index, version := range backup.Versions {
+		data, err := version.secretData()
+		if err != nil {
+			return secretId, nil, err
+		}
+		storeParams := map[string]interface{}{}
+		storeParams["box_id"] = boxId
+		create := secretId == ""
+		if create {
+			for key, value := range params {
+				storeParams[key] = value
+			}
+			storeParams["name"] = name
+		} else {
+			storeParams["secret_id"] = secretId
+		}
+		storedId, err := storeSecretVersion(create, storeParams, tags, data)
+		data.Spool.Close()
+		if storedId != "" {
+			secretId = storedId
+		}
+		if err != nil {
+			return secretId, nil, fmt.Errorf("Unable to restore version %d - %v",
+				version.Version, err)
+		}
+		versions = append(versions, base+index+1)
+	}
+
+	if backup.Current != len(backup.Versions)-1 {
+		params := map[string]interface{}{}
+		params["box_id"] = boxId
+		params["secret_id"] = secretId
+		params["version"] = versions[backup.Current]
+		if _, _, err := postVaultAPI("SetSecretVersion", params); err != nil {
+			return secretId, versions, fmt.Errorf("Unable to set the current version - %v", err)
+		}
+	}
+	return secretId, versions, nil
+}
+
+// backupCmd represents the backup command
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Write an encrypted backup of Boxes, Secrets and Policies",
+	Long: `Read the settings of the Boxes given with --box, or of all the Boxes with
+--all, the metadata and value of each of their Secrets, and the Policies
+granting access to them, into a single compressed archive.
+
+The archive is an age file, encrypted to the X25519 recipients given with
+--recipient (age1... public keys, e.g. from age-keygen), or else to a
+passphrase prompted for or read from --passphrase-file. A passphrase is
+stretched with scrypt and the archive encrypted with ChaCha20-Poly1305, as
+age does, not with AES-GCM. age -d opens it with the matching identity or
+passphrase. Use restore to re-create the backed up items.
+
+Example:
+  pasmcli backup --all -o backup.pasm`,
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+		boxNames, _ := flags.GetStringArray("box")
+		all, _ := flags.GetBool("all")
+		allVersions, _ := flags.GetBool("all-versions")
+		outPath, _ := flags.GetString("out")
+		force, _ := flags.GetBool("force")
+		recipients, _ := flags.GetStringArray("recipient")
+		passphraseFile, _ := flags.GetString("passphrase-file")
+
+		if all == (len(boxNames) != 0) {
+			fmt.Println("Please provide either --box or --all")
+			os.Exit(1)
+		}
+		if len(recipients) != 0 && passphraseFile != "" {
+			fmt.Println("Please provide either --recipient or --passphrase-file")
+			os.Exit(1)
+		}
+		keys := backupKeys{}
+		var err error
+		if keys.Recipients, err = parseBackupRecipients(recipients); err != nil {
+			fmt.Printf("\n%v\n\n", err)
+			os.Exit(1)
+		}
+		if len(recipients) == 0 {
+			passphrase, err := readPassphrase(passphraseFile, true)
+			if err != nil {
+				fmt.Printf("\nUnable to read the passphrase - %v\n\n", err)
+				os.Exit(1)
+			}
+			keys.Passphrase = passphrase
+		}
+
+		archive := backupArchive{
+			Format:    backupFormat,
+			CreatedAt: time.Now().UTC().Format(time.RFC3339),
+			Boxes:     []backupBox{},
+			Policies:  []map[string]interface{}{},
+		}
+		if all {
+			boxes, err := listItems("ListBoxIds", map[string]interface{}{})
+			if err != nil {
+				fmt.Printf("\nUnable to list Boxes - %v\n\n", err)
+				os.Exit(3)
+			}
+			for _, box := range boxes {
+				boxId, _ := box["box_id"].(string)
+				boxNames = append(boxNames, boxId)
+			}
+		}
+
+		backedUpBoxIds := map[string]bool{}
+		secretCount, versionCount := 0, 0
+		for _, boxName := range boxNames {
+			params := map[string]interface{}{}
+			params["box_id"] = boxName
+			box, _, err := postVaultAPI("GetBox", params)
+			if err != nil {
+				fmt.Printf("\nUnable to get Box %s - %v\n\n", boxName, err)
+				os.Exit(3)
+			}
+			boxId, _ := box["box_id"].(string)
+			backedUpBoxIds[boxId] = true
+			params["box_id"] = boxId
+			secrets, err := listItems("ListSecretIds", params)
+			if err != nil {
+				fmt.Printf("\nUnable to list the Secrets of Box %s - %v\n\n", boxName, err)
+				os.Exit(3)
+			}
+
+			backed := backupBox{Box: box, Secrets: []backupSecret{}}
+			for _, secret := range secrets {
+				params["secret_id"] = secret["secret_id"]
+				metadata, _, err := postVaultAPI("GetSecretMetadata", params)
+				if err != nil {
+					fmt.Printf("\nUnable to get Secret %v - %v\n\n", secret["name"], err)
+					os.Exit(3)
+				}
+				backup, err := backupSecretVersions(metadata, allVersions)
+				if err != nil {
+					fmt.Printf("\n%v\n\n", err)
+					os.Exit(3)
+				}
+				backed.Secrets = append(backed.Secrets, backup)
+				secretCount += 1
+				versionCount += len(backup.Versions)
+			}
+			archive.Boxes = append(archive.Boxes, backed)
+			fmt.Fprintf(os.Stderr, "Box %v backed up (%d Secrets)\n", box["name"], len(backed.Secrets))
+		}
+
+		// the Policies granting access to the Boxes backed up
+		policies, err := listItems("ListPolicies", map[string]interface{}{})
+		if err != nil {
+			fmt.Printf("\nUnable to list Policies - %v\n\n", err)
+			os.Exit(3)
+		}
+		for _, listed := range policies {
+			params := map[string]interface{}{}
+			params["policy_id"] = listed["policy_id"]
+			policy, _, err := postVaultAPI("GetPolicy", params)
+			if err != nil {
+				fmt.Printf("\nUnable to get Policy %v - %v\n\n", listed["name"], err)
+				os.Exit(3)
+			}
+			resources, _ := policy["resources"].([]interface{})
+			for _, resource := range resources {
+				resourceMap, _ := resource.(map[string]interface{})
+				if boxId, _ := resourceMap["box_id"].(string); backedUpBoxIds[boxId] {
+					archive.Policies = append(archive.Policies, policy)
+					break
+				}
+			}
+		}
+
+		plain := &bytes.Buffer{}
+		compressor := gzip.NewWriter(plain)
+		err = json.NewEncoder(compressor).Encode(archive)
+		if err == nil {
+			err = compressor.Close()
+		}
+		encrypted := &bytes.Buffer{}
+		if err == nil {
+			err = encryptBackup(encrypted, plain.Bytes(), keys)
+		}
+		if err != nil {
+			fmt.Printf("\nUnable to build the backup - %v\n\n", err)
+			os.Exit(4)
+		}
+		out := fileSecretOutput{Path: outPath, Mode: 0600, Force: force}
+		path, _, _, err := writeFileSecret(out, "", encrypted)
+		if err != nil {
+			fmt.Printf("\nError writing %s - %v\n\n", outPath, err)
+			os.Exit(4)
+		}
+		fmt.Printf("\nBacked up %d Boxes, %d Secrets (%d versions) and %d Policies to %s\n\n",
+			len(archive.Boxes), secretCount, versionCount, len(archive.Policies), path)
+		os.Exit(0)
+	},
+}
+
+// restoreCmd represents the restore command
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Re-create Boxes, Secrets and Policies from a backup",
+	Long: `Re-create the Boxes, Secrets and Policies of a backup written by backup, in
+the Vault logged in to, which need not be the one backed up. Secrets get
+the backed up versions in order, and the same current version.
+
+--conflict tells what to do with items which already exist: skip keeps
+them as they are, overwrite updates their settings and adds the backed up
+versions to existing Secrets, rename restores them as NAME-restored. Policy
+resources are mapped to the restored Boxes and Secrets, resources on items
+not in the backup are left out.
+
+Every Secret restored is then read back and checked against the backup.
+The archive is decrypted with the age identities given with --identity,
+or with the passphrase, prompted for or read from --passphrase-file.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+		inPath, _ := flags.GetString("in")
+		identityFiles, _ := flags.GetStringArray("identity")
+		passphraseFile, _ := flags.GetString("passphrase-file")
+		conflict, _ := flags.GetString("conflict")
+		selectedBoxes, _ := flags.GetStringArray("box")
+		noPolicies, _ := flags.GetBool("no-policies")
+
+		if conflict != restoreConflictSkip && conflict != restoreConflictOverwrite &&
+			conflict != restoreConflictRename {
+			fmt.Printf("\nInvalid --conflict %s. Supported: skip, overwrite, rename\n\n", conflict)
+			os.Exit(1)
+		}
+		encrypted, err := os.ReadFile(inPath)
+		if err != nil {
+			fmt.Printf("\nUnable to read %s - %v\n\n", inPath, err)
+			os.Exit(4)
+		}
+		keys := backupKeys{}
+		for _, identityFile := range identityFiles {
+			identities, err := readBackupIdentities(identityFile)
+			if err != nil {
+				fmt.Printf("\n%v\n\n", err)
+				os.Exit(1)
+			}
+			keys.Identities = append(keys.Identities, identities...)
+		}
+		if passphraseFile != "" || (len(identityFiles) == 0 && backupNeedsPassphrase(encrypted)) {
+			if keys.Passphrase, err = readPassphrase(passphraseFile, false); err != nil {
+				fmt.Printf("\nUnable to read the passphrase - %v\n\n", err)
+				os.Exit(1)
+			}
+		}
+
+		plain, err := decryptBackup(encrypted, keys)
+		archive := backupArchive{}
+		if err == nil {
+			var decompressor *gzip.Reader
+			if decompressor, err = gzip.NewReader(bytes.NewReader(plain)); err == nil {
+				err = json.NewDecoder(decompressor).Decode(&archive)
+			}
+		}
+		if err == nil && archive.Format != backupFormat {
+			err = fmt.Errorf("unsupported backup format %d", archive.Format)
+		}
+		if err != nil {
+			fmt.Printf("\nUnable to read backup %s - %v\n\n", inPath, err)
+			os.Exit(1)
+		}
+
+		fail := func(err error) {
+			fmt.Printf("\n%v\n\n", err)
+			os.Exit(3)
+		}
+		boxExists := func(name string) bool {
+			params := map[string]interface{}{}
+			params["box_id"] = name
+			_, _, err := postVaultAPI("GetBox", params)
+			return err == nil
+		}
+
+		selected := map[string]bool{}
+		for _, box := range selectedBoxes {
+			selected[box] = true
+		}
+		boxIds := map[string]string{}
+		secretIds := map[string]string{}
+		restored := []restoredSecret{}
+		for _, backed := range archive.Boxes {
+			name, _ := backed.Box["name"].(string)
+			backupBoxId, _ := backed.Box["box_id"].(string)
+			if len(selected) != 0 && !selected[name] && !selected[backupBoxId] {
+				continue
+			}
+
+			settings := map[string]interface{}{}
+			for _, field := range clonedBoxFields {
+				if value, present := backed.Box[field]; present && value != nil {
+					settings[field] = value
+				}
+			}
+			params := map[string]interface{}{}
+			params["box_id"] = name
+			live, _, err := postVaultAPI("GetBox", params)
+			exists := err == nil
+			action := "created"
+			switch {
+			case exists && conflict == restoreConflictSkip:
+				action = "kept"
+			case exists && conflict == restoreConflictOverwrite:
+				for key, value := range settings {
+					params[key] = value
+				}
+				params["box_id"] = live["box_id"]
+				params["revision"] = live["revision"]
+				if _, _, err := postVaultAPI("UpdateBox", params); err != nil {
+					fail(fmt.Errorf("Unable to update Box %s - %v", name, err))
+				}
+				action = "updated"
+			case exists && conflict == restoreConflictRename:
+				name = restoredName(name, boxExists)
+				exists = false
+				action = "restored as " + name
+			}
+			if !exists {
+				settings["name"] = name
+				if live, _, err = postVaultAPI("CreateBox", settings); err != nil {
+					fail(fmt.Errorf("Unable to create Box %s - %v", name, err))
+				}
+			}
+			boxId, _ := live["box_id"].(string)
+			boxIds[backupBoxId] = boxId
+			fmt.Printf("Box %v %s\n", backed.Box["name"], action)
+
+			for _, secret := range backed.Secrets {
+				secretName, _ := secret.Metadata["name"].(string)
+				backupSecretId, _ := secret.Metadata["secret_id"].(string)
+				secretExists := func(name string) bool {
+					params := map[string]interface{}{}
+					params["box_id"] = boxId
+					params["secret_id"] = name
+					_, _, err := postVaultAPI("GetSecretMetadata", params)
+					return err == nil
+				}
+
+				params := map[string]interface{}{}
+				params["box_id"] = boxId
+				params["secret_id"] = secretName
+				liveSecret, _, err := postVaultAPI("GetSecretMetadata", params)
+				existingId := ""
+				action := "created"
+				if err == nil {
+					existingId, _ = liveSecret["secret_id"].(string)
+					switch conflict {
+					case restoreConflictSkip:
+						secretIds[backupBoxId+"/"+backupSecretId] = existingId
+						fmt.Printf("Secret %s/%s kept\n", name, secretName)
+						continue
+					case restoreConflictOverwrite:
+						action = "updated"
+					case restoreConflictRename:
+						existingId = ""
+						secretName = restoredName(secretName, secretExists)
+						action = "restored as " + secretName
+					}
+				}
+
+				secretId, versions, err := restoreSecretVersions(boxId, secretName, existingId, secret)
+				if err != nil {
+					fail(fmt.Errorf("Unable to restore Secret %s/%s - %v", name, secretName, err))
+				}
+				if existingId != "" {
+					// bring the settings of the existing Secret to the backed up ones
+					params["secret_id"] = secretId
+					updated, _, err := postVaultAPI("GetSecretMetadata", params)
+					if err == nil {
+						params["revision"] = updated["revision"]
+						for _, field := range []string{"desc", "lease", "rotation",
+							"exclusive_checkout", "expires_at", "tags"} {
+							params[field] = secret.Metadata[field]
+						}
+						_, _, err = postVaultAPI("UpdateSecret", params)
+					}
+					if err != nil {
+						fail(fmt.Errorf("Unable to update Secret %s/%s - %v", name, secretName, err))
+					}
+				}
+				secretIds[backupBoxId+"/"+backupSecretId] = secretId
+				restored = append(restored, restoredSecret{
+					Name: name + "/" + secretName, BoxId: boxId, SecretId: secretId,
+					Versions: versions, Backup: secret,
+				})
+				fmt.Printf("Secret %v/%v %s\n", backed.Box["name"], secret.Metadata["name"], action)
+			}
+		}
+
+		if !noPolicies {
+			livePolicies, _, err := listByName("ListPolicies", map[string]interface{}{})
+			if err != nil {
+				fail(fmt.Errorf("Unable to list Policies - %v", err))
+			}
+			for _, policy := range archive.Policies {
+				name, _ := policy["name"].(string)
+				resources := []interface{}{}
+				backedResources, _ := policy["resources"].([]interface{})
+				for _, resource := range backedResources {
+					resourceMap, _ := resource.(map[string]interface{})
+					backupBoxId, _ := resourceMap["box_id"].(string)
+					boxId, restoredBox := boxIds[backupBoxId]
+					if !restoredBox {
+						continue
+					}
+					mapped := []string{}
+					ids, _ := resourceMap["secret_id"].([]interface{})
+					for _, id := range ids {
+						if id == "*" {
+							mapped = append(mapped, "*")
+						} else if secretId, present := secretIds[backupBoxId+"/"+fmt.Sprint(id)]; present {
+							mapped = append(mapped, secretId)
+						}
+					}
+					if len(mapped) != 0 {
+						resources = append(resources, map[string]interface{}{
+							"box_id": boxId, "secret_id": mapped,
+						})
+					}
+				}
+				if len(resources) == 0 {
+					continue
+				}
+
+				params := map[string]interface{}{}
+				for _, field := range []string{"desc", "role", "principals", "tags"} {
+					if value, present := policy[field]; present && value != nil {
+						params[field] = value
+					}
+				}
+				params["resources"] = resources
+				action := "created"
+				apiAction := "CreatePolicy"
+				if live, exists := livePolicies[name]; exists {
+					switch conflict {
+					case restoreConflictSkip:
+						fmt.Printf("Policy %s kept\n", name)
+						continue
+					case restoreConflictOverwrite:
+						current, _, err := postVaultAPI("GetPolicy",
+							map[string]interface{}{"policy_id": live["policy_id"]})
+						if err != nil {
+							fail(fmt.Errorf("Unable to get Policy %s - %v", name, err))
+						}
+						params["policy_id"] = live["policy_id"]
+						params["revision"] = current["revision"]
+						apiAction = "UpdatePolicy"
+						action = "updated"
+					case restoreConflictRename:
+						name = restoredName(name, func(candidate string) bool {
+							_, exists := livePolicies[candidate]
+							return exists
+						})
+						action = "restored as " + name
+					}
+				}
+				if apiAction == "CreatePolicy" {
+					params["name"] = name
+				}
+				if _, _, err := postVaultAPI(apiAction, params); err != nil {
+					fail(fmt.Errorf("Unable to restore Policy %s - %v", name, err))
+				}
+				fmt.Printf("Policy %v %s\n", policy["name"], action)
+			}
+		}
+
+		// verification pass
+		verified := 0
+		for _, secret := range restored {
+			for index, version := range secret.Backup.Versions {
+				fetched, err := fetchSecretVersion(secret.BoxId, secret.SecretId, secret.Versions[index])
+				if err != nil {
+					fail(fmt.Errorf("Verification of Secret %s failed - %v", secret.Name, err))
+				}
+				fetched.Spool.Close()
+				if !version.matches(fetched) {
+					fail(fmt.Errorf("Verification of Secret %s failed - version %d does not "+
+						"match the backup", secret.Name, secret.Versions[index]))
+				}
+				verified += 1
+			}
+		}
+		fmt.Printf("\nRestored %d Secrets, %d versions verified\n\n", len(restored), verified)
+		os.Exit(0)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+	backupCmd.Flags().StringArrayP("box", "b", []string{},
+		"Id or name of a Box to back up. This option is repeatable.")
+	backupCmd.Flags().Bool("all", false,
+		"Back up all the Boxes")
+	backupCmd.Flags().Bool("all-versions", false,
+		"Back up all the versions of each Secret, not only the current one")
+	backupCmd.Flags().StringP("out", "o", "",
+		"File to write the encrypted backup to")
+	backupCmd.Flags().Bool("force", false,
+		"Overwrite the backup file if it already exists")
+	backupCmd.Flags().StringArrayP("recipient", "r", []string{},
+		"age X25519 public key (age1...) to encrypt the backup to. "+
+			"This option is repeatable.")
+	backupCmd.Flags().String("passphrase-file", "",
+		"File holding the passphrase to encrypt the backup with, "+
+			"instead of recipients")
+	backupCmd.MarkFlagRequired("out")
+
+	rootCmd.AddCommand(restoreCmd)
+	restoreCmd.Flags().StringP("in", "i", "",
+		"Backup file to restore")
+	restoreCmd.Flags().StringArrayP("identity", "I", []string{},
+		"File holding age identities (AGE-SECRET-KEY-1...) to decrypt the "+
+			"backup with. This option is repeatable.")
+	restoreCmd.Flags().String("passphrase-file", "",
+		"File holding the passphrase to decrypt the backup with")
+	restoreCmd.Flags().String("conflict", restoreConflictSkip,
+		"What to do with items which already exist: skip, overwrite or rename")
+	restoreCmd.Flags().StringArrayP("box", "b", []string{},
+		"Restore only this Box of the backup, given by id or name. "+
+			"This option is repeatable.")
+	restoreCmd.Flags().Bool("no-policies", false,
+		"Don't restore Policies")
+	restoreCmd.MarkFlagRequired("in")
+}
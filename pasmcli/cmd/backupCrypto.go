@@ -0,0 +1,150 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// Backups are age files (age-encryption.org/v1), so they can also be
+// opened with age -d and the same identity or passphrase.
+const (
+	backupMagic = "age-encryption.org/v1\n"
+
+	// first line of the header stanza wrapping the file key with a
+	// passphrase
+	backupScryptStanza = "-> scrypt "
+)
+
+// backupKeys are what an archive is encrypted to, or decrypted with. age
+// does not allow a passphrase along with recipients.
+type backupKeys struct {
+	Recipients []age.Recipient
+	Identities []age.Identity
+	Passphrase string
+}
+
+// parseBackupRecipients parses age X25519 public keys, age1...
+func parseBackupRecipients(recipients []string) ([]age.Recipient, error) {
+	parsed := []age.Recipient{}
+	for _, recipient := range recipients {
+		key, err := age.ParseX25519Recipient(recipient)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid recipient %s - %v", recipient, err)
+		}
+		parsed = append(parsed, key)
+	}
+	return parsed, nil
+}
+
+// readBackupIdentities reads age identities, one per line, skipping
+// comments and blank lines as age-keygen writes them
+func readBackupIdentities(path string) ([]age.Identity, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	identities, err := age.ParseIdentities(file)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid identity in %s - %v", path, err)
+	}
+	return identities, nil
+}
+
+// encryptBackup writes the archive encrypted to the recipients, or with the
+// passphrase
+func encryptBackup(out io.Writer, archive []byte, keys backupKeys) error {
+	recipients := keys.Recipients
+	if keys.Passphrase != "" {
+		if len(recipients) != 0 {
+			return fmt.Errorf("a passphrase can't be used along with recipients")
+		}
+		recipient, err := age.NewScryptRecipient(keys.Passphrase)
+		if err != nil {
+			return err
+		}
+		recipients = []age.Recipient{recipient}
+	}
+	if len(recipients) == 0 {
+		return fmt.Errorf("no recipient or passphrase to encrypt to")
+	}
+
+	writer, err := age.Encrypt(out, recipients...)
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(archive); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// decryptBackup reads an encrypted archive with any of the keys
+func decryptBackup(in []byte, keys backupKeys) ([]byte, error) {
+	if !bytes.HasPrefix(in, []byte(backupMagic)) {
+		return nil, fmt.Errorf("not a pasmcli backup")
+	}
+	identities := append([]age.Identity{}, keys.Identities...)
+	if keys.Passphrase != "" {
+		identity, err := age.NewScryptIdentity(keys.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("no identity or passphrase given")
+	}
+
+	reader, err := age.Decrypt(bytes.NewReader(in), identities...)
+	var noMatch *age.NoIdentityMatchError
+	switch {
+	case errors.As(err, &noMatch):
+		return nil, fmt.Errorf("wrong passphrase or identity")
+	case err != nil:
+		return nil, fmt.Errorf("invalid backup header - %v", err)
+	}
+	archive, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("backup is corrupted or was tampered with")
+	}
+	return archive, nil
+}
+
+// backupNeedsPassphrase tells if an archive can be decrypted with a
+// passphrase
+func backupNeedsPassphrase(in []byte) bool {
+	header, err := age.ExtractHeader(bytes.NewReader(in))
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(header), "\n") {
+		if strings.HasPrefix(line, backupScryptStanza) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,438 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	bulkStatusDone   = "done"
+	bulkStatusDryRun = "dry-run"
+	bulkStatusFailed = "failed"
+)
+
+// bulkResult is the outcome of a bulk operation on one Secret
+type bulkResult struct {
+	SecretId string `json:"secret_id"`
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Attempts int    `json:"attempts,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// rateLimiter spaces API requests out to at most rate per second, shared by
+// all the workers. A zero rate doesn't limit.
+type rateLimiter struct {
+	ticker *time.Ticker
+}
+
+func newRateLimiter(rate float64) *rateLimiter {
+	if rate <= 0 {
+		return &rateLimiter{}
+	}
+	return &rateLimiter{ticker: time.NewTicker(time.Duration(float64(time.Second) / rate))}
+}
+
+func (l *rateLimiter) wait() {
+	if l.ticker != nil {
+		<-l.ticker.C
+	}
+}
+
+func (l *rateLimiter) stop() {
+	if l.ticker != nil {
+		l.ticker.Stop()
+	}
+}
+
+// revisionConflictMessage is the error the Vault returns when the revision
+// of an update is not the current one
+const revisionConflictMessage = "revision mismatch"
+
+// isRevisionConflict tells if an API error is about a stale revision
+func isRevisionConflict(retStatus int, err error) bool {
+	return retStatus == http.StatusConflict ||
+		strings.Contains(strings.ToLower(err.Error()), revisionConflictMessage)
+}
+
+// matchesTags tells if tags has every selector, given as KEY or KEY=VALUE
+func matchesTags(tags map[string]interface{}, selectors []string) bool {
+	for _, selector := range selectors {
+		key, value, hasValue := strings.Cut(selector, "=")
+		tag, present := tags[key]
+		if !present {
+			return false
+		}
+		if hasValue && fmt.Sprint(tag) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// listedSecretTags returns the tags of a listed Secret. Some listings leave
+// tags out, they are then read from the Secret metadata and kept in secret.
+func listedSecretTags(secret map[string]interface{}, limiter *rateLimiter) (map[string]interface{}, error) {
+	if _, listed := secret["tags"]; !listed {
+		params := map[string]interface{}{}
+		params["box_id"] = secret["box_id"]
+		params["secret_id"] = secret["secret_id"]
+		limiter.wait()
+		metadata, _, err := postVaultAPI("GetSecretMetadata", params)
+		if err != nil {
+			return nil, err
+		}
+		secret["tags"] = metadata["tags"]
+	}
+	tags, _ := secret["tags"].(map[string]interface{})
+	return tags, nil
+}
+
+// bulkSecrets lists the Secrets of the Box matching the selection flags
+func bulkSecrets(cmd *cobra.Command, limiter *rateLimiter) ([]map[string]interface{}, error) {
+	flags := cmd.Flags()
+	boxid, _ := flags.GetString("boxid")
+	tagSelectors, _ := flags.GetStringArray("match-tag")
+
+	params := map[string]interface{}{}
+	params["box_id"] = boxid
+	if flags.Changed("filter") {
+		filter, _ := flags.GetString("filter")
+		params["filters"] = filter
+	}
+	if flags.Changed("prefix") {
+		prefix, _ := flags.GetString("prefix")
+		params["prefix"] = prefix
+	}
+
+	secrets := []map[string]interface{}{}
+	_, _, err := ListAllPages("ListSecrets", params, ListPaging{All: true},
+		func(item interface{}) error {
+			secret, isMap := item.(map[string]interface{})
+			if !isMap {
+				return nil
+			}
+			if len(tagSelectors) != 0 {
+				tags, err := listedSecretTags(secret, limiter)
+				if err != nil {
+					return err
+				}
+				if !matchesTags(tags, tagSelectors) {
+					return nil
+				}
+			}
+			secrets = append(secrets, secret)
+			return nil
+		})
+	if err == errListNotFound {
+		err = nil
+	}
+	return secrets, err
+}
+
+// runBulk posts action with params to every Secret selected, from
+// concurrent workers. The revision is read just before each request, and
+// read again when the Secret changed in between.
+func runBulk(cmd *cobra.Command, action string, params map[string]interface{}) {
+	flags := cmd.Flags()
+	dryRun, _ := flags.GetBool("dry-run")
+	concurrency, _ := flags.GetInt("concurrency")
+	rate, _ := flags.GetFloat64("rate")
+	retries, _ := flags.GetInt("retries")
+	if concurrency < 1 {
+		fmt.Println("--concurrency must be at least 1")
+		os.Exit(1)
+	}
+
+	limiter := newRateLimiter(rate)
+	defer limiter.stop()
+	secrets, err := bulkSecrets(cmd, limiter)
+	if err != nil {
+		ExitOnListError(err, "Secrets not found")
+	}
+
+	results := make([]bulkResult, len(secrets))
+	apply := func(index int) {
+		secret := secrets[index]
+		result := &results[index]
+		result.SecretId, _ = secret["secret_id"].(string)
+		result.Name, _ = secret["name"].(string)
+		if dryRun {
+			result.Status = bulkStatusDryRun
+			return
+		}
+
+		request := map[string]interface{}{}
+		for key, value := range params {
+			request[key] = value
+		}
+		request["box_id"] = secret["box_id"]
+		request["secret_id"] = result.SecretId
+		var err error
+		for result.Attempts < retries+1 {
+			result.Attempts += 1
+			limiter.wait()
+			var metadata map[string]interface{}
+			metadata, _, err = postVaultAPI("GetSecretMetadata", map[string]interface{}{
+				"box_id":    request["box_id"],
+				"secret_id": request["secret_id"],
+			})
+			if err != nil {
+				break
+			}
+			request["revision"] = metadata["revision"]
+			limiter.wait()
+			var retStatus int
+			_, retStatus, err = postVaultAPI(action, request)
+			if err == nil || !isRevisionConflict(retStatus, err) {
+				break
+			}
+		}
+		if err != nil {
+			result.Status = bulkStatusFailed
+			result.Error = err.Error()
+			return
+		}
+		result.Status = bulkStatusDone
+	}
+
+	indexes := make(chan int)
+	var workers sync.WaitGroup
+	for worker := 0; worker < concurrency; worker += 1 {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for index := range indexes {
+				apply(index)
+			}
+		}()
+	}
+	for index := range secrets {
+		indexes <- index
+	}
+	close(indexes)
+	workers.Wait()
+
+	failed := 0
+	for _, result := range results {
+		if result.Status == bulkStatusFailed {
+			failed += 1
+		}
+	}
+	report, err := JSONMarshalIndent(map[string]interface{}{"results": results})
+	if err != nil {
+		fmt.Println("Error building JSON output: ", err)
+		os.Exit(4)
+	}
+	PrintResponse(cmd, strings.TrimSpace(string(report)))
+	fmt.Fprintf(os.Stderr, "%d Secrets matched, %d failed\n", len(secrets), failed)
+	if failed != 0 {
+		os.Exit(3)
+	}
+	os.Exit(0)
+}
+
+// bulkOption turns an enable, disable or unset flag value into true, false
+// or nil, as update-secret does
+func bulkOption(cmd *cobra.Command, name string) (interface{}, bool) {
+	if !cmd.Flags().Changed(name) {
+		return nil, false
+	}
+	value, _ := cmd.Flags().GetString(name)
+	switch value {
+	case "enable":
+		return true, true
+	case "disable":
+		return false, true
+	case "unset":
+		return nil, true
+	}
+	fmt.Printf("\nInvalid --%s option %s. Supported: enable, disable, unset\n", name, value)
+	os.Exit(1)
+	return nil, false
+}
+
+// bulkUnset turns "unset" into nil, as update-secret does
+func bulkUnset(value string) interface{} {
+	if value == "unset" {
+		return nil
+	}
+	return value
+}
+
+// bulkCmd represents the bulk command
+var bulkCmd = &cobra.Command{
+	Use:   "bulk",
+	Short: "Tag, untag or update many Secrets of a Box at once",
+	Long: `Tag, untag or update all the Secrets of a Box matching --filter, --prefix
+and --match-tag. Secrets are processed concurrently, within --rate requests
+per second. Revision conflicts are retried with the new revision. A result
+is reported for each Secret, use --dry-run to only list the Secrets
+matched.`,
+}
+
+// bulkTagCmd represents the bulk tag command
+var bulkTagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "Tag the Secrets matched",
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+		tagkeyArray, _ := flags.GetStringArray("tagkey")
+		tagvalueArray, _ := flags.GetStringArray("tagvalue")
+		if len(tagkeyArray) != len(tagvalueArray) {
+			fmt.Println("Please provide equal number of tag keys & values")
+			os.Exit(1)
+		}
+
+		tagParams := map[string]interface{}{}
+		for i := 0; i < len(tagvalueArray); i += 1 {
+			if IsJSON(tagvalueArray[i]) {
+				tagParams[tagkeyArray[i]] = JsonStrToMap(tagvalueArray[i])
+			} else {
+				tagParams[tagkeyArray[i]] = tagvalueArray[i]
+			}
+		}
+		runBulk(cmd, "TagSecret", map[string]interface{}{"tags": tagParams})
+	},
+}
+
+// bulkUntagCmd represents the bulk untag command
+var bulkUntagCmd = &cobra.Command{
+	Use:   "untag",
+	Short: "Remove tags from the Secrets matched",
+	Run: func(cmd *cobra.Command, args []string) {
+		tagkeyArray, _ := cmd.Flags().GetStringArray("tagkey")
+		runBulk(cmd, "UntagSecret", map[string]interface{}{"tags": tagkeyArray})
+	},
+}
+
+// bulkUpdateCmd represents the bulk update command
+var bulkUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Update the Secrets matched",
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+		params := map[string]interface{}{}
+
+		if flags.Changed("description") {
+			description, _ := flags.GetString("description")
+			params["desc"] = bulkUnset(description)
+		}
+		if flags.Changed("lease-duration") {
+			leaseDuration, _ := flags.GetString("lease-duration")
+			params["lease"] = map[string]interface{}{"duration": bulkUnset(leaseDuration)}
+		}
+
+		rotationParams := map[string]interface{}{}
+		if flags.Changed("rotation-duration") {
+			rotationDuration, _ := flags.GetString("rotation-duration")
+			rotationParams["duration"] = bulkUnset(rotationDuration)
+		}
+		if force, set := bulkOption(cmd, "rotation-force"); set {
+			rotationParams["force"] = force
+		}
+		if onCheckin, set := bulkOption(cmd, "rotation-on-checkin"); set {
+			rotationParams["on_checkin"] = onCheckin
+		}
+		if len(rotationParams) != 0 {
+			params["rotation"] = rotationParams
+		}
+
+		if exclusiveCheckout, set := bulkOption(cmd, "exclusive-checkout"); set {
+			params["exclusive_checkout"] = exclusiveCheckout
+		}
+		if flags.Changed("expires-at") {
+			expiresAt, _ := flags.GetString("expires-at")
+			params["expires_at"] = bulkUnset(expiresAt)
+		}
+
+		if len(params) == 0 {
+			fmt.Println("Nothing to update, specify at least one field to update")
+			os.Exit(1)
+		}
+		runBulk(cmd, "UpdateSecret", params)
+	},
+}
+
+func addBulkFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("boxid", "b", "",
+		"Id or name of the Box whose Secrets to process")
+	cmd.Flags().StringP("filter", "l", "",
+		"Conditional expression selecting the Secrets, as for list-secrets")
+	cmd.Flags().StringP("prefix", "p", "",
+		"Process only the Secrets whose name starts with this string")
+	cmd.Flags().StringArray("match-tag", []string{},
+		"Process only the Secrets with this tag, given as KEY or KEY=VALUE. "+
+			"This option is repeatable, all the tags must match.")
+	cmd.Flags().Bool("dry-run", false,
+		"Only list the Secrets which would be processed")
+	cmd.Flags().Int("concurrency", 4,
+		"Number of Secrets processed at the same time")
+	cmd.Flags().Float64("rate", 10,
+		"Maximum number of API requests per second, 0 for no limit")
+	cmd.Flags().Int("retries", 3,
+		"Number of times a Secret is retried after a revision conflict")
+
+	// mark mandatory fields as required
+	cmd.MarkFlagRequired("boxid")
+}
+
+func init() {
+	rootCmd.AddCommand(bulkCmd)
+
+	bulkCmd.AddCommand(bulkTagCmd)
+	addBulkFlags(bulkTagCmd)
+	bulkTagCmd.Flags().StringArrayP("tagkey", "t", []string{},
+		"Tag key to associate with the Secrets. This option is repeatable.")
+	bulkTagCmd.Flags().StringArrayP("tagvalue", "v", []string{},
+		"Tag value to associate with the Secrets. This option is repeatable.")
+	bulkTagCmd.MarkFlagRequired("tagkey")
+	bulkTagCmd.MarkFlagRequired("tagvalue")
+
+	bulkCmd.AddCommand(bulkUntagCmd)
+	addBulkFlags(bulkUntagCmd)
+	bulkUntagCmd.Flags().StringArrayP("tagkey", "t", []string{},
+		"Tag key to remove from the Secrets. This option is repeatable.")
+	bulkUntagCmd.MarkFlagRequired("tagkey")
+
+	bulkCmd.AddCommand(bulkUpdateCmd)
+	addBulkFlags(bulkUpdateCmd)
+	bulkUpdateCmd.Flags().StringP("description", "d", "",
+		"Short description for the Secrets. \"unset\" to clear.")
+	bulkUpdateCmd.Flags().String("lease-duration", "",
+		"Lease duration to enforce for the Secrets. \"unset\" to clear.")
+	bulkUpdateCmd.Flags().StringP("rotation-duration", "r", "",
+		"Duration on which the Secrets will be rotated. \"unset\" to clear.")
+	bulkUpdateCmd.Flags().StringP("rotation-force", "f", "",
+		"Force rotation of the Secrets. Supports one of enable, disable or unset.")
+	bulkUpdateCmd.Flags().String("rotation-on-checkin", "",
+		"Attempt rotation of the Secrets on Checkin. Supports one of enable, "+
+			"disable or unset.")
+	bulkUpdateCmd.Flags().StringP("exclusive-checkout", "x", "",
+		"Make all checkouts of the Secrets exclusive. Supports one of enable, "+
+			"disable or unset.")
+	bulkUpdateCmd.Flags().StringP("expires-at", "e", "",
+		"Expiration time in RFC 3339 format. \"unset\" to clear.")
+}
@@ -142,7 +142,7 @@ var checkinSecretCmd = &cobra.Command{
                 fmt.Println("\nCheckin successful\n")
                 os.Exit(0)
             } else {
-                fmt.Println("\n" + retStr + "\n")
+                PrintResponse(cmd, retStr)
                 // make a decision on what to exit with
                 retMap := JsonStrToMap(retStr)
                 if _, present := retMap["error"]; present {
@@ -158,6 +158,7 @@ var checkinSecretCmd = &cobra.Command{
 
 func init() {
     rootCmd.AddCommand(checkinSecretCmd)
+    acceptSecretRef(checkinSecretCmd)
     checkinSecretCmd.Flags().StringP("leaseid", "l", "",
                                  "Lease Id with which to Checkin the Secret")
     checkinSecretCmd.Flags().StringP("lease-file", "f", "",
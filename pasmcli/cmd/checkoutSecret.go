@@ -20,9 +20,10 @@ import (
     // standard
     "os"
     "fmt"
+    "io"
     "time"
-    "bytes"
     "encoding/json"
+    "errors"
     // external
     "github.com/spf13/cobra"
 )
@@ -185,6 +186,13 @@ func processRespInfo(retMap map[string]interface{}, printResp bool) lease_Info {
     return lInfo
 }
 
+// doCheckoutSecret posts the CheckoutSecret request and returns the
+// HTTP status along with the response body. The content of file Secrets is
+// spooled to disk rather than returned in the body.
+func doCheckoutSecret(jsonParams []byte) (int, string, *secretSpool, error) {
+    return postSecretAPI("CheckoutSecret", jsonParams)
+}
+
 // checkoutSecretCmd represents the checkout-secret command
 var checkoutSecretCmd = &cobra.Command{
     Use:   "checkout-secret",
@@ -200,6 +208,64 @@ var checkoutSecretCmd = &cobra.Command{
             os.Exit(1)
         }
 
+        wait, _ := flags.GetBool("wait")
+        waitTimeout, _ := flags.GetDuration("wait-timeout")
+        if (flags.Changed("wait-timeout") && !wait) {
+            fmt.Println("--wait-timeout is applicable only with --wait")
+            os.Exit(1)
+        }
+
+        fileOutput, err := getFileSecretOutput(flags)
+        if err != nil {
+            fmt.Println(err)
+            os.Exit(1)
+        }
+        // -o of any format shows the response like json-output
+        jsonOutput, _ := flags.GetBool("json-output")
+        jsonOutputRequested := jsonOutput || GetOutputFormat(cmd) != ""
+        if (fileOutput.Stdout && jsonOutputRequested) {
+            fmt.Println("Cannot set both output and stdout")
+            os.Exit(1)
+        }
+        valueSelector, err := getSecretValueSelector(flags)
+        if err != nil {
+            fmt.Println(err)
+            os.Exit(1)
+        }
+        if (valueSelector.isRequested() &&
+            (jsonOutputRequested || fileOutput.isRequested())) {
+            fmt.Println("Cannot set field, jsonpath or raw along with output " +
+                        "or file output options")
+            os.Exit(1)
+        }
+        // messages go to stderr when the Secret value goes to stdout
+        var msgOut io.Writer = os.Stdout
+        if (fileOutput.Stdout || valueSelector.isRequested()) {
+            msgOut = os.Stderr
+            InsecureBannerOutput = os.Stderr
+        }
+
+        ttl, _ := flags.GetDuration("ttl")
+        if (flags.Changed("ttl") && (dontSaveLease || ttl <= 0)) {
+            if (dontSaveLease) {
+                fmt.Println("Cannot set both dont-save-lease and ttl")
+            } else {
+                fmt.Println("ttl must be a positive duration, e.g. 30m")
+            }
+            os.Exit(1)
+        }
+        if (ttl > 0) {
+            // the check in is done with the current access token, which must
+            // outlive the TTL
+            sessionEnd := GetAccessTokenExpiration()
+            if (!sessionEnd.IsZero() && sessionEnd.Before(time.Now().Add(ttl))) {
+                fmt.Printf("\nThe login session expires at %s, before the TTL elapses. " +
+                           "Renew the session or use a shorter ttl.\n\n",
+                           TwelveHourTime(sessionEnd))
+                os.Exit(1)
+            }
+        }
+
         
         
 
@@ -224,24 +290,26 @@ var checkoutSecretCmd = &cobra.Command{
             os.Exit(1)
         }
 
-        // now POST
-        endpoint := GetEndPoint("", "1.0", "CheckoutSecret")
-        ret, err := DoPost(endpoint,
-                               GetCACertFile(),
-                               AuthTokenKV(),
-                               jsonParams,
-                               "application/json")
+        // now POST, waiting for the Secret to become available if asked to
+        var retStatus int
+        var retStr string
+        var spool *secretSpool
+        if wait {
+            retStatus, retStr, spool, err = waitAndCheckoutSecret(boxid, secretId,
+                                                                  jsonParams, waitTimeout)
+        } else {
+            retStatus, retStr, spool, err = doCheckoutSecret(jsonParams)
+        }
+        if errors.Is(err, errCheckoutWaitTimeout) {
+            fmt.Fprintf(os.Stderr, "\nSecret is not available - %v\n\n", err)
+            os.Exit(5)
+        }
         if err != nil {
             fmt.Printf("\nHTTP request failed: %s\n", err)
             os.Exit(4)
         } else {
-            // type assertion
-            retBytes := ret["data"].(*bytes.Buffer)
-            retStatus := ret["status"].(int)
-            retStr := retBytes.String()
-
             if retStatus != 200 {
-                fmt.Println("\n" + retStr + "\n")
+                PrintResponse(cmd, retStr)
                 os.Exit(5)
             }
 
@@ -249,10 +317,15 @@ var checkoutSecretCmd = &cobra.Command{
             retMap := JsonStrToMap(retStr)
             secret_subtype_info := retMap["secret_subtype_info"].(map[string]interface{})
 
-            JSONOutput, _ := flags.GetBool("json-output")
+            JSONOutput := jsonOutputRequested
             // expected raw output, print
             if (JSONOutput) {
-                fmt.Println("\n" + retStr + "\n")
+                jsonStr, err := inlineSpooledSecret(retStr, spool)
+                if err != nil {
+                    fmt.Printf("\nError reading Secret data - %v\n", err)
+                    os.Exit(4)
+                }
+                PrintResponse(cmd, jsonStr)
             }
 
             if retVal, present := retMap["error"]; present {
@@ -263,14 +336,16 @@ var checkoutSecretCmd = &cobra.Command{
             } else {
                 if isSecretFile(secret_subtype_info) {
                     if (JSONOutput) {
-                        fmt.Println("This is a file secret, secret_data above contains " +
+                        fmt.Fprintln(os.Stderr, "This is a file secret, secret_data above contains " +
 			            "base64 of file secret. Do a base64 decode " +
 				    "to get actual file content.\n")
                     }
                 }
 
                 if (!JSONOutput) {
-                    lInfo = processRespInfo(retMap, true)
+                    // file content goes to stdout, keep it clean
+                    lInfo = processRespInfo(retMap,
+                                            !fileOutput.Stdout && !valueSelector.isRequested())
                 } else {
                     if (!dontSaveLease) {
                         // we call processRespInfo() with printResp set to
@@ -313,27 +388,60 @@ var checkoutSecretCmd = &cobra.Command{
                         os.Exit(4)
                     }
 
-                    fmt.Printf("\nLease id saved in %s. Pass this file if checking in " +
-                               "the secret with --lease-file option.\n", leaseFile)
+                    fmt.Fprintf(msgOut, "\nLease id saved in %s. Pass this file if checking in " +
+                                "the secret with --lease-file option.\n", leaseFile)
+
+                    if (ttl > 0) {
+                        checkinAt, err := startAutoCheckin(leaseFile, ttl)
+                        if err != nil {
+                            fmt.Printf("\nError starting automatic check in - %v\n", err)
+                            os.Exit(4)
+                        }
+                        fmt.Fprintf(msgOut, "\nSecret will be checked in automatically at %s. " +
+                                    "Run checkin-now to check it in earlier.\n",
+                                    TwelveHourTime(checkinAt))
+                    }
+                } else if (ttl > 0) {
+                    fmt.Fprintln(msgOut, "\nNo lease returned for the Secret, nothing to check in " +
+                                 "automatically")
+                }
+                if (valueSelector.isRequested()) {
+                    err := printSecretValue(valueSelector, retMap, spool)
+                    if err != nil {
+                        fmt.Fprintf(os.Stderr, "\n%v\n", err)
+                        spool.Close()
+                        os.Exit(5)
+                    }
+                    spool.Close()
+                    os.Exit(0)
                 }
                 if isSecretFile(secret_subtype_info) {
                     filename := getFilename(secret_subtype_info)
-                    b64content := retMap["secret_data"].(string)
-                    content := B64Decode(b64content)
-                    f, err := os.Create(filename)
-                    if err != nil {
-                        fmt.Printf("\nUnable to write to %s\n", filename)
-                        os.Exit(4)
+                    content, err := spool.content()
+                    if err == nil {
+                        var path, sum string
+                        var size int64
+                        path, size, sum, err = writeFileSecret(fileOutput, filename, content)
+                        if err == nil {
+                            fmt.Fprintf(msgOut, "\nSuccessfully downloaded %s to %s\nSize: %d bytes\nSHA-256: %s\n",
+                                        filename, path, size, sum)
+                            if !flags.Changed("version") {
+                                if check := checkFileSecretDigest(retMap, sum); check != "" {
+                                    fmt.Fprintln(msgOut, check)
+                                }
+                            }
+                        }
                     }
-                    defer f.Close()
-                    _, err2 := f.WriteString(content)
-                    if err2 != nil {
-                        fmt.Printf("\nUnable to write to %s\n", filename)
+                    if err != nil {
+                        fmt.Fprintf(msgOut, "\nUnable to write file Secret %s - %v\n", filename, err)
+                        spool.Close()
                         os.Exit(4)
                     }
-                    fmt.Printf("\nSuccessfully downloaded %s\n", filename)
+                } else if fileOutput.isRequested() {
+                    fmt.Fprintln(os.Stderr, "\nFile output options are ignored, this is not a file Secret")
                 }
-                fmt.Println()
+                spool.Close()
+                fmt.Fprintln(msgOut)
                 os.Exit(0)
             }
         }
@@ -342,6 +450,7 @@ var checkoutSecretCmd = &cobra.Command{
 
 func init() {
     rootCmd.AddCommand(checkoutSecretCmd)
+    acceptSecretRef(checkoutSecretCmd)
     checkoutSecretCmd.Flags().StringP("boxid", "b", "",
                                  "Id or name of the Box where the Secret is")
     checkoutSecretCmd.Flags().StringP("secretid", "s", "",
@@ -351,6 +460,7 @@ func init() {
                               "specified, fetch the latest version")
     checkoutSecretCmd.Flags().BoolP("json-output", "j", false,
                                  "Show JSON formatted output")
+    checkoutSecretCmd.Flags().MarkDeprecated("json-output", "use -o json")
     checkoutSecretCmd.Flags().StringP("lease-file", "l", "",
                                     "File to save lease details to, on successful " +
                                     "checkout, if lease id present. If this option " +
@@ -359,6 +469,22 @@ func init() {
                                     "by default at $HOMEDIR/vault.data/vault_lease_<id>.txt")
     checkoutSecretCmd.Flags().BoolP("dont-save-lease", "d", false,
                                     "Do not save lease info in a file")
+    addFileSecretOutputFlags(checkoutSecretCmd)
+    addSecretValueFlags(checkoutSecretCmd)
+    checkoutSecretCmd.Flags().BoolP("wait", "w", false,
+                                    "If the Secret is exclusively checked out by " +
+                                    "someone else, wait for the lease to be released " +
+                                    "instead of failing. Checkouts waiting on the same " +
+                                    "host are served in the order they started.")
+    checkoutSecretCmd.Flags().DurationP("ttl", "t", 0,
+                                        "Check in the Secret automatically once " +
+                                        "this duration elapses, e.g. 30m. A background " +
+                                        "process does the check in, even if this " +
+                                        "terminal is closed. Use checkin-now to check " +
+                                        "in earlier.")
+    checkoutSecretCmd.Flags().DurationP("wait-timeout", "W", DefaultCheckoutWaitTimeout,
+                                        "Maximum time to wait for the Secret with " +
+                                        "--wait, e.g. 15m or 1h")
 
     // mark mandatory fields as required
     checkoutSecretCmd.MarkFlagRequired("boxid")
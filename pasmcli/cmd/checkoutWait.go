@@ -0,0 +1,301 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	CheckoutQueueSubdir        = "checkout_queue"
+	checkoutWaitInitialBackoff = 2 * time.Second
+	checkoutWaitMaxBackoff     = 30 * time.Second
+	DefaultCheckoutWaitTimeout = 15 * time.Minute
+	// tickets are refreshed on every poll, so a ticket older than this
+	// belongs to a waiter that was killed and is removed from the queue
+	checkoutQueueStaleTicketAge = 4 * checkoutWaitMaxBackoff
+)
+
+// errCheckoutWaitTimeout is returned when the Secret was not available
+// before the wait timeout
+var errCheckoutWaitTimeout = errors.New("timed out waiting for the Secret")
+
+// checkoutQueue is a host local FIFO queue of pasmcli processes waiting for
+// the same Secret. Each waiter owns a ticket file named after its arrival
+// time, and only the waiter holding the oldest ticket attempts the checkout.
+type checkoutQueue struct {
+	dir    string
+	ticket string
+}
+
+func joinCheckoutQueue(boxId string, secretId string) (*checkoutQueue, error) {
+	vaultDataDir, err := GetDataDir()
+	if err != nil {
+		return nil, err
+	}
+
+	identifier := fmt.Sprintf("%s|%s", boxId, secretId)
+	queueDir := filepath.Join(vaultDataDir, CheckoutQueueSubdir,
+		base64.RawURLEncoding.EncodeToString([]byte(identifier)))
+	if err := os.MkdirAll(queueDir, 0700); err != nil {
+		return nil, err
+	}
+
+	queue := &checkoutQueue{
+		dir:    queueDir,
+		ticket: fmt.Sprintf("%020d-%d", time.Now().UnixNano(), os.Getpid()),
+	}
+	return queue, queue.refresh()
+}
+
+// refresh marks the ticket as belonging to a live waiter
+func (q *checkoutQueue) refresh() error {
+	ticketFile := filepath.Join(q.dir, q.ticket)
+	now := time.Now()
+	if err := os.Chtimes(ticketFile, now, now); err == nil {
+		return nil
+	}
+	// ticket is missing, probably removed as stale while we were suspended,
+	// recreate it keeping our original place in the queue
+	return os.WriteFile(ticketFile, []byte{}, 0600)
+}
+
+// position returns the number of live waiters ahead of us
+func (q *checkoutQueue) position() (int, error) {
+	if err := q.refresh(); err != nil {
+		return 0, err
+	}
+
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return 0, err
+	}
+
+	tickets := []string{}
+	for _, entry := range entries {
+		if entry.Name() == q.ticket {
+			tickets = append(tickets, entry.Name())
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if time.Since(info.ModTime()) > checkoutQueueStaleTicketAge {
+			os.Remove(filepath.Join(q.dir, entry.Name()))
+			continue
+		}
+		tickets = append(tickets, entry.Name())
+	}
+
+	sort.Strings(tickets)
+	return sort.SearchStrings(tickets, q.ticket), nil
+}
+
+func (q *checkoutQueue) leave() {
+	os.Remove(filepath.Join(q.dir, q.ticket))
+}
+
+// secretLease is an item of the ListLeasesBySecret response
+type secretLease struct {
+	LeaseId   string `json:"lease_id"`
+	User      string `json:"user"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+func listSecretLeases(boxId string, secretId string) ([]secretLease, error) {
+	params := map[string]interface{}{}
+	params["box_id"] = boxId
+	params["secret_id"] = secretId
+
+	retMap, _, err := postVaultAPI("ListLeasesBySecret", params)
+	if err != nil {
+		return nil, err
+	}
+	_, items := ListItemsFromResponse(retMap)
+	leases := []secretLease{}
+	for _, item := range items {
+		encoded, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		lease := secretLease{}
+		if err := json.Unmarshal(encoded, &lease); err != nil {
+			return nil, fmt.Errorf("unexpected lease %s - %v", encoded, err)
+		}
+		leases = append(leases, lease)
+	}
+	return leases, nil
+}
+
+// describeLease returns who holds the lease and when it expires
+func describeLease(lease secretLease) string {
+	holder := lease.User
+	if holder == "" {
+		holder = "unknown user"
+	}
+	description := "held by " + holder
+	if lease.ExpiresAt != "" {
+		expiresAt := lease.ExpiresAt
+		expiration, err := time.Parse(time.RFC3339, expiresAt)
+		if err == nil {
+			expiresAt = TwelveHourTime(expiration.In(convertUTCtoLocal()))
+		}
+		description += ", expires at " + expiresAt
+	}
+	return description
+}
+
+// isExclusiveCheckout tells if checkouts of the Secret are exclusive, as
+// set on the Secret or else on its Box
+func isExclusiveCheckout(boxId string, secretId string) (bool, error) {
+	params := map[string]interface{}{}
+	params["box_id"] = boxId
+	params["secret_id"] = secretId
+	metadata, _, err := postVaultAPI("GetSecretMetadata", params)
+	if err != nil {
+		return false, err
+	}
+	if exclusive, isSet := metadata["exclusive_checkout"].(bool); isSet {
+		return exclusive, nil
+	}
+	delete(params, "secret_id")
+	box, _, err := postVaultAPI("GetBox", params)
+	if err != nil {
+		return false, err
+	}
+	exclusive, _ := box["exclusive_checkout"].(bool)
+	return exclusive, nil
+}
+
+// activeLeases returns the leases of the Secret which have not expired
+func activeLeases(boxId string, secretId string) ([]secretLease, error) {
+	leases, err := listSecretLeases(boxId, secretId)
+	if err != nil {
+		return nil, err
+	}
+	active := []secretLease{}
+	for _, lease := range leases {
+		expiration, err := time.Parse(time.RFC3339, lease.ExpiresAt)
+		if err == nil && !expiration.After(time.Now()) {
+			continue
+		}
+		active = append(active, lease)
+	}
+	return active, nil
+}
+
+// waitAndCheckoutSecret retries CheckoutSecret with backoff while the Secret
+// is exclusively checked out by another lease, until timeout elapses. Other
+// failures are returned at once. Waiters on this host are served in arrival
+// order. The last checkout response is returned, or errCheckoutWaitTimeout.
+func waitAndCheckoutSecret(boxId string,
+	secretId string,
+	jsonParams []byte,
+	timeout time.Duration) (int, string, *secretSpool, error) {
+
+	queue, err := joinCheckoutQueue(boxId, secretId)
+	if err != nil {
+		return 0, "", nil, fmt.Errorf("Error joining local checkout queue - %v", err)
+	}
+	defer queue.leave()
+
+	deadline := time.Now().Add(timeout)
+	backoff := checkoutWaitInitialBackoff
+	lastStatus := ""
+	retStatus, retStr := 0, ""
+	unheldFailures := 0
+	for {
+		var status string
+		position, err := queue.position()
+		if err != nil {
+			return 0, "", nil, fmt.Errorf("Error reading local checkout queue - %v", err)
+		}
+
+		if position == 0 {
+			var spool *secretSpool
+			retStatus, retStr, spool, err = doCheckoutSecret(jsonParams)
+			if err != nil || retStatus == 200 {
+				return retStatus, retStr, spool, err
+			}
+			spool.Close()
+
+			// the checkout is only waited for when another lease holds
+			// the Secret exclusively
+			exclusive, err := isExclusiveCheckout(boxId, secretId)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Unable to get the checkout settings of the Secret - %v\n", err)
+				return retStatus, retStr, nil, nil
+			}
+			if !exclusive {
+				return retStatus, retStr, nil, nil
+			}
+			leases, err := activeLeases(boxId, secretId)
+			switch {
+			case err != nil:
+				fmt.Fprintf(os.Stderr, "Unable to list the leases of the Secret - %v\n", err)
+				return retStatus, retStr, nil, nil
+			case len(leases) == 0:
+				// nobody holds the Secret, so the checkout failed for some
+				// other reason. Retry once in case the lease was released
+				// in between, otherwise give up
+				unheldFailures += 1
+				if unheldFailures > 1 {
+					return retStatus, retStr, nil, nil
+				}
+				continue
+			default:
+				descriptions := []string{}
+				for _, lease := range leases {
+					descriptions = append(descriptions, describeLease(lease))
+				}
+				status = "Secret is checked out - " + strings.Join(descriptions, "; ")
+			}
+			unheldFailures = 0
+		} else {
+			status = fmt.Sprintf("Waiting behind %d other checkout(s) on this host", position)
+		}
+
+		if status != lastStatus {
+			fmt.Fprintln(os.Stderr, status)
+			lastStatus = status
+			backoff = checkoutWaitInitialBackoff
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return retStatus, retStr, nil, fmt.Errorf("%w after %v", errCheckoutWaitTimeout, timeout)
+		}
+		if backoff > remaining {
+			backoff = remaining
+		}
+		time.Sleep(backoff)
+
+		backoff *= 2
+		if backoff > checkoutWaitMaxBackoff {
+			backoff = checkoutWaitMaxBackoff
+		}
+	}
+}
@@ -0,0 +1,261 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// clonedBoxFields are the Box settings carried over to the clone
+var clonedBoxFields = []string{
+	"description", "lease", "rotation", "max_secret_versions",
+	"exclusive_checkout", "secret_duration", "tags",
+}
+
+// generatePassword asks the Vault for a new password
+func generatePassword(length int) (string, error) {
+	params := map[string]interface{}{}
+	params["length"] = length
+	retMap, _, err := postVaultAPI("GeneratePassword", params)
+	if err != nil {
+		return "", err
+	}
+	password, _ := retMap["password"].(string)
+	if password == "" {
+		return "", fmt.Errorf("no password in the GeneratePassword response")
+	}
+	return password, nil
+}
+
+// isPasswordSecret tells from its metadata if a Secret is a password Secret
+func isPasswordSecret(metadata map[string]interface{}) bool {
+	subtypeInfo, _ := metadata["secret_subtype_info"].(map[string]interface{})
+	subtype, _ := subtypeInfo["type"].(string)
+	return subtype == "password"
+}
+
+// clonePolicyResources adds, to every Policy with resources in the source
+// Box, the same resources in the clone. Secret ids are mapped to the ids of
+// their copies, Secrets which weren't copied are left out. Returns the names
+// of the Policies updated.
+func clonePolicyResources(sourceBoxId string, cloneBoxId string,
+	secretIds map[string]string) ([]string, error) {
+
+	policyIds := []string{}
+	_, _, err := ListAllPages("ListPolicies", map[string]interface{}{}, ListPaging{All: true},
+		func(item interface{}) error {
+			policy, _ := item.(map[string]interface{})
+			if policyId, _ := policy["policy_id"].(string); policyId != "" {
+				policyIds = append(policyIds, policyId)
+			}
+			return nil
+		})
+	if err != nil && err != errListNotFound {
+		return nil, fmt.Errorf("Unable to list Policies - %v", err)
+	}
+
+	updated := []string{}
+	for _, policyId := range policyIds {
+		params := map[string]interface{}{}
+		params["policy_id"] = policyId
+		policy, _, err := postVaultAPI("GetPolicy", params)
+		if err != nil {
+			return updated, fmt.Errorf("Unable to get Policy %s - %v", policyId, err)
+		}
+
+		resources, _ := policy["resources"].([]interface{})
+		added := []interface{}{}
+		for _, resource := range resources {
+			resourceMap, _ := resource.(map[string]interface{})
+			if boxId, _ := resourceMap["box_id"].(string); boxId != sourceBoxId {
+				continue
+			}
+			cloneSecretIds := []string{}
+			sourceSecretIds, _ := resourceMap["secret_id"].([]interface{})
+			for _, sourceSecretId := range sourceSecretIds {
+				id, _ := sourceSecretId.(string)
+				if id == "*" {
+					cloneSecretIds = append(cloneSecretIds, id)
+				} else if cloneId, copied := secretIds[id]; copied {
+					cloneSecretIds = append(cloneSecretIds, cloneId)
+				}
+			}
+			if len(cloneSecretIds) != 0 {
+				added = append(added, map[string]interface{}{
+					"box_id":    cloneBoxId,
+					"secret_id": cloneSecretIds,
+				})
+			}
+		}
+		if len(added) == 0 {
+			continue
+		}
+
+		params["revision"] = policy["revision"]
+		params["resources"] = append(resources, added...)
+		if _, _, err := postVaultAPI("UpdatePolicy", params); err != nil {
+			return updated, fmt.Errorf("Unable to update Policy %s - %v", policyId, err)
+		}
+		name, _ := policy["name"].(string)
+		if name == "" {
+			name = policyId
+		}
+		updated = append(updated, name)
+	}
+	return updated, nil
+}
+
+// cloneBoxCmd represents the clone-box command
+var cloneBoxCmd = &cobra.Command{
+	Use:   "clone-box",
+	Short: "Create a Box with the settings of another one",
+	Long: `Create a Box with the description, lease, rotation, secret duration, maximum
+Secret versions, exclusive checkout and tags of another Box.
+
+With --with-secrets, the current version of every Secret is copied as
+copy-secret does, except that password Secrets get a newly generated
+password unless --keep-passwords is given. With --with-policies, Policies
+granting access to the source Box are updated to grant the same access to
+the clone.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+
+		sourceBox, _ := flags.GetString("source")
+		name, _ := flags.GetString("name")
+		withSecrets, _ := flags.GetBool("with-secrets")
+		keepPasswords, _ := flags.GetBool("keep-passwords")
+		passwordLength, _ := flags.GetInt("password-length")
+		withPolicies, _ := flags.GetBool("with-policies")
+
+		params := map[string]interface{}{}
+		params["box_id"] = sourceBox
+		box, _, err := postVaultAPI("GetBox", params)
+		if err != nil {
+			fmt.Printf("\nUnable to get Box %s - %v\n\n", sourceBox, err)
+			os.Exit(3)
+		}
+		sourceBoxId, _ := box["box_id"].(string)
+
+		params = map[string]interface{}{}
+		for _, field := range clonedBoxFields {
+			if value, present := box[field]; present && value != nil {
+				params[field] = value
+			}
+		}
+		params["name"] = name
+		if flags.Changed("description") {
+			description, _ := flags.GetString("description")
+			params["description"] = description
+		}
+		clone, _, err := postVaultAPI("CreateBox", params)
+		if err != nil {
+			fmt.Printf("\nUnable to create Box %s - %v\n\n", name, err)
+			os.Exit(3)
+		}
+		cloneBoxId, _ := clone["box_id"].(string)
+		fmt.Printf("\nBox %s cloned to %s (%s)\n", sourceBox, name, cloneBoxId)
+
+		// Secret ids in the source Box and of their copies
+		secretIds := map[string]string{}
+		if withSecrets {
+			sourceSecretIds := []string{}
+			secretNames := map[string]string{}
+			params := map[string]interface{}{}
+			params["box_id"] = sourceBoxId
+			_, _, err := ListAllPages("ListSecrets", params, ListPaging{All: true},
+				func(item interface{}) error {
+					secret, _ := item.(map[string]interface{})
+					if secretId, _ := secret["secret_id"].(string); secretId != "" {
+						sourceSecretIds = append(sourceSecretIds, secretId)
+						secretNames[secretId], _ = secret["name"].(string)
+					}
+					return nil
+				})
+			if err != nil && err != errListNotFound {
+				fmt.Printf("\nUnable to list the Secrets of Box %s - %v\n\n", sourceBox, err)
+				os.Exit(3)
+			}
+
+			freshPassword := func(metadata map[string]interface{},
+				data secretVersion) (secretVersion, error) {
+				if keepPasswords || !isPasswordSecret(metadata) {
+					return data, nil
+				}
+				password, err := generatePassword(passwordLength)
+				if err != nil {
+					return data, fmt.Errorf("Unable to generate a password - %v", err)
+				}
+				return secretVersion{Data: password}, nil
+			}
+			for _, sourceSecretId := range sourceSecretIds {
+				_, cloneSecretId, _, err := copySecret(secretCopy{
+					Source:    currentVaultSession(),
+					Target:    currentVaultSession(),
+					BoxId:     sourceBoxId,
+					SecretId:  sourceSecretId,
+					TargetBox: cloneBoxId,
+					Transform: freshPassword,
+				})
+				if err != nil {
+					fmt.Printf("\nUnable to copy Secret %s - %v\n\n", sourceSecretId, err)
+					os.Exit(3)
+				}
+				secretIds[sourceSecretId] = cloneSecretId
+				fmt.Printf("Secret %s copied (%s)\n", secretNames[sourceSecretId], cloneSecretId)
+			}
+		}
+
+		if withPolicies {
+			updated, err := clonePolicyResources(sourceBoxId, cloneBoxId, secretIds)
+			for _, policyName := range updated {
+				fmt.Printf("Policy %s updated\n", policyName)
+			}
+			if err != nil {
+				fmt.Printf("\n%v\n\n", err)
+				os.Exit(3)
+			}
+		}
+		fmt.Println()
+		os.Exit(0)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cloneBoxCmd)
+	cloneBoxCmd.Flags().StringP("source", "s", "",
+		"Id or name of the Box to clone")
+	cloneBoxCmd.Flags().StringP("name", "n", "",
+		"Name of the new Box")
+	cloneBoxCmd.Flags().StringP("description", "d", "",
+		"Description of the new Box. Default is the description of the source Box")
+	cloneBoxCmd.Flags().Bool("with-secrets", false,
+		"Copy the Secrets of the source Box to the new Box")
+	cloneBoxCmd.Flags().Bool("keep-passwords", false,
+		"With --with-secrets, copy password Secrets as they are instead of "+
+			"generating new passwords")
+	cloneBoxCmd.Flags().Int("password-length", 16,
+		"Length of the passwords generated for password Secrets")
+	cloneBoxCmd.Flags().Bool("with-policies", false,
+		"Grant the new Box the access Policies grant to the source Box")
+
+	// mark mandatory fields as required
+	cloneBoxCmd.MarkFlagRequired("source")
+	cloneBoxCmd.MarkFlagRequired("name")
+}
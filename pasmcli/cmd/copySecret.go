@@ -0,0 +1,532 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+// copiedSecretFields are the Secret metadata fields carried over to the copy
+var copiedSecretFields = []string{
+	"desc", "lease", "rotation", "exclusive_checkout", "expires_at",
+	"secret_type", "secret_config", "secret_subtype_info",
+}
+
+// secretVersion is the data of one version of a Secret. File content stays
+// in the spool, only its SHA-256 is kept.
+type secretVersion struct {
+	Data  interface{}
+	Spool *secretSpool
+	Size  int64
+	Sum   string
+}
+
+func (v secretVersion) isFile() bool {
+	return v.Spool != nil
+}
+
+// vaultSession is a Vault a command talks to, with the token to use for it
+type vaultSession struct {
+	token tokenInfo
+}
+
+// do runs fn with the session token as the current one
+func (s vaultSession) do(fn func() error) error {
+	previous := useTokenInfo(s.token)
+	defer useTokenInfo(previous)
+	return fn()
+}
+
+// currentVaultSession is the Vault given by --token-file, or the default one
+func currentVaultSession() vaultSession {
+	return vaultSession{token: gTokenInfo}
+}
+
+// vaultSessionFromFlag is the Vault of the token file given with flag, or
+// the current one when it isn't given
+func vaultSessionFromFlag(cmd *cobra.Command, flag string) (vaultSession, error) {
+	tokenFile, _ := cmd.Flags().GetString(flag)
+	if tokenFile == "" {
+		return currentVaultSession(), nil
+	}
+	token, err := loadTokenInfo(tokenFile)
+	if err != nil {
+		return vaultSession{}, fmt.Errorf("Error getting Server information from %s. %v",
+			tokenFile, err)
+	}
+	return vaultSession{token: token}, nil
+}
+
+// responseError returns the error reported in an API response, if any
+func responseError(retStr string) error {
+	retMap := map[string]interface{}{}
+	if retStr == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(retStr), &retMap); err != nil {
+		return fmt.Errorf("invalid response - %v", err)
+	}
+	if retVal, present := retMap["error"]; present {
+		return fmt.Errorf("%v", retVal)
+	}
+	return nil
+}
+
+// fetchSecretVersion gets one version of a Secret. The caller closes the
+// spool of file Secrets.
+func fetchSecretVersion(boxId string, secretId string, version int) (secretVersion, error) {
+	var fetched secretVersion
+	params := map[string]interface{}{}
+	params["box_id"] = boxId
+	params["secret_id"] = secretId
+	params["version"] = version
+	jsonParams, err := json.Marshal(params)
+	if err != nil {
+		return fetched, err
+	}
+
+	retStatus, retStr, spool, err := postSecretAPI("GetSecret", jsonParams)
+	if err == nil {
+		err = responseError(retStr)
+	}
+	if err == nil && retStr == "" {
+		err = fmt.Errorf("not found (HTTP %d)", retStatus)
+	}
+	if err != nil {
+		spool.Close()
+		return fetched, fmt.Errorf("Unable to get version %d of Secret %s - %v",
+			version, secretId, err)
+	}
+
+	if spool == nil {
+		fetched.Data = JsonStrToMap(retStr)["secret_data"]
+		return fetched, nil
+	}
+	fetched.Spool = spool
+	content, err := spool.content()
+	if err == nil {
+		hash := sha256.New()
+		fetched.Size, err = io.Copy(hash, content)
+		fetched.Sum = hex.EncodeToString(hash.Sum(nil))
+	}
+	if err != nil {
+		spool.Close()
+		return fetched, fmt.Errorf("Unable to read version %d of file Secret %s - %v",
+			version, secretId, err)
+	}
+	return fetched, nil
+}
+
+// storeSecretVersion creates the Secret with the first version, or adds a
+// version to it. Returns the id of the Secret.
+func storeSecretVersion(create bool,
+	params map[string]interface{},
+	tags map[string]interface{},
+	data secretVersion) (string, error) {
+
+	action := "PutSecretValue"
+	if create {
+		action = "CreateSecret"
+	}
+
+	retMap := map[string]interface{}{}
+	var err error
+	if data.isFile() {
+		content, contentErr := data.Spool.content()
+		if contentErr != nil {
+			return "", contentErr
+		}
+		var sentTags map[string]interface{}
+		if create {
+			sentTags = tags
+		}
+		var retStr string
+		_, retStr, _, err = postFileSecretContent(action, params, sentTags,
+			content, "file Secret", data.Size)
+		if err == nil {
+			err = responseError(retStr)
+		}
+		if err == nil && retStr != "" {
+			json.Unmarshal([]byte(retStr), &retMap)
+		}
+	} else {
+		params["secret_data"] = data.Data
+		if create && len(tags) != 0 {
+			params["tags"] = tags
+		}
+		retMap, _, err = postVaultAPI(action, params)
+		delete(params, "secret_data")
+		delete(params, "tags")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	secretId, _ := params["secret_id"].(string)
+	if create {
+		secretId, _ = retMap["secret_id"].(string)
+		if secretId == "" {
+			return "", fmt.Errorf("no secret_id in the %s response", action)
+		}
+	}
+	if data.isFile() && !create {
+		// PutSecretValue doesn't take tags
+		if err := recordFileSecretDigest(params["box_id"].(string), secretId, data.Sum); err != nil {
+			return secretId, err
+		}
+	}
+	return secretId, nil
+}
+
+// secretCopy tells which Secret copySecret copies and where to
+type secretCopy struct {
+	Source      vaultSession
+	Target      vaultSession
+	BoxId       string
+	SecretId    string
+	Version     int // 0 for the current version
+	AllVersions bool
+	TargetBox   string
+	TargetName  string // empty to keep the name
+
+	// Transform, when set, replaces the data of each version before it's
+	// stored, e.g. with a new password. The copy is then checked against
+	// the replaced data.
+	Transform func(metadata map[string]interface{}, data secretVersion) (secretVersion, error)
+}
+
+// copySecret copies a Secret, all or one of its versions, then reads the
+// copy back to verify it. Returns the Box and Secret ids of the copy, and
+// the source versions copied.
+func copySecret(c secretCopy) (string, string, []int, error) {
+	source, target := c.Source, c.Target
+	boxid, secretid, version := c.BoxId, c.SecretId, c.Version
+	allVersions := c.AllVersions
+	targetBox, targetName := c.TargetBox, c.TargetName
+
+	// source metadata and versions
+	var metadata map[string]interface{}
+	versions := []int{}
+	currentIndex := 0
+	err := source.do(func() error {
+		params := map[string]interface{}{}
+		params["box_id"] = boxid
+		params["secret_id"] = secretid
+		var err error
+		if metadata, _, err = postVaultAPI("GetSecretMetadata", params); err != nil {
+			return fmt.Errorf("Unable to get Secret %s - %v", secretid, err)
+		}
+		current := 0
+		if value, isNumber := metadata["current_version"].(float64); isNumber {
+			current = int(value)
+		}
+		switch {
+		case !allVersions && version != 0:
+			versions = append(versions, version)
+		case !allVersions:
+			versions = append(versions, current)
+		default:
+			params["box_id"] = metadata["box_id"]
+			params["secret_id"] = metadata["secret_id"]
+			retMap, _, err := postVaultAPI("ListSecretVersions", params)
+			if err != nil {
+				return fmt.Errorf("Unable to list versions of Secret %s - %v", secretid, err)
+			}
+			items, _ := retMap["versions"].([]interface{})
+			for _, item := range items {
+				itemMap, _ := item.(map[string]interface{})
+				if value, isNumber := itemMap["version"].(float64); isNumber {
+					versions = append(versions, int(value))
+				}
+			}
+			sort.Ints(versions)
+			currentIndex = len(versions) - 1
+			for index, value := range versions {
+				if value == current {
+					currentIndex = index
+				}
+			}
+		}
+		if len(versions) == 0 || versions[0] == 0 {
+			return fmt.Errorf("Secret %s has no version to copy", secretid)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", "", nil, err
+	}
+	sourceBoxId, _ := metadata["box_id"].(string)
+	sourceSecretId, _ := metadata["secret_id"].(string)
+	if targetName == "" {
+		targetName, _ = metadata["name"].(string)
+	}
+
+	params := map[string]interface{}{}
+	for _, field := range copiedSecretFields {
+		if value, present := metadata[field]; present && value != nil {
+			params[field] = value
+		}
+	}
+	if description, present := metadata["description"]; present && params["desc"] == nil {
+		params["desc"] = description
+	}
+	tags, _ := metadata["tags"].(map[string]interface{})
+	if tags == nil {
+		tags = map[string]interface{}{}
+	}
+
+	// the target Box must exist, the Secret must not
+	var targetBoxId string
+	err = target.do(func() error {
+		params := map[string]interface{}{}
+		params["box_id"] = targetBox
+		box, _, err := postVaultAPI("GetBox", params)
+		if err != nil {
+			return fmt.Errorf("Unable to get target Box %s - %v", targetBox, err)
+		}
+		targetBoxId, _ = box["box_id"].(string)
+		if targetBoxId == sourceBoxId && target.token == source.token {
+			if name, _ := metadata["name"].(string); name == targetName {
+				return fmt.Errorf("Cannot copy Secret %s onto itself", targetName)
+			}
+		}
+		params["box_id"] = targetBoxId
+		params["secret_id"] = targetName
+		if _, _, err := postVaultAPI("GetSecretMetadata", params); err == nil {
+			return fmt.Errorf("Secret %s already exists in Box %s", targetName, targetBox)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	// copy the versions in order, one at a time
+	sent := []secretVersion{}
+	defer func() {
+		for _, data := range sent {
+			data.Spool.Close()
+		}
+	}()
+	targetSecretId := ""
+	for index, sourceVersion := range versions {
+		var data secretVersion
+		err := source.do(func() error {
+			var err error
+			data, err = fetchSecretVersion(sourceBoxId, sourceSecretId, sourceVersion)
+			return err
+		})
+		if err != nil {
+			return "", "", nil, err
+		}
+		sent = append(sent, data)
+		if c.Transform != nil {
+			if data, err = c.Transform(metadata, data); err != nil {
+				return "", "", nil, err
+			}
+			sent[len(sent)-1] = data
+		}
+
+		err = target.do(func() error {
+			storeParams := map[string]interface{}{}
+			storeParams["box_id"] = targetBoxId
+			if index == 0 {
+				for key, value := range params {
+					storeParams[key] = value
+				}
+				storeParams["name"] = targetName
+			} else {
+				storeParams["secret_id"] = targetSecretId
+			}
+			secretId, err := storeSecretVersion(index == 0, storeParams, tags, data)
+			if secretId != "" {
+				targetSecretId = secretId
+			}
+			return err
+		})
+		if err != nil {
+			return targetBoxId, targetSecretId, nil, fmt.Errorf("Unable to copy version %d - %v",
+				sourceVersion, err)
+		}
+	}
+
+	// the copy numbers its versions from 1
+	copiedVersions := []int{}
+	err = target.do(func() error {
+		if currentIndex != len(versions)-1 {
+			params := map[string]interface{}{}
+			params["box_id"] = targetBoxId
+			params["secret_id"] = targetSecretId
+			params["version"] = currentIndex + 1
+			if _, _, err := postVaultAPI("SetSecretVersion", params); err != nil {
+				return fmt.Errorf("Unable to set the current version - %v", err)
+			}
+		}
+
+		// read back every version and compare it with the source
+		for index, expected := range sent {
+			copied, err := fetchSecretVersion(targetBoxId, targetSecretId, index+1)
+			if err != nil {
+				return err
+			}
+			copied.Spool.Close()
+			if expected.isFile() != copied.isFile() || expected.Sum != copied.Sum ||
+				!reflect.DeepEqual(expected.Data, copied.Data) {
+				return fmt.Errorf("Version %d of the copy does not match version %d "+
+					"of the source", index+1, versions[index])
+			}
+			copiedVersions = append(copiedVersions, versions[index])
+		}
+		return nil
+	})
+	if err != nil {
+		return targetBoxId, targetSecretId, nil, err
+	}
+	return targetBoxId, targetSecretId, copiedVersions, nil
+}
+
+// runCopySecret implements copy-secret and move-secret
+func runCopySecret(cmd *cobra.Command, move bool) {
+	flags := cmd.Flags()
+	boxid, _ := flags.GetString("boxid")
+	secretid, _ := flags.GetString("secretid")
+	version, _ := flags.GetInt("version")
+	allVersions, _ := flags.GetBool("all-versions")
+	targetBox, _ := flags.GetString("to-box")
+	targetName, _ := flags.GetString("to-name")
+	if allVersions && version != 0 {
+		fmt.Println("Specify only one of --version and --all-versions")
+		os.Exit(1)
+	}
+
+	source := currentVaultSession()
+	target, err := vaultSessionFromFlag(cmd, "to-token-file")
+	if err != nil {
+		fmt.Printf("\n%v\n\n", err)
+		os.Exit(1)
+	}
+
+	targetBoxId, targetSecretId, copied, err := copySecret(secretCopy{
+		Source:      source,
+		Target:      target,
+		BoxId:       boxid,
+		SecretId:    secretid,
+		Version:     version,
+		AllVersions: allVersions || move,
+		TargetBox:   targetBox,
+		TargetName:  targetName,
+	})
+	if err != nil {
+		fmt.Printf("\n%v\n", err)
+		if targetSecretId != "" {
+			fmt.Printf("The incomplete copy %s in Box %s was left in place, "+
+				"delete it with delete-secret\n", targetSecretId, targetBoxId)
+		}
+		if move {
+			fmt.Println("The source Secret was not deleted")
+		}
+		fmt.Println()
+		os.Exit(3)
+	}
+	if targetName == "" {
+		targetName = secretid
+	}
+	fmt.Printf("\nSecret %s copied to %s (%s) in Box %s, versions %v verified\n",
+		secretid, targetName, targetSecretId, targetBox, copied)
+
+	if move {
+		params := map[string]interface{}{}
+		params["box_id"] = boxid
+		params["secret_id"] = secretid
+		if _, _, err := postVaultAPI("DeleteSecret", params); err != nil {
+			fmt.Printf("\nUnable to delete the source Secret %s - %v\n\n", secretid, err)
+			os.Exit(3)
+		}
+		fmt.Printf("Source Secret %s deleted\n", secretid)
+	}
+	fmt.Println()
+	os.Exit(0)
+}
+
+// copySecretCmd represents the copy-secret command
+var copySecretCmd = &cobra.Command{
+	Use:   "copy-secret",
+	Short: "Copy a Secret to another Box or Vault",
+	Long: `Copy a Secret to another Box, or to a Box of another Vault logged into
+with a different --to-token-file. The type, data or file, description, tags,
+lease and rotation settings, exclusive checkout and expiry are copied. The
+current version is copied, or all of them in order with --all-versions.
+The copy is read back and compared with the source.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runCopySecret(cmd, false)
+	},
+}
+
+// moveSecretCmd represents the move-secret command
+var moveSecretCmd = &cobra.Command{
+	Use:   "move-secret",
+	Short: "Move a Secret to another Box or Vault",
+	Long: `Move a Secret to another Box, or to a Box of another Vault logged into
+with a different --to-token-file. All versions are copied as copy-secret
+--all-versions does, and the source Secret is deleted only once the copy
+has been read back and found identical.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runCopySecret(cmd, true)
+	},
+}
+
+func addCopySecretFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("boxid", "b", "",
+		"Id or name of the Box where the Secret is")
+	cmd.Flags().StringP("secretid", "s", "",
+		"Id or name of the Secret")
+	cmd.Flags().StringP("to-box", "B", "",
+		"Id or name of the Box to copy the Secret to")
+	cmd.Flags().StringP("to-name", "N", "",
+		"Name of the copy. Default is the name of the Secret")
+	cmd.Flags().String("to-token-file", "",
+		"Token file of the Vault to copy the Secret to, as saved by login "+
+			"--token-file. Default is the same Vault")
+
+	// mark mandatory fields as required
+	cmd.MarkFlagRequired("boxid")
+	cmd.MarkFlagRequired("secretid")
+	cmd.MarkFlagRequired("to-box")
+}
+
+func init() {
+	rootCmd.AddCommand(copySecretCmd)
+	acceptSecretRef(copySecretCmd)
+	addCopySecretFlags(copySecretCmd)
+	copySecretCmd.Flags().IntP("version", "v", 0,
+		"Version of the Secret to copy. Default is the current version")
+	copySecretCmd.Flags().Bool("all-versions", false,
+		"Copy all the versions of the Secret, in order")
+
+	rootCmd.AddCommand(moveSecretCmd)
+	acceptSecretRef(moveSecretCmd)
+	addCopySecretFlags(moveSecretCmd)
+}
@@ -20,8 +20,6 @@ import (
     // standard
     "os"
     "fmt"
-    "bytes"
-    "encoding/json"
     "strings"
     // external
     "github.com/spf13/cobra"
@@ -55,31 +53,19 @@ var createFileSecretCmd = &cobra.Command{
 
         // file
         filename, _ := flags.GetString("filename")
-        fi, err := os.Stat(filename)
+        size, err := checkFileSecretSize(flags, filename)
         if err != nil {
             fmt.Println(err)
             os.Exit(1)
         }
 
-        // 5 MB
-        max_filesize_bytes := int64(1000 * 1000 * 5)
-        if fi.Size() > max_filesize_bytes {
-            fmt.Println("File size cannot be greater than 5 MB")
-            os.Exit(1)
-        }
-        b64file, err := B64File(filename)
+        // only the base name is stored with the Secret
+        secretFilename, err := fileSecretName(filename)
         if err != nil {
             fmt.Println(err)
-            os.Exit(4)
-        }
-
-        if len(b64file) == 0 {
-            fmt.Println("Error. Empty file provided: %s", filename)
             os.Exit(1)
         }
 
-        params["secret_data"] = b64file
-
         // tags
         if ((flags.Changed("tagkey") && !flags.Changed("tagvalue")) ||
             (!flags.Changed("tagkey") && flags.Changed("tagvalue"))) {
@@ -87,6 +73,8 @@ var createFileSecretCmd = &cobra.Command{
                 os.Exit(1)
         }
 
+        // SHA-256 of the file is added to the tags while uploading
+        tags := map[string]interface{}{}
         if (flags.Changed("tagkey") && flags.Changed("tagvalue")) {
             tagkeyArray, _ := flags.GetStringArray("tagkey")
             tagvalueArray, _ := flags.GetStringArray("tagvalue")
@@ -103,7 +91,7 @@ var createFileSecretCmd = &cobra.Command{
                     tagParams[tagkeyArray[i]] = tagvalueArray[i]
                 }
             }
-            params["tags"] = tagParams
+            tags = tagParams
         }
 
         if flags.Changed("expires_at") {
@@ -114,45 +102,33 @@ var createFileSecretCmd = &cobra.Command{
         params["secret_subtype_info"] = map[string]interface{}{
             "type": "file",
             "info": map[string]interface{} {
-                "filename": filename,
+                "filename": secretFilename,
             },
         }
 
-        // JSONify
-        jsonParams, err := json.Marshal(params)
-        if (err != nil) {
-            fmt.Println("Error building JSON request: ", err)
-            os.Exit(1)
-        }
-
-        // now POST
-        endpoint := GetEndPoint("", "1.0", "CreateSecret")
-        ret, err := DoPost(endpoint,
-                               GetCACertFile(),
-                               AuthTokenKV(),
-                               jsonParams,
-                               "application/json")
+        // now POST, streaming the file content
+        retStatus, retStr, sum, err := postFileSecret("CreateSecret", params, tags,
+                                                      filename, size)
         if err != nil {
             fmt.Printf("\nHTTP request failed: %s\n", err)
             os.Exit(4)
         } else {
-            // type assertion
-            retBytes := ret["data"].(*bytes.Buffer)
-            retStatus := ret["status"].(int)
-            retStr := strings.ReplaceAll(retBytes.String(), "\\", "")
+            retStr = strings.ReplaceAll(retStr, "\\", "")
 
             if (retStr == "" && retStatus == 404) {
                 fmt.Println("\nAction denied\n")
                 os.Exit(5)
             }
 
-            fmt.Println("\n" + retStr + "\n")
+            PrintResponse(cmd, retStr)
 
             // make a decision on what to exit with
             retMap := JsonStrToMap(retStr)
             if _, present := retMap["error"]; present {
                 os.Exit(3)
             } else {
+                fmt.Fprintf(os.Stderr, "Uploaded %s\nSize: %d bytes\nSHA-256: %s (saved in tag %s)\n\n",
+                            secretFilename, size, sum, FileSecretSHA256Tag)
                 os.Exit(0)
             }
         }
@@ -179,6 +155,8 @@ func init() {
     createFileSecretCmd.Flags().StringP("expires_at", "e", "",
                                     "Expiration time in RFC 3339 format.")
 
+    addFileSecretUploadFlags(createFileSecretCmd)
+
     // mark mandatory fields as required
     createFileSecretCmd.MarkFlagRequired("boxid")
     createFileSecretCmd.MarkFlagRequired("name")
@@ -115,30 +115,11 @@ var createPolicyCmd = &cobra.Command{
 
 
         if (flags.Changed("resource")) {
-            resourceParams := []interface{}{}
             resourceArray, _ := flags.GetStringArray("resource")
-
-            for i := 0; i < len(resourceArray); i +=1 {
-                resource := strings.Split(resourceArray[i], ",")
-                // remove trailing/leading whitespace
-                for index := range resource {
-                    resource[index] = strings.TrimSpace(resource[index])
-                }
-
-                if len(resource) < 2 {
-                    fmt.Printf("\nInvalid resource argument: %s\n", resourceArray[i])
-                    os.Exit(1)
-                }
-
-                resourceMap := map[string]interface{}{}
-                resourceMap["box_id"] = resource[0]
-
-                secretIdList := []string{}
-                for i := 1; i < len(resource); i +=1 {
-                    secretIdList = append(secretIdList, resource[i])
-                }
-                resourceMap["secret_id"] = secretIdList
-                resourceParams = append(resourceParams, resourceMap)
+            resourceParams, err := policyResources(resourceArray)
+            if err != nil {
+                fmt.Printf("\n%v\n", err)
+                os.Exit(1)
             }
             params["resources"] = resourceParams
         }
@@ -197,7 +178,7 @@ var createPolicyCmd = &cobra.Command{
                 os.Exit(5)
             }
 
-            fmt.Println("\n" + retStr + "\n")
+            PrintResponse(cmd, retStr)
 
             // make a decision on what to exit with
             retMap := JsonStrToMap(retStr)
@@ -235,7 +216,9 @@ func init() {
                                     "Comma-separated string containing box id " +
                                     "and secret ids subsequently, to be added " +
                                     "as resources. Give * after box id to " +
-                                    "include all Secrets in the Box.")
+                                    "include all Secrets in the Box. A reference " +
+                                    "such as pasm://BOX/SECRET or BOX/* is also " +
+                                    "accepted, using names.")
     createPolicyCmd.Flags().StringArrayP("tagkey", "t", []string{},
                                          "Tag key to associate with the Policy." +
                                          " This option is repeatable.")
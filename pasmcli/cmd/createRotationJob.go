@@ -80,7 +80,7 @@ var createRotationJobCmd = &cobra.Command{
 		os.Exit(0)
 	   }
 
-           fmt.Println("\n" + retStr + "\n")
+           PrintResponse(cmd, retStr)
            // make a decision on what to exit with
            if _, present := retMap["error"]; present {
             os.Exit(3)
@@ -0,0 +1,246 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	csvImportInitialBackoff = 2 * time.Second
+	csvImportMaxBackoff     = 30 * time.Second
+
+	// DefaultCSVImportFollowTimeout is how long an import is followed
+	DefaultCSVImportFollowTimeout = time.Hour
+
+	// The statuses GetCSVImportStatus returns, compared ignoring case: an
+	// import in progress is followed until it is completed, failed or
+	// cancelled. Any other status is not known to be over, nor to progress.
+	csvImportInProgress = "in progress"
+	csvImportCompleted  = "completed"
+	csvImportFailed     = "failed"
+	csvImportCancelled  = "cancelled"
+)
+
+// csvImportFailure is a row of the CSV file the Vault could not import
+type csvImportFailure struct {
+	Line   int    `json:"line"`
+	Box    string `json:"box_name"`
+	Secret string `json:"secret_name"`
+	Error  string `json:"error"`
+}
+
+// csvImportProgress is the state of an import, as GetCSVImportStatus
+// returns it
+type csvImportProgress struct {
+	Status    string             `json:"status"`
+	Total     int                `json:"total"`
+	Processed int                `json:"processed"`
+	Succeeded int                `json:"succeeded"`
+	Failed    int                `json:"failed"`
+	Failures  []csvImportFailure `json:"failures"`
+}
+
+// done tells if the import is over, and fails on a missing or unknown
+// status rather than taking it for the end of the import
+func (p csvImportProgress) done() (bool, error) {
+	switch strings.ToLower(p.Status) {
+	case csvImportInProgress:
+		return false, nil
+	case csvImportCompleted, csvImportFailed, csvImportCancelled:
+		return true, nil
+	case "":
+		return false, fmt.Errorf("no status in the CSV import status")
+	}
+	return false, fmt.Errorf("unknown CSV import status %q", p.Status)
+}
+
+// summary returns the counts of the progress as one line
+func (p csvImportProgress) summary() string {
+	return fmt.Sprintf("%s: processed %d/%d, succeeded %d, failed %d",
+		p.Status, p.Processed, p.Total, p.Succeeded, p.Failed)
+}
+
+// getCSVImportStatus returns the progress of the current CSV import
+func getCSVImportStatus() (csvImportProgress, error) {
+	jsonParams, err := json.Marshal(map[string]interface{}{})
+	if err != nil {
+		return csvImportProgress{}, err
+	}
+	endpoint := GetEndPoint("", "1.0", "GetCSVImportStatus")
+	ret, err := DoGet(endpoint, GetCACertFile(), AuthTokenKV(), jsonParams, "application/json")
+	if err != nil {
+		return csvImportProgress{}, fmt.Errorf("HTTP request failed: %s", err)
+	}
+	retStr := ret["data"].(*bytes.Buffer).String()
+	if retStr == "" {
+		return csvImportProgress{}, fmt.Errorf("no CSV import status (HTTP %d)", ret["status"].(int))
+	}
+	response := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(retStr), &response); err != nil {
+		return csvImportProgress{}, fmt.Errorf("Error parsing GetCSVImportStatus response: %v", err)
+	}
+	if retVal, present := response["error"]; present {
+		return csvImportProgress{}, fmt.Errorf("%v", retVal)
+	}
+	progress := csvImportProgress{}
+	if err := json.Unmarshal([]byte(retStr), &progress); err != nil {
+		return csvImportProgress{}, fmt.Errorf("Error parsing GetCSVImportStatus response: %v", err)
+	}
+	return progress, nil
+}
+
+// writeCSVImportReport writes the failed rows as CSV, to fix and import again
+func writeCSVImportReport(path string, failures []csvImportFailure) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"line", "box_name", "secret_name", "error"})
+	for _, failure := range failures {
+		line := ""
+		if failure.Line > 0 {
+			line = fmt.Sprintf("%d", failure.Line)
+		}
+		writer.Write([]string{line, failure.Box, failure.Secret, failure.Error})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// confirmCSVImportCancel asks on the terminal whether to cancel the import
+func confirmCSVImportCancel() bool {
+	fmt.Printf("\nCancel the CSV import? [y/N] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// followCSVImport polls GetCSVImportStatus with backoff until the import is
+// completed, failed or cancelled, or the timeout flag of cmd is over,
+// printing the progress and the failed rows as they appear. Ctrl-C offers to
+// cancel the import. Exits with 3 when the import did not complete, rows
+// failed or the status is unknown, and 4 on timeout, after writing the
+// failed rows to the report flag of cmd if set.
+func followCSVImport(cmd *cobra.Command) {
+	report, _ := cmd.Flags().GetString("report")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	deadline := time.Now().Add(timeout)
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupted)
+
+	backoff := csvImportInitialBackoff
+	lastSummary := ""
+	reported := map[csvImportFailure]bool{}
+	timedOut := false
+	var progress csvImportProgress
+	for {
+		var err error
+		progress, err = getCSVImportStatus()
+		if err != nil {
+			fmt.Printf("\nUnable to get the CSV import status - %v\n\n", err)
+			os.Exit(3)
+		}
+		for _, failure := range progress.Failures {
+			if reported[failure] {
+				continue
+			}
+			reported[failure] = true
+			fmt.Printf("  line %d: %s/%s - %s\n", failure.Line, failure.Box, failure.Secret, failure.Error)
+		}
+		if summary := progress.summary(); summary != lastSummary {
+			fmt.Printf("%s CSV import %s\n", time.Now().Format("15:04:05"), summary)
+			lastSummary = summary
+			backoff = csvImportInitialBackoff
+		}
+		done, err := progress.done()
+		if err != nil {
+			fmt.Printf("\nUnable to follow the CSV import - %v\n\n", err)
+			os.Exit(3)
+		}
+		if done {
+			break
+		}
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			timedOut = true
+			break
+		}
+		if wait > backoff {
+			wait = backoff
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-interrupted:
+			if !confirmCSVImportCancel() {
+				fmt.Printf("\nStill following the CSV import\n\n")
+				continue
+			}
+			if _, _, err := postVaultAPI("CancelCSVImport", map[string]interface{}{}); err != nil {
+				fmt.Printf("\nUnable to cancel the CSV import - %v\n\n", err)
+				os.Exit(3)
+			}
+			fmt.Printf("\nCSV import cancelled\n\n")
+			os.Exit(130)
+		}
+		backoff *= 2
+		if backoff > csvImportMaxBackoff {
+			backoff = csvImportMaxBackoff
+		}
+	}
+
+	if report != "" {
+		if err := writeCSVImportReport(report, progress.Failures); err != nil {
+			fmt.Printf("\nUnable to write the report %s - %v\n\n", report, err)
+			os.Exit(4)
+		}
+		fmt.Printf("\nFailed rows written to %s\n", report)
+	}
+	switch {
+	case timedOut:
+		fmt.Printf("\nCSV import still in progress after %v, follow it again with "+
+			"get-csv-import-status --follow\n\n", timeout)
+		os.Exit(4)
+	case progress.Failed > 0 || len(progress.Failures) > 0:
+		fmt.Printf("\nCSV import %s with %d failed rows\n\n", progress.Status, progress.Failed)
+		os.Exit(3)
+	case !strings.EqualFold(progress.Status, csvImportCompleted):
+		fmt.Printf("\nCSV import %s, Secrets imported: %d\n\n", progress.Status, progress.Succeeded)
+		os.Exit(3)
+	}
+	fmt.Printf("\nCSV import %s, Secrets imported: %d\n\n", progress.Status, progress.Succeeded)
+	os.Exit(0)
+}
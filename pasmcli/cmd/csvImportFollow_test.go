@@ -0,0 +1,45 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import "testing"
+
+func TestCSVImportProgressDone(t *testing.T) {
+	tests := []struct {
+		status  string
+		done    bool
+		wantErr bool
+	}{
+		{status: "In Progress", done: false},
+		{status: "Completed", done: true},
+		{status: "failed", done: true},
+		{status: "Cancelled", done: true},
+		{status: "", wantErr: true},
+		{status: "Queued", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.status, func(t *testing.T) {
+			done, err := csvImportProgress{Status: test.status}.done()
+			if (err != nil) != test.wantErr {
+				t.Fatalf("done() error = %v, want error %v", err, test.wantErr)
+			}
+			if done != test.done {
+				t.Errorf("done() = %v, want %v", done, test.done)
+			}
+		})
+	}
+}
@@ -74,7 +74,7 @@ var deleteBoxCmd = &cobra.Command{
                 fmt.Println("\nBox deleted successfully\n")
                 os.Exit(0)
             } else {
-                fmt.Println("\n" + retStr + "\n")
+                PrintResponse(cmd, retStr)
 
                 // make a decision on what to exit with
                 retMap := JsonStrToMap(retStr)
@@ -74,7 +74,7 @@ var deleteLeaseCmd = &cobra.Command{
                 fmt.Println("\nLease deleted successfully\n")
                 os.Exit(0)
             } else {
-                fmt.Println("\n" + retStr + "\n")
+                PrintResponse(cmd, retStr)
 
                 // make a decision on what to exit with
                 retMap := JsonStrToMap(retStr)
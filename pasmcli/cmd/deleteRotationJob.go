@@ -75,7 +75,7 @@ var deleteRotationJobCmd = &cobra.Command{
                 fmt.Printf("\nRotation job %v deleted successfully\n\n", retMap["job_id"])
                 os.Exit(0)
             } else {
-                fmt.Println("\n" + retStr + "\n")
+                PrintResponse(cmd, retStr)
 
                 // make a decision on what to exit with
                 if _, present := retMap["error"]; present {
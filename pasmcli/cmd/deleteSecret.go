@@ -78,7 +78,7 @@ var deleteSecretCmd = &cobra.Command{
                 fmt.Println("\nSecret deleted successfully\n")
                 os.Exit(0)
             } else {
-                fmt.Println("\n" + retStr + "\n")
+                PrintResponse(cmd, retStr)
 
                 // make a decision on what to exit with
                 retMap := JsonStrToMap(retStr)
@@ -94,6 +94,7 @@ var deleteSecretCmd = &cobra.Command{
 
 func init() {
     rootCmd.AddCommand(deleteSecretCmd)
+    acceptSecretRef(deleteSecretCmd)
     deleteSecretCmd.Flags().StringP("boxid", "b", "",
                                     "Id or name of the Box where the Secret is")
     deleteSecretCmd.Flags().StringP("secretid", "s", "",
@@ -0,0 +1,577 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	diffAdded     = "added"
+	diffRemoved   = "removed"
+	diffChanged   = "changed"
+	diffUnchanged = "unchanged"
+
+	// diffMask replaces Secret values unless --reveal is given
+	diffMask = "****"
+
+	// file Secrets larger than this are only compared by size and hash
+	diffTextLimit = 1 << 20
+
+	// larger diffs are not computed, the line by line comparison being
+	// quadratic
+	diffMaxLinePairs = 4000000
+	diffContextLines = 3
+)
+
+// secretDifference is a line of the diff-secret report
+type secretDifference struct {
+	Section string      `json:"section"` // metadata or data
+	Item    string      `json:"item"`
+	Change  string      `json:"change"`
+	From    interface{} `json:"from,omitempty"`
+	To      interface{} `json:"to,omitempty"`
+}
+
+// diffValue compares one item of two versions
+func diffValue(section string, item string, from interface{}, fromPresent bool,
+	to interface{}, toPresent bool) secretDifference {
+
+	difference := secretDifference{Section: section, Item: item, From: from, To: to}
+	switch {
+	case fromPresent && !toPresent:
+		difference.Change, difference.To = diffRemoved, nil
+	case !fromPresent && toPresent:
+		difference.Change, difference.From = diffAdded, nil
+	case reflect.DeepEqual(from, to):
+		difference.Change, difference.From, difference.To = diffUnchanged, nil, nil
+	default:
+		difference.Change = diffChanged
+	}
+	return difference
+}
+
+// diffVersionMetadata compares the description, tags and expiry recorded
+// with two versions
+func diffVersionMetadata(from map[string]interface{}, to map[string]interface{}) []secretDifference {
+	differences := []secretDifference{}
+	description := func(version map[string]interface{}) (interface{}, bool) {
+		if value, present := version["desc"]; present {
+			return value, true
+		}
+		value, present := version["description"]
+		return value, present
+	}
+	fromDescription, fromPresent := description(from)
+	toDescription, toPresent := description(to)
+	if fromPresent || toPresent {
+		differences = append(differences, diffValue("metadata", "description",
+			fromDescription, fromPresent, toDescription, toPresent))
+	}
+
+	fromTags, fromPresent := from["tags"].(map[string]interface{})
+	toTags, toPresent := to["tags"].(map[string]interface{})
+	if fromPresent || toPresent {
+		for _, key := range unionKeys(fromTags, toTags) {
+			fromValue, fromPresent := fromTags[key]
+			toValue, toPresent := toTags[key]
+			differences = append(differences, diffValue("metadata", "tags."+key,
+				fromValue, fromPresent, toValue, toPresent))
+		}
+	}
+
+	fromExpiry, fromPresent := from["expires_at"]
+	toExpiry, toPresent := to["expires_at"]
+	if fromPresent || toPresent {
+		differences = append(differences, diffValue("metadata", "expires_at",
+			fromExpiry, fromPresent, toExpiry, toPresent))
+	}
+	return differences
+}
+
+// unionKeys returns the keys of both maps, sorted
+func unionKeys(a map[string]interface{}, b map[string]interface{}) []string {
+	keys := []string{}
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, present := a[key]; !present {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// diffSecretData compares the data of two versions. Returns the unified
+// diff of text files too, empty unless reveal.
+func diffSecretData(from secretVersion, to secretVersion, reveal bool,
+	fromName string, toName string) ([]secretDifference, string, error) {
+
+	differences := []secretDifference{}
+	masked := func(value interface{}) interface{} {
+		if reveal {
+			return value
+		}
+		return diffMask
+	}
+
+	fromMap, fromIsMap := from.Data.(map[string]interface{})
+	toMap, toIsMap := to.Data.(map[string]interface{})
+	switch {
+	case from.isFile() && to.isFile():
+		differences = append(differences,
+			diffValue("data", "size", from.Size, true, to.Size, true),
+			diffValue("data", "sha256", from.Sum, true, to.Sum, true))
+		if from.Sum == to.Sum {
+			return differences, "", nil
+		}
+		fromText, err := spoolText(from)
+		if err != nil {
+			return nil, "", err
+		}
+		toText, err := spoolText(to)
+		if err != nil {
+			return nil, "", err
+		}
+		if fromText == nil || toText == nil {
+			return differences, "", nil
+		}
+		fromLines, toLines := splitLines(*fromText), splitLines(*toText)
+		if len(fromLines)*len(toLines) > diffMaxLinePairs {
+			return differences, "", nil
+		}
+		edits := diffLines(fromLines, toLines)
+		added, removed := 0, 0
+		for _, edit := range edits {
+			switch edit.Op {
+			case '+':
+				added++
+			case '-':
+				removed++
+			}
+		}
+		differences = append(differences, secretDifference{Section: "data", Item: "lines",
+			Change: diffChanged, From: fmt.Sprintf("-%d", removed), To: fmt.Sprintf("+%d", added)})
+		if !reveal {
+			return differences, "", nil
+		}
+		return differences, unifiedDiff(edits, fromName, toName), nil
+
+	case from.isFile() || to.isFile():
+		return append(differences, secretDifference{Section: "data", Item: "type",
+			Change: diffChanged, From: versionKind(from), To: versionKind(to)}), "", nil
+
+	case fromIsMap && toIsMap:
+		for _, key := range unionKeys(fromMap, toMap) {
+			fromValue, fromPresent := fromMap[key]
+			toValue, toPresent := toMap[key]
+			difference := diffValue("data", key, fromValue, fromPresent, toValue, toPresent)
+			if difference.From != nil {
+				difference.From = masked(difference.From)
+			}
+			if difference.To != nil {
+				difference.To = masked(difference.To)
+			}
+			differences = append(differences, difference)
+		}
+		return differences, "", nil
+
+	case fromIsMap || toIsMap:
+		return append(differences, secretDifference{Section: "data", Item: "type",
+			Change: diffChanged, From: versionKind(from), To: versionKind(to)}), "", nil
+	}
+
+	// string Secrets only tell whether they changed
+	difference := diffValue("data", "value", from.Data, true, to.Data, true)
+	difference.From, difference.To = nil, nil
+	return append(differences, difference), "", nil
+}
+
+// versionKind names the kind of data of a version
+func versionKind(version secretVersion) string {
+	if version.isFile() {
+		return "file"
+	}
+	if _, isMap := version.Data.(map[string]interface{}); isMap {
+		return "key-value"
+	}
+	return "string"
+}
+
+// spoolText returns the content of a file version, or nil when it's too
+// large or doesn't look like text
+func spoolText(version secretVersion) (*string, error) {
+	if version.Size > diffTextLimit {
+		return nil, nil
+	}
+	content, err := version.Spool.content()
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, err
+	}
+	if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+		return nil, nil
+	}
+	text := string(data)
+	return &text, nil
+}
+
+// splitLines splits text after each newline
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// lineEdit is a line kept (' '), removed ('-') or added ('+')
+type lineEdit struct {
+	Op   byte
+	Line string
+	From int // line numbers from 1, 0 when not in that side
+	To   int
+}
+
+// diffLines lists the edits turning a into b, from their longest common
+// subsequence
+func diffLines(a []string, b []string) []lineEdit {
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else if common[i+1][j] >= common[i][j+1] {
+				common[i][j] = common[i+1][j]
+			} else {
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	edits := []lineEdit{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, lineEdit{Op: ' ', Line: a[i], From: i + 1, To: j + 1})
+			i, j = i+1, j+1
+		case i < len(a) && (j == len(b) || common[i+1][j] >= common[i][j+1]):
+			edits = append(edits, lineEdit{Op: '-', Line: a[i], From: i + 1})
+			i++
+		default:
+			edits = append(edits, lineEdit{Op: '+', Line: b[j], To: j + 1})
+			j++
+		}
+	}
+	return edits
+}
+
+// unifiedDiff renders edits as a unified diff with 3 lines of context
+func unifiedDiff(edits []lineEdit, fromName string, toName string) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+	for start := 0; start < len(edits); {
+		// the next hunk starts a few lines before a change, and ends when
+		// two contexts' worth of lines are unchanged
+		for start < len(edits) && edits[start].Op == ' ' {
+			start++
+		}
+		if start == len(edits) {
+			break
+		}
+		first := start - diffContextLines
+		if first < 0 {
+			first = 0
+		}
+		end, unchanged := start, 0
+		for end < len(edits) && unchanged <= 2*diffContextLines {
+			if edits[end].Op == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+			end++
+		}
+		last := end - unchanged + diffContextLines
+		if last > len(edits) {
+			last = len(edits)
+		}
+
+		fromBefore, toBefore := 0, 0
+		for _, edit := range edits[:first] {
+			if edit.Op != '+' {
+				fromBefore++
+			}
+			if edit.Op != '-' {
+				toBefore++
+			}
+		}
+		fromCount, toCount := 0, 0
+		for _, edit := range edits[first:last] {
+			if edit.Op != '+' {
+				fromCount++
+			}
+			if edit.Op != '-' {
+				toCount++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(fromBefore, fromCount),
+			hunkRange(toBefore, toCount))
+		for _, edit := range edits[first:last] {
+			out.WriteByte(edit.Op)
+			out.WriteString(edit.Line)
+			if !strings.HasSuffix(edit.Line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		start = last
+	}
+	return out.String()
+}
+
+// hunkRange formats the lines of a hunk on one side, after the lines
+// before it. An empty range is given as the line before it.
+func hunkRange(before int, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", before)
+	case 1:
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
+
+// formatDiffValue prints a value of the report
+func formatDiffValue(value interface{}) string {
+	if text, isString := value.(string); isString {
+		return text
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
+
+// printSecretDiff prints the report as text, one line per item
+func printSecretDiff(title string, differences []secretDifference, metadataRecorded bool,
+	kind string, unified string) {
+
+	fmt.Printf("%s\n", title)
+	for _, section := range []string{"metadata", "data"} {
+		switch section {
+		case "metadata":
+			fmt.Printf("\nMetadata\n")
+			if !metadataRecorded {
+				fmt.Printf("  no description, tags or expiry recorded with these versions\n")
+				continue
+			}
+		case "data":
+			fmt.Printf("\nData (%s)\n", kind)
+		}
+		for _, difference := range differences {
+			if difference.Section != section {
+				continue
+			}
+			switch difference.Change {
+			case diffAdded:
+				fmt.Printf("  + %s: %s\n", difference.Item, formatDiffValue(difference.To))
+			case diffRemoved:
+				fmt.Printf("  - %s: %s\n", difference.Item, formatDiffValue(difference.From))
+			case diffChanged:
+				if difference.From == nil && difference.To == nil {
+					fmt.Printf("  ~ %s: changed\n", difference.Item)
+				} else {
+					fmt.Printf("  ~ %s: %s -> %s\n", difference.Item,
+						formatDiffValue(difference.From), formatDiffValue(difference.To))
+				}
+			default:
+				fmt.Printf("    %s: unchanged\n", difference.Item)
+			}
+		}
+	}
+	if unified != "" {
+		fmt.Printf("\n%s", unified)
+	}
+}
+
+// diffSecretCmd represents the diff-secret command
+var diffSecretCmd = &cobra.Command{
+	Use:   "diff-secret",
+	Short: "Show what changed between two versions of a Secret",
+	Long: `Compare two versions of a Secret, given as a BOX/SECRET reference with
+--secret. --to defaults to the current version.
+
+Key-value Secrets are compared key by key, with the values masked unless
+--reveal is given. String Secrets, e.g. passwords, are only reported as
+changed or unchanged. File Secrets are compared by size and SHA-256 and,
+when both versions are text files of up to 1 MiB, by the number of lines
+added and removed. With --reveal, the unified diff of text files is shown.
+
+The description, tags and expiry are compared as recorded with each
+version by list-secret-versions.
+
+With -o, the differences are printed as a list of items, e.g. -o json.
+
+Examples:
+  pasmcli diff-secret --secret db-box/postgres --from 3 --to 5
+  pasmcli diff-secret --secret app/config.yaml --from 1 --reveal`,
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+		secret, _ := flags.GetString("secret")
+		fromVersion, _ := flags.GetInt("from")
+		toVersion, _ := flags.GetInt("to")
+		reveal, _ := flags.GetBool("reveal")
+
+		ref, err := parseSecretRefArg(secret)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if ref.Key != "" || ref.Version != 0 {
+			fmt.Printf("\nGive --secret as BOX/SECRET, and the versions with --from and --to\n\n")
+			os.Exit(1)
+		}
+
+		params := map[string]interface{}{}
+		params["box_id"] = ref.Box
+		params["secret_id"] = ref.Secret
+		metadata, status, err := postVaultAPI("GetSecretMetadata", params)
+		if err != nil {
+			fmt.Printf("\nUnable to get Secret %s - %v\n\n", secret, err)
+			if status == 404 {
+				os.Exit(5)
+			}
+			os.Exit(3)
+		}
+		boxId, _ := metadata["box_id"].(string)
+		secretId, _ := metadata["secret_id"].(string)
+		name, _ := metadata["name"].(string)
+		if !flags.Changed("to") {
+			if current, isNumber := metadata["current_version"].(float64); isNumber {
+				toVersion = int(current)
+			}
+		}
+		if fromVersion <= 0 || toVersion <= 0 {
+			fmt.Printf("\nGive the versions to compare with --from and --to\n\n")
+			os.Exit(1)
+		}
+
+		params["box_id"] = boxId
+		params["secret_id"] = secretId
+		retMap, _, err := postVaultAPI("ListSecretVersions", params)
+		if err != nil {
+			fmt.Printf("\nUnable to list the versions of Secret %s - %v\n\n", secret, err)
+			os.Exit(3)
+		}
+		versions := map[int]map[string]interface{}{}
+		items, _ := retMap["versions"].([]interface{})
+		for _, item := range items {
+			itemMap, _ := item.(map[string]interface{})
+			if version, isNumber := itemMap["version"].(float64); isNumber {
+				versions[int(version)] = itemMap
+			}
+		}
+		for _, version := range []int{fromVersion, toVersion} {
+			if versions[version] == nil {
+				fmt.Printf("\nSecret %s has no version %d\n\n", secret, version)
+				os.Exit(5)
+			}
+		}
+
+		fetched := []secretVersion{}
+		closeFetched := func() {
+			for _, data := range fetched {
+				data.Spool.Close()
+			}
+		}
+		for _, version := range []int{fromVersion, toVersion} {
+			data, err := fetchSecretVersion(boxId, secretId, version)
+			if err != nil {
+				closeFetched()
+				fmt.Printf("\n%v\n\n", err)
+				os.Exit(3)
+			}
+			fetched = append(fetched, data)
+		}
+
+		differences := diffVersionMetadata(versions[fromVersion], versions[toVersion])
+		metadataRecorded := len(differences) != 0
+		dataDifferences, unified, err := diffSecretData(fetched[0], fetched[1], reveal,
+			fmt.Sprintf("%s@%d", name, fromVersion), fmt.Sprintf("%s@%d", name, toVersion))
+		closeFetched()
+		if err != nil {
+			fmt.Printf("\nUnable to compare the versions - %v\n\n", err)
+			os.Exit(4)
+		}
+		differences = append(differences, dataDifferences...)
+
+		format := GetOutputFormat(cmd)
+		if format == "" {
+			kind := versionKind(fetched[1])
+			if kind != versionKind(fetched[0]) {
+				kind = versionKind(fetched[0]) + " -> " + kind
+			}
+			printSecretDiff(fmt.Sprintf("Secret %s of Box %s, version %d -> %d",
+				name, ref.Box, fromVersion, toVersion), differences, metadataRecorded, kind, unified)
+			os.Exit(0)
+		}
+		report, err := JSONMarshalIndent(map[string]interface{}{"differences": differences})
+		if err != nil {
+			fmt.Println("Error building JSON output: ", err)
+			os.Exit(4)
+		}
+		if err := PrintFormatted(os.Stdout, format, "differences", strings.TrimSpace(string(report))); err != nil {
+			fmt.Printf("\nError formatting output - %v\n", err)
+			os.Exit(4)
+		}
+		os.Exit(0)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffSecretCmd)
+	diffSecretCmd.Flags().StringP("secret", "s", "",
+		"Secret to compare, as BOX/SECRET")
+	diffSecretCmd.MarkFlagRequired("secret")
+	diffSecretCmd.Flags().Int("from", 0,
+		"Version to compare from")
+	diffSecretCmd.MarkFlagRequired("from")
+	diffSecretCmd.Flags().Int("to", 0,
+		"Version to compare to. Default is the current version")
+	diffSecretCmd.Flags().Bool("reveal", false,
+		"Show the values of key-value Secrets, and the diff of text files")
+}
@@ -0,0 +1,127 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// numberLines returns the lines "1\n" to "n\n", replacing the given ones
+func numberLines(n int, replaced map[int]string) string {
+	var text strings.Builder
+	for i := 1; i <= n; i++ {
+		if line, found := replaced[i]; found {
+			text.WriteString(line + "\n")
+		} else {
+			text.WriteString(strconv.Itoa(i) + "\n")
+		}
+	}
+	return text.String()
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want string
+	}{
+		{
+			name: "identical",
+			from: "a\nb\n",
+			to:   "a\nb\n",
+			want: "--- a\n+++ b\n",
+		},
+		{
+			name: "one change with context",
+			from: numberLines(10, nil),
+			to:   numberLines(10, map[int]string{5: "five"}),
+			want: "--- a\n+++ b\n" +
+				"@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name: "changes far apart",
+			from: numberLines(20, nil),
+			to:   numberLines(20, map[int]string{2: "two", 15: "fifteen"}),
+			want: "--- a\n+++ b\n" +
+				"@@ -1,5 +1,5 @@\n 1\n-2\n+two\n 3\n 4\n 5\n" +
+				"@@ -12,7 +12,7 @@\n 12\n 13\n 14\n-15\n+fifteen\n 16\n 17\n 18\n",
+		},
+		{
+			name: "changes close together share a hunk",
+			from: numberLines(20, nil),
+			to:   numberLines(20, map[int]string{2: "two", 9: "nine"}),
+			want: "--- a\n+++ b\n" +
+				"@@ -1,12 +1,12 @@\n 1\n-2\n+two\n 3\n 4\n 5\n 6\n 7\n 8\n-9\n+nine\n 10\n 11\n 12\n",
+		},
+		{
+			name: "insertion into empty",
+			from: "",
+			to:   "a\nb\n",
+			want: "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "deletion of everything",
+			from: "a\n",
+			to:   "",
+			want: "--- a\n+++ b\n@@ -1 +0,0 @@\n-a\n",
+		},
+		{
+			name: "no newline at end of file",
+			from: "a\nb",
+			to:   "a\nc",
+			want: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n" +
+				"+c\n\\ No newline at end of file\n",
+		},
+		{
+			name: "newline added at end of file",
+			from: "a\nb",
+			to:   "a\nb\n",
+			want: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			edits := diffLines(splitLines(test.from), splitLines(test.to))
+			got := unifiedDiff(edits, "a", "b")
+			if got != test.want {
+				t.Errorf("unifiedDiff() =\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestHunkRange(t *testing.T) {
+	tests := []struct {
+		before int
+		count  int
+		want   string
+	}{
+		{before: 0, count: 0, want: "0,0"},
+		{before: 4, count: 0, want: "4,0"},
+		{before: 4, count: 1, want: "5"},
+		{before: 4, count: 7, want: "5,7"},
+	}
+	for _, test := range tests {
+		if got := hunkRange(test.before, test.count); got != test.want {
+			t.Errorf("hunkRange(%d, %d) = %q, want %q", test.before, test.count,
+				got, test.want)
+		}
+	}
+}
@@ -0,0 +1,357 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// csvSecretDataKeys are the keys the Secrets of a secret type hold CSV
+// columns in, when they differ from the column: create-ssh-key-secret stores
+// the user as username and the private key base64 encoded, and
+// create-esxi-host-secret stores the user as uid
+var csvSecretDataKeys = map[string]map[string]string{
+	csvSecretTypeESXi: {"user": "uid"},
+	csvSecretTypeSSHKey: {
+		"user":    "username",
+		"keyfile": "private_key",
+		"keypwd":  "private_key_pwd",
+	},
+}
+
+// csvSecretValue returns the value of a CSV column of the secret type from
+// the Secret metadata and data, or ""
+func csvSecretValue(secretType string, column string, boxName string,
+	metadata map[string]interface{}, data interface{}) string {
+	setting := func(name string, field string) string {
+		nested, _ := metadata[name].(map[string]interface{})
+		if value, present := nested[field]; present && value != nil {
+			return fmt.Sprintf("%v", value)
+		}
+		return ""
+	}
+	switch strings.ReplaceAll(strings.ToLower(column), "_", "-") {
+	case "box-name":
+		return boxName
+	case "secret-name":
+		name, _ := metadata["name"].(string)
+		return name
+	case "desc":
+		desc, _ := metadata["desc"].(string)
+		return desc
+	case "lease-duration":
+		return setting("lease", "duration")
+	case "rotation-duration":
+		return setting("rotation", "duration")
+	case "rotation-on-checkin":
+		return setting("rotation", "on_checkin")
+	case "rotation-force":
+		return setting("rotation", "force")
+	case "exclusive-checkout":
+		if value, present := metadata["exclusive_checkout"]; present && value != nil {
+			return fmt.Sprintf("%v", value)
+		}
+		return ""
+	case "expires-at":
+		// the CSV import format takes dates as YYYY-MM-DD
+		expiresAt, _ := metadata["expires_at"].(string)
+		if expiration, err := time.Parse(time.RFC3339, expiresAt); err == nil {
+			return expiration.UTC().Format("2006-01-02")
+		}
+		return expiresAt
+	case "tags":
+		tags, _ := metadata["tags"].(map[string]interface{})
+		pairs := []string{}
+		for key, value := range tags {
+			pairs = append(pairs, fmt.Sprintf("%s=%v", key, value))
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ",")
+	}
+
+	switch value := data.(type) {
+	case string:
+		// a password Secret holds its value alone
+		if column == "password" {
+			return value
+		}
+	case map[string]interface{}:
+		if key, mapped := csvSecretDataKeys[secretType][column]; mapped {
+			if found, present := value[key]; present && found != nil {
+				if key == "private_key" {
+					// the CSV import format takes the key itself
+					if decoded, err := base64.StdEncoding.DecodeString(fmt.Sprintf("%v", found)); err == nil {
+						return string(decoded)
+					}
+				}
+				return fmt.Sprintf("%v", found)
+			}
+		}
+		if found, present := value[column]; present && found != nil {
+			return fmt.Sprintf("%v", found)
+		}
+	}
+	return ""
+}
+
+// addCSVDecryptionFlags adds the flags to read files written by export-csv
+func addCSVDecryptionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayP("identity", "I", []string{},
+		"File holding age identities (AGE-SECRET-KEY-1...) to decrypt a CSV "+
+			"file written by export-csv. This option is repeatable.")
+	cmd.Flags().String("passphrase-file", "",
+		"File holding the passphrase to decrypt a CSV file written by export-csv")
+}
+
+// plainCSVFile returns the path of the CSV file to read, decrypting files
+// written by export-csv to a temporary file. cleanup removes it.
+func plainCSVFile(flags *pflag.FlagSet, path string) (string, func(), error) {
+	cleanup := func() {}
+	content, err := os.ReadFile(path)
+	if err != nil || !bytes.HasPrefix(content, []byte(backupMagic)) {
+		return path, cleanup, err
+	}
+
+	identityFiles, _ := flags.GetStringArray("identity")
+	passphraseFile, _ := flags.GetString("passphrase-file")
+	keys := backupKeys{}
+	for _, identityFile := range identityFiles {
+		identities, err := readBackupIdentities(identityFile)
+		if err != nil {
+			return "", cleanup, err
+		}
+		keys.Identities = append(keys.Identities, identities...)
+	}
+	if passphraseFile != "" || (len(identityFiles) == 0 && backupNeedsPassphrase(content)) {
+		if keys.Passphrase, err = readPassphrase(passphraseFile, false); err != nil {
+			return "", cleanup, fmt.Errorf("Unable to read the passphrase - %v", err)
+		}
+	}
+	plain, err := decryptBackup(content, keys)
+	if err != nil {
+		return "", cleanup, fmt.Errorf("Unable to decrypt %s - %v", path, err)
+	}
+
+	dir, err := os.MkdirTemp("", "pasmcli-csv")
+	if err != nil {
+		return "", cleanup, err
+	}
+	cleanup = func() { os.RemoveAll(dir) }
+	name := strings.TrimSuffix(filepath.Base(path), ".enc")
+	if filepath.Ext(name) != ".csv" {
+		name += ".csv"
+	}
+	plainPath := filepath.Join(dir, name)
+	if err := os.WriteFile(plainPath, plain, 0600); err != nil {
+		cleanup()
+		return "", func() {}, err
+	}
+	return plainPath, cleanup, nil
+}
+
+// exportCSVCmd represents the export-csv command
+var exportCSVCmd = &cobra.Command{
+	Use:   "export-csv",
+	Short: "Export the Secrets of a Box to the CSV import format",
+	Long: `Write the Secrets of a Box as a CSV file in the column layout of the sample
+CSV of the --secret_type, so that import-csv can create them in another
+Vault. The sample CSV is downloaded from the Vault as download-sample-csv
+does, or read from the --sample file.
+
+The values are read from the current version of each Secret: each column
+from the key of the same name of a key-value Secret, e.g. host, and the
+password column from a password Secret. The Secrets create-ssh-key-secret
+and create-esxi-host-secret make are read under their own keys: user from
+username or uid, keyfile from the decoded private_key, and keypwd from
+private_key_pwd. Secrets lacking a required column are skipped and reported.
+
+The file holds the Secret values, so it is encrypted by default, the same
+way backup encrypts, as an age file: to the X25519 recipients given with
+--recipient, or else to a passphrase prompted for or read from
+--passphrase-file.
+import-csv and validate-csv decrypt it with --identity or --passphrase-file.
+Use --plaintext to write the CSV as is.
+
+Examples:
+  pasmcli export-csv -b servers -t "SSH key endpoint" --out servers.csv.enc
+  pasmcli import-csv -c servers.csv.enc -t "SSH key endpoint" -I key.txt --wait`,
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+		boxName, _ := flags.GetString("box")
+		secretType, _ := flags.GetString("secret_type")
+		sample, _ := flags.GetString("sample")
+		outPath, _ := flags.GetString("out")
+		force, _ := flags.GetBool("force")
+		plaintext, _ := flags.GetBool("plaintext")
+		recipients, _ := flags.GetStringArray("recipient")
+		passphraseFile, _ := flags.GetString("passphrase-file")
+
+		if _, supported := csvRequiredColumns[secretType]; !supported {
+			fmt.Printf("\nInvalid secret type %q. Supported: %s, %s, %s\n\n", secretType,
+				csvSecretTypeESXi, csvSecretTypeStatic, csvSecretTypeSSHKey)
+			os.Exit(1)
+		}
+		if plaintext && (len(recipients) != 0 || passphraseFile != "") {
+			fmt.Printf("\n--plaintext can't be used with --recipient or --passphrase-file\n\n")
+			os.Exit(1)
+		}
+		if len(recipients) != 0 && passphraseFile != "" {
+			fmt.Println("Please provide either --recipient or --passphrase-file")
+			os.Exit(1)
+		}
+		keys := backupKeys{}
+		var err error
+		if keys.Recipients, err = parseBackupRecipients(recipients); err != nil {
+			fmt.Printf("\n%v\n\n", err)
+			os.Exit(1)
+		}
+		if !plaintext && len(recipients) == 0 {
+			passphrase, err := readPassphrase(passphraseFile, true)
+			if err != nil {
+				fmt.Printf("\nUnable to read the passphrase - %v\n\n", err)
+				os.Exit(1)
+			}
+			keys.Passphrase = passphrase
+		}
+
+		schema, err := loadCSVSchema(secretType, sample)
+		if err != nil {
+			fmt.Printf("\n%v\n\n", err)
+			os.Exit(4)
+		}
+
+		params := map[string]interface{}{}
+		params["box_id"] = boxName
+		box, _, err := postVaultAPI("GetBox", params)
+		if err != nil {
+			fmt.Printf("\nUnable to get Box %s - %v\n\n", boxName, err)
+			os.Exit(3)
+		}
+		boxId, _ := box["box_id"].(string)
+		name, _ := box["name"].(string)
+		params["box_id"] = boxId
+		secrets, err := listItems("ListSecretIds", params)
+		if err != nil {
+			fmt.Printf("\nUnable to list the Secrets of Box %s - %v\n\n", boxName, err)
+			os.Exit(3)
+		}
+
+		plain := &bytes.Buffer{}
+		writer := csv.NewWriter(plain)
+		writer.Write(schema.Columns)
+		exported, skipped := 0, 0
+		for _, secret := range secrets {
+			params["secret_id"] = secret["secret_id"]
+			metadata, _, err := postVaultAPI("GetSecretMetadata", params)
+			if err != nil {
+				fmt.Printf("\nUnable to get Secret %v - %v\n\n", secret["name"], err)
+				os.Exit(3)
+			}
+			secretId, _ := metadata["secret_id"].(string)
+			fetched, err := fetchSecretVersion(boxId, secretId, newSyncStamp(metadata).Version)
+			if err != nil {
+				fmt.Printf("\n%v\n\n", err)
+				os.Exit(3)
+			}
+			fetched.Spool.Close()
+
+			record := []string{}
+			missing := []string{}
+			for _, column := range schema.Columns {
+				value := csvSecretValue(secretType, column, name, metadata, fetched.Data)
+				record = append(record, value)
+				for _, required := range schema.Required {
+					if required == column && value == "" {
+						missing = append(missing, column)
+					}
+				}
+			}
+			if len(missing) != 0 {
+				fmt.Fprintf(os.Stderr, "Secret %v skipped: no value for %s\n",
+					metadata["name"], strings.Join(missing, ", "))
+				skipped += 1
+				continue
+			}
+			writer.Write(record)
+			exported += 1
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			fmt.Printf("\nUnable to build the CSV file - %v\n\n", err)
+			os.Exit(4)
+		}
+
+		content := plain
+		if !plaintext {
+			content = &bytes.Buffer{}
+			if err := encryptBackup(content, plain.Bytes(), keys); err != nil {
+				fmt.Printf("\nUnable to encrypt the CSV file - %v\n\n", err)
+				os.Exit(4)
+			}
+		}
+		out := fileSecretOutput{Path: outPath, Mode: 0600, Force: force}
+		path, _, _, err := writeFileSecret(out, "", content)
+		if err != nil {
+			fmt.Printf("\nError writing %s - %v\n\n", outPath, err)
+			os.Exit(4)
+		}
+		state := "encrypted"
+		if plaintext {
+			state = "plaintext"
+		}
+		fmt.Printf("\nExported %d Secrets of Box %s to %s (%s), %d skipped\n\n",
+			exported, name, path, state, skipped)
+		os.Exit(0)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCSVCmd)
+	exportCSVCmd.Flags().StringP("box", "b", "",
+		"Id or name of the Box to export")
+	exportCSVCmd.Flags().StringP("secret_type", "t", "",
+		"Secret type whose CSV layout to write, which can be esxi, static, or SSH key endpoint")
+	exportCSVCmd.Flags().String("sample", "",
+		"Sample CSV file from download-sample-csv to take the columns from, "+
+			"instead of downloading it")
+	exportCSVCmd.Flags().String("out", "",
+		"File to write the CSV to")
+	exportCSVCmd.Flags().Bool("force", false,
+		"Overwrite the file if it already exists")
+	exportCSVCmd.Flags().Bool("plaintext", false,
+		"Write the CSV unencrypted")
+	exportCSVCmd.Flags().StringArrayP("recipient", "r", []string{},
+		"age X25519 public key (age1...) to encrypt the CSV to. "+
+			"This option is repeatable.")
+	exportCSVCmd.Flags().String("passphrase-file", "",
+		"File holding the passphrase to encrypt the CSV with, "+
+			"instead of recipients")
+	exportCSVCmd.MarkFlagRequired("box")
+	exportCSVCmd.MarkFlagRequired("secret_type")
+	exportCSVCmd.MarkFlagRequired("out")
+}
@@ -0,0 +1,331 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	exportEnvFormatDotenv  = "dotenv"
+	exportEnvFormatExport  = "export"
+	exportEnvFormatFish    = "fish"
+	exportEnvFormatSystemd = "systemd"
+)
+
+// envVar is a variable exported from a Secret
+type envVar struct {
+	Name  string
+	Value string
+}
+
+// getKVSecret gets the Secret ref names and returns its data, or only the
+// value under the key of the reference. GetSecret leaves no lease behind.
+func getKVSecret(ref secretRef) (interface{}, error) {
+	params := map[string]interface{}{}
+	params["box_id"] = ref.Box
+	params["secret_id"] = ref.Secret
+	if ref.Version != 0 {
+		params["version"] = ref.Version
+	}
+	jsonParams, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	retStatus, retStr, spool, err := postSecretAPI("GetSecret", jsonParams)
+	if spool != nil {
+		spool.Close()
+		return nil, fmt.Errorf("%s is a file Secret, not a key-value Secret", ref)
+	}
+	if err == nil {
+		err = responseError(retStr)
+	}
+	if err == nil && (retStr == "" || retStatus != 200) {
+		err = fmt.Errorf("not found (HTTP %d)", retStatus)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get %s - %v", ref, err)
+	}
+	retMap := JsonStrToMap(retStr)
+	if subtypeInfo, isMap := retMap["secret_subtype_info"].(map[string]interface{}); isMap &&
+		isSecretFile(subtypeInfo) {
+		return nil, fmt.Errorf("%s is a file Secret, not a key-value Secret", ref)
+	}
+
+	data := retMap["secret_data"]
+	if str, isString := data.(string); isString {
+		if err := json.Unmarshal([]byte(str), &data); err != nil {
+			return nil, fmt.Errorf("%s is not a key-value Secret", ref)
+		}
+	}
+	if _, isMap := data.(map[string]interface{}); !isMap {
+		return nil, fmt.Errorf("%s is not a key-value Secret", ref)
+	}
+	if ref.Key == "" {
+		return data, nil
+	}
+	matches := LookupJSONPath(data, []interface{}{ref.Key})
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("Key %q not found in %s", ref.Key, ref)
+	}
+	return matches[0], nil
+}
+
+// sanitizeEnvName turns a key into a valid variable name: letters, digits
+// and underscores, not starting with a digit
+func sanitizeEnvName(name string, keepCase bool) string {
+	var builder strings.Builder
+	for _, char := range name {
+		switch {
+		case char >= 'a' && char <= 'z' && !keepCase:
+			builder.WriteRune(char - 'a' + 'A')
+		case char >= 'a' && char <= 'z', char >= 'A' && char <= 'Z',
+			char >= '0' && char <= '9', char == '_':
+			builder.WriteRune(char)
+		default:
+			builder.WriteRune('_')
+		}
+	}
+	sanitized := builder.String()
+	if sanitized == "" || (sanitized[0] >= '0' && sanitized[0] <= '9') {
+		sanitized = "_" + sanitized
+	}
+	return sanitized
+}
+
+// flattenEnv adds a variable for each leaf of value, nested keys and array
+// indexes joined with separator
+func flattenEnv(vars map[string]string, name string, value interface{}, separator string) {
+	join := func(key string) string {
+		if name == "" {
+			return key
+		}
+		return name + separator + key
+	}
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range value {
+			flattenEnv(vars, join(key), nested, separator)
+		}
+	case []interface{}:
+		for index, nested := range value {
+			flattenEnv(vars, join(strconv.Itoa(index)), nested, separator)
+		}
+	case string:
+		vars[name] = value
+	case float64:
+		vars[name] = strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		vars[name] = strconv.FormatBool(value)
+	case nil:
+		vars[name] = ""
+	}
+}
+
+// quoteEnvValue quotes a value for the given format
+func quoteEnvValue(format string, value string) (string, error) {
+	switch format {
+	case exportEnvFormatExport:
+		// POSIX single quotes, closing and reopening them around '
+		return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'", nil
+	case exportEnvFormatFish:
+		replacer := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+		return "'" + replacer.Replace(value) + "'", nil
+	case exportEnvFormatSystemd:
+		if strings.ContainsAny(value, "\r\n") {
+			return "", fmt.Errorf("systemd EnvironmentFile cannot hold multi-line values")
+		}
+		replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "`", "\\`")
+		return `"` + replacer.Replace(value) + `"`, nil
+	}
+	// dotenv: single quotes are taken literally, double quotes for values
+	// containing them or newlines
+	if !strings.ContainsAny(value, "'\r\n") {
+		return "'" + value + "'", nil
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "`", "\\`",
+		"\n", `\n`, "\r", `\r`)
+	return `"` + replacer.Replace(value) + `"`, nil
+}
+
+// formatEnv renders the variables, one per line
+func formatEnv(format string, vars []envVar) (string, error) {
+	var builder strings.Builder
+	for _, v := range vars {
+		quoted, err := quoteEnvValue(format, v.Value)
+		if err != nil {
+			return "", fmt.Errorf("%s - %v", v.Name, err)
+		}
+		switch format {
+		case exportEnvFormatExport:
+			fmt.Fprintf(&builder, "export %s=%s\n", v.Name, quoted)
+		case exportEnvFormatFish:
+			fmt.Fprintf(&builder, "set -gx %s %s\n", v.Name, quoted)
+		default:
+			fmt.Fprintf(&builder, "%s=%s\n", v.Name, quoted)
+		}
+	}
+	return builder.String(), nil
+}
+
+// exportEnvCmd represents the export-env command
+var exportEnvCmd = &cobra.Command{
+	Use:   "export-env",
+	Short: "Export key-value Secrets as environment variables",
+	Long: `Read one or more key-value Secrets and print their keys as environment
+variables, in dotenv, POSIX shell export, fish or systemd EnvironmentFile
+format. The Secrets are read without checking them out, so no lease is
+taken. Nested keys are joined with --separator, and key names are made
+valid variable names: upper-cased, with any other character than letters,
+digits and _ replaced by _.
+
+Secrets are given as pasm://BOX/SECRET[#KEY][@VERSION] references. With a
+key, only the value under it is exported. When Secrets have keys in common,
+the last one given wins.
+
+Examples:
+  pasmcli export-env --secret db-box/postgres --prefix DB_ > .env
+  pasmcli export-env -s app/config -s app/creds -F systemd --output /etc/app.env
+  eval "$(pasmcli export-env -s app/config -F export)"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+
+		secrets, _ := flags.GetStringArray("secret")
+		prefix, _ := flags.GetString("prefix")
+		separator, _ := flags.GetString("separator")
+		keepCase, _ := flags.GetBool("keep-case")
+		format, _ := flags.GetString("format")
+		switch format {
+		case exportEnvFormatDotenv, exportEnvFormatExport,
+			exportEnvFormatFish, exportEnvFormatSystemd:
+		default:
+			fmt.Printf("Invalid --format %q. Expected dotenv, export, fish or systemd\n", format)
+			os.Exit(1)
+		}
+
+		out := fileSecretOutput{}
+		out.Path, _ = flags.GetString(fileSecretOptionOutput)
+		if out.Path == "" {
+			// the output is written to stdout, keep it clean
+			InsecureBannerOutput = os.Stderr
+		}
+		out.Force, _ = flags.GetBool(fileSecretOptionForce)
+		mode, _ := flags.GetString(fileSecretOptionMode)
+		var err error
+		if out.Mode, err = parseFileMode(mode); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		refs := []secretRef{}
+		for _, secret := range secrets {
+			ref, err := parseSecretRefArg(secret)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			refs = append(refs, ref)
+		}
+
+		// later Secrets override earlier ones
+		values := map[string]string{}
+		for _, ref := range refs {
+			data, err := getKVSecret(ref)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "\n%v\n\n", err)
+				os.Exit(3)
+			}
+			leaves := map[string]string{}
+			if _, isMap := data.(map[string]interface{}); isMap {
+				flattenEnv(leaves, "", data, separator)
+			} else {
+				flattenEnv(leaves, ref.Key, data, separator)
+			}
+
+			names := map[string]string{}
+			for key, value := range leaves {
+				name := sanitizeEnvName(prefix+key, keepCase)
+				if other, present := names[name]; present {
+					fmt.Fprintf(os.Stderr, "\nKeys %q and %q of %s are both exported as %s\n\n",
+						other, key, ref, name)
+					os.Exit(1)
+				}
+				names[name] = key
+				values[name] = value
+			}
+		}
+
+		names := make([]string, 0, len(values))
+		for name := range values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		vars := []envVar{}
+		for _, name := range names {
+			vars = append(vars, envVar{Name: name, Value: values[name]})
+		}
+		rendered, err := formatEnv(format, vars)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\n%v\n\n", err)
+			os.Exit(1)
+		}
+
+		if out.Path == "" {
+			fmt.Print(rendered)
+			os.Exit(0)
+		}
+		path, _, _, err := writeFileSecret(out, "", strings.NewReader(rendered))
+		if err != nil {
+			fmt.Printf("\nError writing %s - %v\n", out.Path, err)
+			os.Exit(4)
+		}
+		fmt.Printf("\n%d variables written to %s\n\n", len(vars), path)
+		os.Exit(0)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportEnvCmd)
+	exportEnvCmd.Flags().StringArrayP("secret", "s", []string{},
+		"Secret to export, as pasm://BOX/SECRET[#KEY][@VERSION]. "+
+			"Specify multiple times to export several Secrets")
+	exportEnvCmd.Flags().StringP("prefix", "p", "",
+		"Prefix added to every variable name, e.g. APP_")
+	exportEnvCmd.Flags().String("separator", "_",
+		"Separator between the keys of nested values")
+	exportEnvCmd.Flags().Bool("keep-case", false,
+		"Keep the case of key names instead of upper-casing them")
+	exportEnvCmd.Flags().StringP("format", "F", exportEnvFormatDotenv,
+		"Output format: dotenv, export (POSIX shell), fish or systemd (EnvironmentFile)")
+	exportEnvCmd.Flags().String(fileSecretOptionOutput, "",
+		"Write the variables to this file instead of standard output. "+
+			"The file is written atomically.")
+	exportEnvCmd.Flags().String(fileSecretOptionMode, DefaultFileSecretMode,
+		"Permissions of the file written with --"+fileSecretOptionOutput)
+	exportEnvCmd.Flags().Bool(fileSecretOptionForce, false,
+		"Overwrite the file given with --"+fileSecretOptionOutput+" if it exists")
+
+	// mark mandatory fields as required
+	exportEnvCmd.MarkFlagRequired("secret")
+}
@@ -0,0 +1,490 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	K8sSecretTypeOpaque     = "Opaque"
+	K8sSecretTypeTLS        = "kubernetes.io/tls"
+	K8sSecretTypeSSHAuth    = "kubernetes.io/ssh-auth"
+	K8sSecretTypeDockerJSON = "kubernetes.io/dockerconfigjson"
+
+	// annotations recording where a manifest came from
+	k8sAnnotationBox     = "pasm.entrust.com/box"
+	k8sAnnotationSecret  = "pasm.entrust.com/secret"
+	k8sAnnotationVersion = "pasm.entrust.com/version"
+)
+
+var (
+	k8sLabelNameRegexp  = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?$`)
+	k8sLabelValueRegexp = regexp.MustCompile(`^([A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?)?$`)
+	k8sDNSPrefixRegexp  = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]{0,251}[a-z0-9])?$`)
+	k8sInvalidKeyChars  = regexp.MustCompile(`[^-._A-Za-z0-9]`)
+	k8sInvalidNameChars = regexp.MustCompile(`[^-.a-z0-9]+`)
+
+	// keys a TLS Secret may hold its certificate and private key under
+	k8sTLSCertKeys = []string{"tls.crt", "certificate", "cert", "crt"}
+	k8sTLSKeyKeys  = []string{"tls.key", "private_key", "key"}
+)
+
+// k8sSecret is a Kubernetes Secret manifest, fields in kubectl order
+type k8sSecret struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   k8sObjectMeta     `yaml:"metadata"`
+	Type       string            `yaml:"type"`
+	Data       map[string]string `yaml:"data"`
+}
+
+type k8sObjectMeta struct {
+	Name        string            `yaml:"name"`
+	Namespace   string            `yaml:"namespace,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// isK8sLabel tells if a tag can be a label, otherwise it becomes an
+// annotation
+func isK8sLabel(key string, value string) bool {
+	name := key
+	if slash := strings.LastIndex(key, "/"); slash != -1 {
+		if !k8sDNSPrefixRegexp.MatchString(key[:slash]) {
+			return false
+		}
+		name = key[slash+1:]
+	}
+	return k8sLabelNameRegexp.MatchString(name) && k8sLabelValueRegexp.MatchString(value)
+}
+
+// k8sObjectName turns a Secret name into a valid object name
+func k8sObjectName(name string) string {
+	objectName := k8sInvalidNameChars.ReplaceAllString(strings.ToLower(name), "-")
+	if len(objectName) > 253 {
+		objectName = objectName[:253]
+	}
+	objectName = strings.Trim(objectName, "-.")
+	if objectName == "" {
+		objectName = "secret"
+	}
+	return objectName
+}
+
+// addK8sTags adds tags as labels, or as annotations when they aren't valid
+// labels
+func addK8sTags(meta *k8sObjectMeta, tags interface{}) {
+	tagMap, isMap := tags.(map[string]interface{})
+	if !isMap {
+		return
+	}
+	for key, value := range tagMap {
+		str, isString := value.(string)
+		if !isString {
+			encoded, _ := json.Marshal(value)
+			str = string(encoded)
+		}
+		if isK8sLabel(key, str) {
+			meta.Labels[key] = str
+			delete(meta.Annotations, key)
+		} else {
+			meta.Annotations[key] = str
+			delete(meta.Labels, key)
+		}
+	}
+}
+
+func b64(value string) string {
+	return base64.StdEncoding.EncodeToString([]byte(value))
+}
+
+// k8sValue returns a Secret value as the string stored in the manifest
+func k8sValue(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case nil:
+		return ""
+	}
+	encoded, _ := JSONMarshalIndent(value)
+	return strings.TrimSpace(string(encoded))
+}
+
+// firstKey returns the first of keys present in data
+func firstKey(data map[string]interface{}, keys []string) (string, bool) {
+	for _, key := range keys {
+		if _, present := data[key]; present {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// inferK8sSecretType picks the Kubernetes Secret type from the Secret
+// subtype, or from the keys of a key-value Secret
+func inferK8sSecretType(subtype string, data interface{}) string {
+	subtype = strings.ToLower(subtype)
+	kv, isMap := data.(map[string]interface{})
+	switch {
+	case strings.Contains(subtype, "ssh"):
+		return K8sSecretTypeSSHAuth
+	case strings.Contains(subtype, "docker"):
+		return K8sSecretTypeDockerJSON
+	case !isMap:
+		return K8sSecretTypeOpaque
+	}
+	if _, present := kv[".dockerconfigjson"]; present {
+		return K8sSecretTypeDockerJSON
+	}
+	_, hasCert := firstKey(kv, k8sTLSCertKeys)
+	_, hasKey := firstKey(kv, k8sTLSKeyKeys)
+	_, hasTLSCert := kv["tls.crt"]
+	if hasCert && hasKey && (hasTLSCert || strings.Contains(subtype, "tls") ||
+		strings.Contains(subtype, "cert")) {
+		return K8sSecretTypeTLS
+	}
+	return K8sSecretTypeOpaque
+}
+
+// k8sSecretData builds the base64 encoded data of a Secret of the given
+// type. Keys not part of the type are kept as they are.
+func k8sSecretData(secretType string, data interface{}) (map[string]string, error) {
+	kv, isMap := data.(map[string]interface{})
+	if !isMap {
+		if secretType != K8sSecretTypeOpaque {
+			return nil, fmt.Errorf("a %s Secret needs a key-value Secret", secretType)
+		}
+		return map[string]string{"value": b64(k8sValue(data))}, nil
+	}
+
+	entries := map[string]string{}
+	special := map[string]bool{}
+	switch secretType {
+	case K8sSecretTypeTLS:
+		certKey, hasCert := firstKey(kv, k8sTLSCertKeys)
+		keyKey, hasKey := firstKey(kv, k8sTLSKeyKeys)
+		if !hasCert || !hasKey {
+			return nil, fmt.Errorf("a %s Secret needs a certificate and a private key", secretType)
+		}
+		entries["tls.crt"] = b64(k8sValue(kv[certKey]))
+		entries["tls.key"] = b64(k8sValue(kv[keyKey]))
+		special[certKey], special[keyKey] = true, true
+	case K8sSecretTypeSSHAuth:
+		privateKey, present := kv["private_key"].(string)
+		if !present {
+			if privateKey, present = kv["ssh-privatekey"].(string); !present {
+				return nil, fmt.Errorf("a %s Secret needs a private key", secretType)
+			}
+			privateKey = b64(privateKey)
+		}
+		// SSH key Secrets hold the key file base64 encoded already
+		if _, err := base64.StdEncoding.DecodeString(privateKey); err != nil {
+			return nil, fmt.Errorf("the private key is not base64 encoded")
+		}
+		entries["ssh-privatekey"] = privateKey
+		special["private_key"], special["ssh-privatekey"] = true, true
+	case K8sSecretTypeDockerJSON:
+		if config, present := kv[".dockerconfigjson"]; present {
+			entries[".dockerconfigjson"] = b64(k8sValue(config))
+			special[".dockerconfigjson"] = true
+			break
+		}
+		registryKey, hasRegistry := firstKey(kv, []string{"registry", "server", "host"})
+		_, hasUser := kv["username"]
+		_, hasPassword := kv["password"]
+		if !hasRegistry || !hasUser || !hasPassword {
+			return nil, fmt.Errorf("a %s Secret needs .dockerconfigjson, or a "+
+				"registry, username and password", secretType)
+		}
+		username, password := k8sValue(kv["username"]), k8sValue(kv["password"])
+		auth := map[string]interface{}{
+			"username": username,
+			"password": password,
+			"auth":     b64(username + ":" + password),
+		}
+		config := map[string]interface{}{
+			"auths": map[string]interface{}{k8sValue(kv[registryKey]): auth},
+		}
+		encoded, _ := json.Marshal(config)
+		entries[".dockerconfigjson"] = b64(string(encoded))
+		special[registryKey], special["username"], special["password"] = true, true, true
+	}
+
+	for key, value := range kv {
+		if special[key] {
+			continue
+		}
+		entryKey := k8sInvalidKeyChars.ReplaceAllString(key, "_")
+		if _, present := entries[entryKey]; present {
+			return nil, fmt.Errorf("more than one key is stored as %q", entryKey)
+		}
+		entries[entryKey] = b64(k8sValue(value))
+	}
+	return entries, nil
+}
+
+// exportK8sSecret builds the manifest of one Secret in a Box
+func exportK8sSecret(box map[string]interface{}, secretId string,
+	namespace string, secretType string) (k8sSecret, error) {
+
+	manifest := k8sSecret{APIVersion: "v1", Kind: "Secret"}
+	boxId, _ := box["box_id"].(string)
+	boxName, _ := box["name"].(string)
+
+	params := map[string]interface{}{}
+	params["box_id"] = boxId
+	params["secret_id"] = secretId
+	metadata, _, err := postVaultAPI("GetSecretMetadata", params)
+	if err != nil {
+		return manifest, fmt.Errorf("Unable to get Secret %s - %v", secretId, err)
+	}
+	secretName, _ := metadata["name"].(string)
+	if secretName == "" {
+		secretName = secretId
+	}
+
+	// GetSecret reads the Secret without leaving a lease behind
+	jsonParams, _ := json.Marshal(params)
+	retStatus, retStr, spool, err := postSecretAPI("GetSecret", jsonParams)
+	defer spool.Close()
+	if err == nil {
+		err = responseError(retStr)
+	}
+	if err == nil && retStatus != 200 {
+		err = fmt.Errorf("HTTP %d", retStatus)
+	}
+	if err != nil {
+		return manifest, fmt.Errorf("Unable to get Secret %s - %v", secretName, err)
+	}
+	retMap := map[string]interface{}{}
+	if retStr != "" {
+		retMap = JsonStrToMap(retStr)
+	}
+
+	subtypeInfo, _ := retMap["secret_subtype_info"].(map[string]interface{})
+	subtype, _ := subtypeInfo["type"].(string)
+	if config, isMap := metadata["secret_config"].(map[string]interface{}); isMap && subtype == "" {
+		subtype, _ = config["type"].(string)
+	}
+
+	if spool != nil {
+		// file Secrets are stored under their file name
+		if secretType != "" && secretType != K8sSecretTypeOpaque {
+			return manifest, fmt.Errorf("file Secret %s can only be exported as %s",
+				secretName, K8sSecretTypeOpaque)
+		}
+		encoded, err := spool.encoded()
+		if err != nil {
+			return manifest, fmt.Errorf("Unable to read file Secret %s - %v", secretName, err)
+		}
+		filename := k8sInvalidKeyChars.ReplaceAllString(getFilename(subtypeInfo), "_")
+		if filename == "" {
+			filename = secretName
+		}
+		manifest.Type = K8sSecretTypeOpaque
+		manifest.Data = map[string]string{filename: encoded}
+	} else {
+		data := retMap["secret_data"]
+		if str, isString := data.(string); isString {
+			var decoded map[string]interface{}
+			if json.Unmarshal([]byte(str), &decoded) == nil {
+				data = decoded
+			} else if subtype == "password" {
+				data = map[string]interface{}{"password": str}
+			}
+		}
+		manifest.Type = secretType
+		if manifest.Type == "" {
+			manifest.Type = inferK8sSecretType(subtype, data)
+		}
+		if manifest.Data, err = k8sSecretData(manifest.Type, data); err != nil {
+			return manifest, fmt.Errorf("Unable to export Secret %s - %v", secretName, err)
+		}
+	}
+
+	manifest.Metadata = k8sObjectMeta{
+		Name:        k8sObjectName(secretName),
+		Namespace:   namespace,
+		Labels:      map[string]string{},
+		Annotations: map[string]string{},
+	}
+	// Secret tags win over Box tags
+	addK8sTags(&manifest.Metadata, box["tags"])
+	addK8sTags(&manifest.Metadata, metadata["tags"])
+	manifest.Metadata.Annotations[k8sAnnotationBox] = boxName
+	manifest.Metadata.Annotations[k8sAnnotationSecret] = secretName
+	if version, present := retMap["version"]; present {
+		manifest.Metadata.Annotations[k8sAnnotationVersion] = k8sValue(version)
+	}
+	return manifest, nil
+}
+
+// exportK8sCmd represents the export-k8s command
+var exportK8sCmd = &cobra.Command{
+	Use:   "export-k8s",
+	Short: "Export Secrets as Kubernetes Secret manifests",
+	Long: `Read Secrets of a Box and print them as Kubernetes Secret manifests,
+ready for kubectl apply. Key-value Secrets have an entry per key, and file
+Secrets are stored under their file name. Box and Secret tags become labels,
+or annotations when they are not valid label keys or values.
+
+The manifest type is inferred from the Secret:
+  kubernetes.io/ssh-auth          SSH key Secrets
+  kubernetes.io/tls               key-value Secrets with tls.crt and tls.key
+  kubernetes.io/dockerconfigjson  key-value Secrets with .dockerconfigjson,
+                                  or registry, username and password
+  Opaque                          anything else
+
+Examples:
+  pasmcli export-k8s --boxid app --namespace prod | kubectl apply -f -
+  pasmcli export-k8s -b app -s registry --type kubernetes.io/dockerconfigjson`,
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+
+		boxid, _ := flags.GetString("boxid")
+		secretIds, _ := flags.GetStringArray("secretid")
+		namespace, _ := flags.GetString("namespace")
+		secretType, _ := flags.GetString("type")
+		switch secretType {
+		case "", K8sSecretTypeOpaque, K8sSecretTypeTLS,
+			K8sSecretTypeSSHAuth, K8sSecretTypeDockerJSON:
+		default:
+			fmt.Printf("Invalid --type %q. Expected %s, %s, %s or %s\n", secretType,
+				K8sSecretTypeOpaque, K8sSecretTypeTLS, K8sSecretTypeSSHAuth,
+				K8sSecretTypeDockerJSON)
+			os.Exit(1)
+		}
+
+		out := fileSecretOutput{}
+		out.Path, _ = flags.GetString(fileSecretOptionOutput)
+		if out.Path == "" {
+			// the output is written to stdout, keep it clean
+			InsecureBannerOutput = os.Stderr
+		}
+		out.Force, _ = flags.GetBool(fileSecretOptionForce)
+		mode, _ := flags.GetString(fileSecretOptionMode)
+		var err error
+		if out.Mode, err = parseFileMode(mode); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		params := map[string]interface{}{}
+		params["box_id"] = boxid
+		box, _, err := postVaultAPI("GetBox", params)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\nUnable to get Box %s - %v\n\n", boxid, err)
+			os.Exit(3)
+		}
+		boxId, _ := box["box_id"].(string)
+
+		// all the Secrets of the Box by default
+		if len(secretIds) == 0 {
+			params["box_id"] = boxId
+			_, _, err := ListAllPages("ListSecrets", params, ListPaging{All: true},
+				func(item interface{}) error {
+					secret, _ := item.(map[string]interface{})
+					if secretId, _ := secret["secret_id"].(string); secretId != "" {
+						secretIds = append(secretIds, secretId)
+					}
+					return nil
+				})
+			ExitOnListError(err, "Secrets not found")
+		}
+
+		var builder strings.Builder
+		names := map[string]string{}
+		for index, secretId := range secretIds {
+			manifest, err := exportK8sSecret(box, secretId, namespace, secretType)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "\n%v\n\n", err)
+				os.Exit(3)
+			}
+			name := manifest.Metadata.Name
+			if other, present := names[name]; present {
+				fmt.Fprintf(os.Stderr, "\nSecrets %s and %s are both exported as %s\n\n",
+					other, manifest.Metadata.Annotations[k8sAnnotationSecret], name)
+				os.Exit(1)
+			}
+			names[name] = manifest.Metadata.Annotations[k8sAnnotationSecret]
+
+			var encoded bytes.Buffer
+			encoder := yaml.NewEncoder(&encoded)
+			encoder.SetIndent(2)
+			err = encoder.Encode(manifest)
+			if err == nil {
+				err = encoder.Close()
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "\nError building manifest - %v\n\n", err)
+				os.Exit(4)
+			}
+			if index > 0 {
+				builder.WriteString("---\n")
+			}
+			builder.Write(encoded.Bytes())
+		}
+
+		if out.Path == "" {
+			fmt.Print(builder.String())
+			os.Exit(0)
+		}
+		path, _, _, err := writeFileSecret(out, "", strings.NewReader(builder.String()))
+		if err != nil {
+			fmt.Printf("\nError writing %s - %v\n", out.Path, err)
+			os.Exit(4)
+		}
+		fmt.Printf("\n%d manifests written to %s\n\n", len(secretIds), path)
+		os.Exit(0)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportK8sCmd)
+	exportK8sCmd.Flags().StringP("boxid", "b", "",
+		"Id or name of the Box to export Secrets from")
+	exportK8sCmd.Flags().StringArrayP("secretid", "s", []string{},
+		"Id or name of a Secret to export. Specify multiple times to export "+
+			"several Secrets. Default is all the Secrets of the Box")
+	exportK8sCmd.Flags().StringP("namespace", "n", "",
+		"Namespace of the manifests")
+	exportK8sCmd.Flags().String("type", "",
+		"Type of the manifests, instead of inferring it from each Secret")
+	exportK8sCmd.Flags().String(fileSecretOptionOutput, "",
+		"Write the manifests to this file instead of standard output. "+
+			"The file is written atomically.")
+	exportK8sCmd.Flags().String(fileSecretOptionMode, DefaultFileSecretMode,
+		"Permissions of the file written with --"+fileSecretOptionOutput)
+	exportK8sCmd.Flags().Bool(fileSecretOptionForce, false,
+		"Overwrite the file given with --"+fileSecretOptionOutput+" if it exists")
+
+	// mark mandatory fields as required
+	exportK8sCmd.MarkFlagRequired("boxid")
+}
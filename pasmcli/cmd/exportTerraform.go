@@ -0,0 +1,365 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// terraformSecretFields are the Secret metadata exported, never its value
+var terraformSecretFields = []string{
+	"desc", "secret_type", "secret_subtype_info", "secret_config", "lease",
+	"rotation", "exclusive_checkout", "expires_at", "tags",
+}
+
+// terraformPolicyFields are the Policy settings exported, but resources
+var terraformPolicyFields = []string{"desc", "role", "principals", "tags"}
+
+// hclExpression is written as is, unquoted, e.g. a resource reference
+type hclExpression string
+
+var hclIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// terraformNameInvalid matches what isn't allowed in resource names
+var terraformNameInvalid = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// hclString quotes a string, escaping the template sequences
+func hclString(value string) string {
+	quoted := strconv.Quote(value)
+	quoted = strings.ReplaceAll(quoted, "${", "$${")
+	return strings.ReplaceAll(quoted, "%{", "%%{")
+}
+
+// writeHCLValue writes a decoded JSON value as an HCL expression, objects
+// spread over lines indented by depth
+func writeHCLValue(out *bytes.Buffer, value interface{}, depth int) {
+	indent := strings.Repeat("  ", depth)
+	switch value := value.(type) {
+	case nil:
+		out.WriteString("null")
+	case hclExpression:
+		out.WriteString(string(value))
+	case string:
+		out.WriteString(hclString(value))
+	case bool:
+		out.WriteString(strconv.FormatBool(value))
+	case float64:
+		out.WriteString(strconv.FormatFloat(value, 'f', -1, 64))
+	case []interface{}:
+		if len(value) == 0 {
+			out.WriteString("[]")
+			return
+		}
+		out.WriteString("[\n")
+		for _, element := range value {
+			out.WriteString(indent + "  ")
+			writeHCLValue(out, element, depth+1)
+			out.WriteString(",\n")
+		}
+		out.WriteString(indent + "]")
+	case map[string]interface{}:
+		if len(value) == 0 {
+			out.WriteString("{}")
+			return
+		}
+		out.WriteString("{\n")
+		writeHCLAttributes(out, value, depth+1)
+		out.WriteString(indent + "}")
+	default:
+		out.WriteString(hclString(fmt.Sprint(value)))
+	}
+}
+
+// writeHCLAttributes writes KEY = VALUE lines sorted by key
+func writeHCLAttributes(out *bytes.Buffer, attributes map[string]interface{}, depth int) {
+	keys := []string{}
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name := key
+		if !hclIdentifier.MatchString(key) {
+			name = hclString(key)
+		}
+		out.WriteString(strings.Repeat("  ", depth) + name + " = ")
+		writeHCLValue(out, attributes[key], depth)
+		out.WriteString("\n")
+	}
+}
+
+// terraformNames hands out resource names derived from Vault names, unique
+// and stable for the same Vault content
+type terraformNames struct {
+	used map[string]bool
+}
+
+func (n *terraformNames) name(parts ...string) string {
+	name := strings.ToLower(strings.Join(parts, "_"))
+	name = terraformNameInvalid.ReplaceAllString(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	unique := name
+	for suffix := 2; n.used[unique]; suffix += 1 {
+		unique = fmt.Sprintf("%s_%d", name, suffix)
+	}
+	n.used[unique] = true
+	return unique
+}
+
+// terraformResource is a resource block and the id to import it from
+type terraformResource struct {
+	Type       string
+	Name       string
+	ImportId   string
+	Attributes map[string]interface{}
+}
+
+func (r terraformResource) address() string {
+	return r.Type + "." + r.Name
+}
+
+// writeTerraformResources writes resource blocks, or with imports the
+// import blocks for them
+func writeTerraformResources(resources []terraformResource, imports bool) []byte {
+	out := &bytes.Buffer{}
+	out.WriteString("# Generated by pasmcli export-terraform\n")
+	for _, resource := range resources {
+		out.WriteString("\n")
+		if imports {
+			fmt.Fprintf(out, "import {\n  to = %s\n  id = %s\n}\n",
+				resource.address(), hclString(resource.ImportId))
+			continue
+		}
+		fmt.Fprintf(out, "resource %s %s {\n", hclString(resource.Type), hclString(resource.Name))
+		writeHCLAttributes(out, resource.Attributes, 1)
+		out.WriteString("}\n")
+	}
+	return out.Bytes()
+}
+
+// listItems lists all the items of action, sorted by name
+func listItems(action string, params map[string]interface{}) ([]map[string]interface{}, error) {
+	items := []map[string]interface{}{}
+	_, _, err := ListAllPages(action, params, ListPaging{All: true},
+		func(item interface{}) error {
+			if itemMap, isMap := item.(map[string]interface{}); isMap {
+				items = append(items, itemMap)
+			}
+			return nil
+		})
+	if err == errListNotFound {
+		err = nil
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return fmt.Sprint(items[i]["name"]) < fmt.Sprint(items[j]["name"])
+	})
+	return items, err
+}
+
+// copyFields returns the fields of item which are set
+func copyFields(item map[string]interface{}, fields []string) map[string]interface{} {
+	copied := map[string]interface{}{}
+	for _, field := range fields {
+		if value, present := item[field]; present && value != nil {
+			copied[field] = value
+		}
+	}
+	return copied
+}
+
+// exportTerraformCmd represents the export-terraform command
+var exportTerraformCmd = &cobra.Command{
+	Use:   "export-terraform",
+	Short: "Export Boxes, Secret metadata and Policies as Terraform configuration",
+	Long: `Write the Boxes, the metadata of their Secrets and the Policies of the
+Vault as Terraform resource blocks, along with import blocks binding them to
+the existing items. Secret values are never exported.
+
+The files boxes.tf, secrets.tf, policies.tf and imports.tf are written to
+the --out directory. Attributes are named as the Vault APIs name the
+fields. Resource names are derived from the Box, Secret and Policy names,
+so exporting the same Vault again gives the same configuration. Box and
+Secret ids in Secrets and Policy resources refer to the exported resources.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+		outDir, _ := flags.GetString("out")
+		force, _ := flags.GetBool("force")
+		prefix, _ := flags.GetString("resource-prefix")
+
+		files := []string{"boxes.tf", "secrets.tf", "policies.tf", "imports.tf"}
+		if !force {
+			for _, file := range files {
+				if _, err := os.Stat(filepath.Join(outDir, file)); err == nil {
+					fmt.Printf("\n%s already exists, use --force to overwrite it\n\n",
+						filepath.Join(outDir, file))
+					os.Exit(1)
+				}
+			}
+		}
+
+		// resource names are unique per resource type
+		boxNames := &terraformNames{used: map[string]bool{}}
+		secretNames := &terraformNames{used: map[string]bool{}}
+		policyNames := &terraformNames{used: map[string]bool{}}
+		boxes := []terraformResource{}
+		secrets := []terraformResource{}
+		policies := []terraformResource{}
+		// references to exported Boxes and Secrets, by id
+		references := map[string]string{}
+
+		boxItems, err := listItems("ListBoxes", map[string]interface{}{})
+		if err != nil {
+			fmt.Printf("\nUnable to list Boxes - %v\n\n", err)
+			os.Exit(3)
+		}
+		for _, box := range boxItems {
+			boxId, _ := box["box_id"].(string)
+			boxName, _ := box["name"].(string)
+			attributes := copyFields(box, clonedBoxFields)
+			attributes["name"] = boxName
+			resource := terraformResource{
+				Type:       prefix + "_box",
+				Name:       boxNames.name(boxName),
+				ImportId:   boxId,
+				Attributes: attributes,
+			}
+			boxes = append(boxes, resource)
+			references[boxId] = resource.address() + ".id"
+
+			params := map[string]interface{}{}
+			params["box_id"] = boxId
+			secretItems, err := listItems("ListSecrets", params)
+			if err != nil {
+				fmt.Printf("\nUnable to list the Secrets of Box %s - %v\n\n", boxName, err)
+				os.Exit(3)
+			}
+			for _, secret := range secretItems {
+				secretId, _ := secret["secret_id"].(string)
+				secretName, _ := secret["name"].(string)
+				attributes := copyFields(secret, terraformSecretFields)
+				attributes["name"] = secretName
+				attributes["box_id"] = hclExpression(references[boxId])
+				resource := terraformResource{
+					Type:       prefix + "_secret",
+					Name:       secretNames.name(boxName, secretName),
+					ImportId:   boxId + "/" + secretId,
+					Attributes: attributes,
+				}
+				secrets = append(secrets, resource)
+				references[boxId+"/"+secretId] = resource.address() + ".secret_id"
+			}
+		}
+
+		policyItems, err := listItems("ListPolicies", map[string]interface{}{})
+		if err != nil {
+			fmt.Printf("\nUnable to list Policies - %v\n\n", err)
+			os.Exit(3)
+		}
+		for _, listed := range policyItems {
+			params := map[string]interface{}{}
+			params["policy_id"] = listed["policy_id"]
+			policy, _, err := postVaultAPI("GetPolicy", params)
+			if err != nil {
+				fmt.Printf("\nUnable to get Policy %v - %v\n\n", listed["name"], err)
+				os.Exit(3)
+			}
+			policyId, _ := policy["policy_id"].(string)
+			policyName, _ := policy["name"].(string)
+			attributes := copyFields(policy, terraformPolicyFields)
+			attributes["name"] = policyName
+
+			if resources, present := policy["resources"].([]interface{}); present {
+				exported := []interface{}{}
+				for _, resource := range resources {
+					resourceMap, _ := resource.(map[string]interface{})
+					boxId, _ := resourceMap["box_id"].(string)
+					exportedResource := map[string]interface{}{"box_id": boxId}
+					if reference, known := references[boxId]; known {
+						exportedResource["box_id"] = hclExpression(reference)
+					}
+					secretIds := []interface{}{}
+					listedIds, _ := resourceMap["secret_id"].([]interface{})
+					for _, secretId := range listedIds {
+						id, _ := secretId.(string)
+						if reference, known := references[boxId+"/"+id]; known {
+							secretIds = append(secretIds, hclExpression(reference))
+						} else {
+							secretIds = append(secretIds, id)
+						}
+					}
+					exportedResource["secret_id"] = secretIds
+					exported = append(exported, exportedResource)
+				}
+				attributes["resources"] = exported
+			}
+
+			policies = append(policies, terraformResource{
+				Type:       prefix + "_policy",
+				Name:       policyNames.name(policyName),
+				ImportId:   policyId,
+				Attributes: attributes,
+			})
+		}
+
+		if err := os.MkdirAll(outDir, 0755); err != nil {
+			fmt.Printf("\nUnable to create %s - %v\n\n", outDir, err)
+			os.Exit(4)
+		}
+		all := append(append(append([]terraformResource{}, boxes...), secrets...), policies...)
+		contents := [][]byte{
+			writeTerraformResources(boxes, false),
+			writeTerraformResources(secrets, false),
+			writeTerraformResources(policies, false),
+			writeTerraformResources(all, true),
+		}
+		for index, file := range files {
+			path := filepath.Join(outDir, file)
+			if err := os.WriteFile(path, contents[index], 0644); err != nil {
+				fmt.Printf("\nUnable to write %s - %v\n\n", path, err)
+				os.Exit(4)
+			}
+		}
+		fmt.Printf("\nExported %d Boxes, %d Secrets and %d Policies to %s\n\n",
+			len(boxes), len(secrets), len(policies), outDir)
+		os.Exit(0)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportTerraformCmd)
+	exportTerraformCmd.Flags().String("out", "",
+		"Directory to write the Terraform files to")
+	exportTerraformCmd.Flags().Bool("force", false,
+		"Overwrite Terraform files already in the directory")
+	exportTerraformCmd.Flags().String("resource-prefix", "pasm",
+		"Prefix of the resource types, e.g. pasm for pasm_box, pasm_secret "+
+			"and pasm_policy")
+
+	// mark mandatory fields as required
+	exportTerraformCmd.MarkFlagRequired("out")
+}
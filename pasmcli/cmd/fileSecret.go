@@ -0,0 +1,184 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	fileSecretOptionOutput    = "output"
+	fileSecretOptionOutputDir = "output-dir"
+	fileSecretOptionStdout    = "stdout"
+	fileSecretOptionMode      = "mode"
+	fileSecretOptionForce     = "force"
+	DefaultFileSecretMode     = "0600"
+)
+
+// fileSecretOutput tells where the content of a file Secret is written to
+type fileSecretOutput struct {
+	Path   string
+	Dir    string
+	Stdout bool
+	Mode   os.FileMode
+	Force  bool
+}
+
+func addFileSecretOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().String(fileSecretOptionOutput, "",
+		"For file Secrets, path to write the file to instead of the "+
+			"file name stored with the Secret")
+	cmd.Flags().String(fileSecretOptionOutputDir, "",
+		"For file Secrets, directory to write the file to, using the "+
+			"file name stored with the Secret. Default is the current directory.")
+	cmd.Flags().Bool(fileSecretOptionStdout, false,
+		"For file Secrets, write the file content to standard output")
+	cmd.Flags().String(fileSecretOptionMode, DefaultFileSecretMode,
+		"For file Secrets, permissions (octal) of the written file")
+	cmd.Flags().Bool(fileSecretOptionForce, false,
+		"For file Secrets, overwrite the file if it already exists")
+}
+
+func getFileSecretOutput(flags *pflag.FlagSet) (fileSecretOutput, error) {
+	var out fileSecretOutput
+	out.Path, _ = flags.GetString(fileSecretOptionOutput)
+	out.Dir, _ = flags.GetString(fileSecretOptionOutputDir)
+	out.Stdout, _ = flags.GetBool(fileSecretOptionStdout)
+	out.Force, _ = flags.GetBool(fileSecretOptionForce)
+
+	destinations := 0
+	for _, set := range []bool{out.Path != "", out.Dir != "", out.Stdout} {
+		if set {
+			destinations += 1
+		}
+	}
+	if destinations > 1 {
+		return out, fmt.Errorf("Specify only one of --%s, --%s and --%s",
+			fileSecretOptionOutput, fileSecretOptionOutputDir, fileSecretOptionStdout)
+	}
+
+	mode, _ := flags.GetString(fileSecretOptionMode)
+	var err error
+	out.Mode, err = parseFileMode(mode)
+	return out, err
+}
+
+// parseFileMode parses octal permissions given with --mode
+func parseFileMode(mode string) (os.FileMode, error) {
+	perm, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || perm > 0777 {
+		return 0, fmt.Errorf("Invalid --%s %q. Expected octal permissions, e.g. 0600",
+			fileSecretOptionMode, mode)
+	}
+	return os.FileMode(perm), nil
+}
+
+// isRequested tells if any of the file Secret output options was given
+func (out fileSecretOutput) isRequested() bool {
+	return out.Path != "" || out.Dir != "" || out.Stdout
+}
+
+// validateFileSecretName makes sure a file name stored with a Secret is a
+// plain file name, which can't point outside of the directory it's written to
+func validateFileSecretName(name string) error {
+	if name == "" || name == "." || name == ".." ||
+		strings.ContainsAny(name, `/\`+"\x00") ||
+		filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return fmt.Errorf("Unsafe file name %q stored with the Secret. "+
+			"Use --%s to choose where to write the file", name, fileSecretOptionOutput)
+	}
+	return nil
+}
+
+// target returns the path to write a file Secret stored under name to
+func (out fileSecretOutput) target(name string) (string, error) {
+	if out.Path != "" {
+		return out.Path, nil
+	}
+	if err := validateFileSecretName(name); err != nil {
+		return "", err
+	}
+	return filepath.Join(out.Dir, name), nil
+}
+
+// writeFileSecret writes the file Secret content to the requested
+// destination and returns where it went along with the content size and
+// SHA-256. Files are written to a temporary file first and renamed into
+// place, so a failed write never leaves a partial file behind.
+func writeFileSecret(out fileSecretOutput, name string, content io.Reader) (string, int64, string, error) {
+	hash := sha256.New()
+	if out.Stdout {
+		size, err := io.Copy(io.MultiWriter(os.Stdout, hash), content)
+		return "standard output", size, hex.EncodeToString(hash.Sum(nil)), err
+	}
+
+	path, err := out.target(name)
+	if err != nil {
+		return "", 0, "", err
+	}
+	if _, err := os.Lstat(path); err == nil && !out.Force {
+		return path, 0, "", fmt.Errorf("%s already exists. Use --%s to overwrite it",
+			path, fileSecretOptionForce)
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return path, 0, "", err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	size, err := io.Copy(io.MultiWriter(tmpFile, hash), content)
+	if err == nil {
+		err = tmpFile.Chmod(out.Mode)
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return path, size, "", err
+	}
+
+	// check again, in case the file showed up while we were writing
+	if _, err := os.Lstat(path); err == nil && !out.Force {
+		return path, size, "", fmt.Errorf("%s already exists. Use --%s to overwrite it",
+			path, fileSecretOptionForce)
+	}
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		return path, size, "", err
+	}
+	return path, size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// fileSecretName returns the name to store a local file under in a file
+// Secret. Only the base name is kept, the directories are local detail.
+func fileSecretName(filename string) (string, error) {
+	name := filepath.Base(filename)
+	if err := validateFileSecretName(name); err != nil {
+		return "", fmt.Errorf("Invalid file name %q", filename)
+	}
+	return name, nil
+}
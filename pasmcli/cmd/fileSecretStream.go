@@ -0,0 +1,613 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
+)
+
+const (
+	fileSecretOptionMaxSize  = "max-size"
+	DefaultMaxFileSecretSize = "5MB"
+	// tag recording the SHA-256 of the last uploaded file content
+	FileSecretSHA256Tag    = "file_sha256"
+	progressUpdateInterval = 200 * time.Millisecond
+)
+
+var byteSizeUnits = []struct {
+	suffix string
+	size   int64
+}{
+	// longest suffixes first
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30},
+	{"KB", 1000}, {"MB", 1000 * 1000}, {"GB", 1000 * 1000 * 1000},
+	{"K", 1000}, {"M", 1000 * 1000}, {"G", 1000 * 1000 * 1000},
+	{"B", 1},
+}
+
+// parseByteSize parses sizes like 5MB, 512MiB or 1048576
+func parseByteSize(size string) (int64, error) {
+	size = strings.TrimSpace(size)
+	multiplier := int64(1)
+	for _, unit := range byteSizeUnits {
+		if strings.HasSuffix(strings.ToUpper(size), strings.ToUpper(unit.suffix)) {
+			size = strings.TrimSpace(size[:len(size)-len(unit.suffix)])
+			multiplier = unit.size
+			break
+		}
+	}
+	value, err := strconv.ParseFloat(size, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("Invalid size %q", size)
+	}
+	return int64(value * float64(multiplier)), nil
+}
+
+func formatByteSize(size int64) string {
+	switch {
+	case size >= 1000*1000*1000:
+		return fmt.Sprintf("%.1f GB", float64(size)/(1000*1000*1000))
+	case size >= 1000*1000:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1000*1000))
+	case size >= 1000:
+		return fmt.Sprintf("%.1f KB", float64(size)/1000)
+	}
+	return fmt.Sprintf("%d bytes", size)
+}
+
+func addFileSecretUploadFlags(cmd *cobra.Command) {
+	cmd.Flags().String(fileSecretOptionMaxSize, DefaultMaxFileSecretSize,
+		"Largest file to upload, e.g. 5MB or 512MiB. Raise it only if the "+
+			"Vault accepts larger file Secrets")
+}
+
+// checkFileSecretSize makes sure filename is a non empty regular file within
+// the --max-size limit, and returns its size
+func checkFileSecretSize(flags *pflag.FlagSet, filename string) (int64, error) {
+	maxSizeStr, _ := flags.GetString(fileSecretOptionMaxSize)
+	maxSize, err := parseByteSize(maxSizeStr)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid --%s - %v", fileSecretOptionMaxSize, err)
+	}
+
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return 0, err
+	}
+	if !fi.Mode().IsRegular() {
+		return 0, fmt.Errorf("%s is not a regular file", filename)
+	}
+	if fi.Size() == 0 {
+		return 0, fmt.Errorf("Error. Empty file provided: %s", filename)
+	}
+	if fi.Size() > maxSize {
+		return 0, fmt.Errorf("%s is %s, larger than the %s limit for file Secrets. "+
+			"Use --%s if the Vault accepts larger files", filename,
+			formatByteSize(fi.Size()), formatByteSize(maxSize), fileSecretOptionMaxSize)
+	}
+	return fi.Size(), nil
+}
+
+// progressReader reports how much of a transfer is done on standard error,
+// when standard error is a terminal
+type progressReader struct {
+	reader  io.Reader
+	label   string
+	total   int64
+	done    int64
+	shown   time.Time
+	enabled bool
+}
+
+func newProgressReader(reader io.Reader, label string, total int64) *progressReader {
+	return &progressReader{
+		reader:  reader,
+		label:   label,
+		total:   total,
+		enabled: term.IsTerminal(int(os.Stderr.Fd())),
+	}
+}
+
+func (p *progressReader) Read(buf []byte) (int, error) {
+	n, err := p.reader.Read(buf)
+	p.done += int64(n)
+	if p.enabled && time.Since(p.shown) >= progressUpdateInterval {
+		p.show()
+	}
+	return n, err
+}
+
+func (p *progressReader) show() {
+	p.shown = time.Now()
+	if p.total > 0 {
+		fmt.Fprintf(os.Stderr, "\r%s %s of %s (%d%%)  ", p.label, formatByteSize(p.done),
+			formatByteSize(p.total), p.done*100/p.total)
+	} else {
+		fmt.Fprintf(os.Stderr, "\r%s %s  ", p.label, formatByteSize(p.done))
+	}
+}
+
+func (p *progressReader) finish() {
+	if p.enabled {
+		p.show()
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+// readAPIResponse reads a small API response, indenting JSON like DoPost does
+func readAPIResponse(body io.Reader) (string, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	dst := &bytes.Buffer{}
+	if len(data) != 0 {
+		if err := json.Indent(dst, data, "", "  "); err != nil {
+			return "", fmt.Errorf("Unexpected response: %s", string(data))
+		}
+	}
+	return dst.String(), nil
+}
+
+// postFileSecret posts params to action with the base64 content of filename
+// streamed into secret_data, so the file is never held in memory. The SHA-256
+// of the file is computed on the fly. If tags is not nil, it's sent along
+// with the SHA-256 added under FileSecretSHA256Tag. Returns the HTTP status,
+// the response body and the SHA-256.
+func postFileSecret(action string,
+	params map[string]interface{},
+	tags map[string]interface{},
+	filename string,
+	size int64) (int, string, string, error) {
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return 0, "", "", err
+	}
+	defer file.Close()
+	return postFileSecretContent(action, params, tags, file, filename, size)
+}
+
+// postFileSecretContent is postFileSecret for content read from any reader,
+// size bytes long. name is used in messages.
+func postFileSecretContent(action string,
+	params map[string]interface{},
+	tags map[string]interface{},
+	content io.Reader,
+	name string,
+	size int64) (int, string, string, error) {
+
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return 0, "", "", err
+	}
+	prefix := string(paramsJSON[:len(paramsJSON)-1])
+	if len(params) != 0 {
+		prefix += ","
+	}
+	prefix += `"secret_data":"`
+
+	suffix := func(sum string) ([]byte, error) {
+		if tags == nil {
+			return []byte(`"}`), nil
+		}
+		tags[FileSecretSHA256Tag] = sum
+		tagsJSON, err := json.Marshal(tags)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(`","tags":` + string(tagsJSON) + `}`), nil
+	}
+	// the SHA-256 is only known at the end, but its length is fixed
+	placeholder, err := suffix(strings.Repeat("0", sha256.Size*2))
+	if err != nil {
+		return 0, "", "", err
+	}
+	contentLength := int64(len(prefix)) + int64(base64.StdEncoding.EncodedLen(int(size))) +
+		int64(len(placeholder))
+
+	hash := sha256.New()
+	progress := newProgressReader(content, "Uploading", size)
+	body, bodyWriter := io.Pipe()
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		_, err := io.WriteString(bodyWriter, prefix)
+		if err == nil {
+			encoder := base64.NewEncoder(base64.StdEncoding, bodyWriter)
+			var copied int64
+			copied, err = io.Copy(encoder, io.TeeReader(progress, hash))
+			if err == nil {
+				err = encoder.Close()
+			}
+			if err == nil && copied != size {
+				err = fmt.Errorf("%s changed while uploading it", name)
+			}
+		}
+		if err == nil {
+			var end []byte
+			end, err = suffix(hex.EncodeToString(hash.Sum(nil)))
+			if err == nil {
+				_, err = bodyWriter.Write(end)
+			}
+		}
+		bodyWriter.CloseWithError(err)
+	}()
+
+	endpoint := GetEndPoint("", "1.0", action)
+	response, err := DoPostStream(endpoint,
+		GetCACertFile(),
+		AuthTokenKV(),
+		body,
+		contentLength,
+		"application/json")
+	// unblocks the writer if the request failed before sending everything
+	body.Close()
+	<-sent
+	progress.finish()
+	if err != nil {
+		return 0, "", "", err
+	}
+	defer response.Body.Close()
+
+	retStr, err := readAPIResponse(response.Body)
+	return response.StatusCode, retStr, hex.EncodeToString(hash.Sum(nil)), err
+}
+
+// recordFileSecretDigest tags the Secret with the SHA-256 of the file just
+// uploaded to it, for commands whose API call doesn't take tags
+func recordFileSecretDigest(boxId string, secretId string, sum string) error {
+	params := map[string]interface{}{}
+	params["box_id"] = boxId
+	params["secret_id"] = secretId
+
+	// revision changes under us if someone else updates the Secret, retry once
+	var err error
+	for attempt := 0; attempt < 2; attempt += 1 {
+		var metadata map[string]interface{}
+		metadata, _, err = postVaultAPI("GetSecretMetadata", params)
+		if err != nil {
+			return err
+		}
+		params["revision"] = metadata["revision"]
+		params["tags"] = map[string]interface{}{FileSecretSHA256Tag: sum}
+		_, _, err = postVaultAPI("TagSecret", params)
+		if err == nil {
+			return nil
+		}
+		delete(params, "revision")
+		delete(params, "tags")
+	}
+	return err
+}
+
+// secretSpool holds the still base64 encoded content of a file Secret,
+// spooled to disk while reading a GetSecret or CheckoutSecret response
+type secretSpool struct {
+	file    *os.File
+	removed bool
+}
+
+func newSecretSpool() (*secretSpool, error) {
+	vaultDataDir, err := GetDataDir()
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.CreateTemp(vaultDataDir, "secret_spool_*")
+	if err != nil {
+		return nil, err
+	}
+	// unlink right away where the OS allows it, so nothing is left behind
+	// whichever way pasmcli exits
+	removed := os.Remove(file.Name()) == nil
+	return &secretSpool{file: file, removed: removed}, nil
+}
+
+func (s *secretSpool) Close() {
+	if s == nil {
+		return
+	}
+	s.file.Close()
+	if !s.removed {
+		os.Remove(s.file.Name())
+	}
+}
+
+// content returns a reader of the decoded file Secret content
+func (s *secretSpool) content() (io.Reader, error) {
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return base64.NewDecoder(base64.StdEncoding, bufio.NewReader(s.file)), nil
+}
+
+// encoded returns the spooled secret_data as sent by the Vault
+func (s *secretSpool) encoded() (string, error) {
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	raw, err := io.ReadAll(s.file)
+	if err != nil {
+		return "", err
+	}
+	var value string
+	err = json.Unmarshal([]byte(`"`+string(raw)+`"`), &value)
+	return value, err
+}
+
+func nextNonSpace(reader *bufio.Reader) (byte, error) {
+	for {
+		c, err := reader.ReadByte()
+		if err != nil {
+			return c, err
+		}
+		if !strings.ContainsRune(" \t\r\n", rune(c)) {
+			return c, nil
+		}
+	}
+}
+
+// readJSONValue reads one complete JSON value, which starts with first
+func readJSONValue(reader *bufio.Reader, first byte) ([]byte, error) {
+	value := []byte{first}
+	depth := 0
+	inString := first == '"'
+	switch first {
+	case '{', '[':
+		depth = 1
+	case '"':
+	default:
+		// number, true, false or null
+		for {
+			c, err := reader.ReadByte()
+			if err != nil {
+				return value, err
+			}
+			if strings.ContainsRune(",}] \t\r\n", rune(c)) {
+				return value, reader.UnreadByte()
+			}
+			value = append(value, c)
+		}
+	}
+
+	for {
+		c, err := reader.ReadByte()
+		if err != nil {
+			return value, err
+		}
+		value = append(value, c)
+		switch {
+		case inString && c == '\\':
+			c, err = reader.ReadByte()
+			if err != nil {
+				return value, err
+			}
+			value = append(value, c)
+		case c == '"':
+			inString = !inString
+			if !inString && depth == 0 {
+				return value, nil
+			}
+		case !inString && (c == '{' || c == '['):
+			depth += 1
+		case !inString && (c == '}' || c == ']'):
+			depth -= 1
+			if depth == 0 {
+				return value, nil
+			}
+		}
+	}
+}
+
+// spoolJSONString copies a JSON string, whose opening quote was already
+// read, to spool without the quotes
+func spoolJSONString(reader *bufio.Reader, spool *secretSpool) error {
+	writer := bufio.NewWriter(spool.file)
+	for {
+		c, err := reader.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch c {
+		case '"':
+			return writer.Flush()
+		case '\\':
+			escaped, err := reader.ReadByte()
+			if err != nil {
+				return err
+			}
+			// base64 only ever needs "\/", keep any other escape as is
+			if escaped != '/' {
+				writer.WriteByte(c)
+			}
+			c = escaped
+		}
+		if err := writer.WriteByte(c); err != nil {
+			return err
+		}
+	}
+}
+
+// decodeSecretResponse decodes a GetSecret or CheckoutSecret response. The
+// content of a file Secret is spooled to disk instead of being held in memory
+// and is left empty in the returned map.
+func decodeSecretResponse(body io.Reader) (map[string]interface{}, *secretSpool, error) {
+	retMap := map[string]interface{}{}
+	reader := bufio.NewReader(body)
+	c, err := nextNonSpace(reader)
+	if err == io.EOF {
+		return retMap, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if c != '{' {
+		rest, _ := io.ReadAll(io.LimitReader(reader, 1024))
+		return nil, nil, fmt.Errorf("Unexpected response: %s", string(c)+string(rest))
+	}
+
+	var spool *secretSpool
+	fail := func(err error) (map[string]interface{}, *secretSpool, error) {
+		spool.Close()
+		return nil, nil, err
+	}
+	for {
+		c, err := nextNonSpace(reader)
+		if err != nil {
+			return fail(err)
+		}
+		if c == '}' {
+			break
+		}
+		if c == ',' {
+			continue
+		}
+
+		rawKey, err := readJSONValue(reader, c)
+		if err != nil {
+			return fail(err)
+		}
+		var key string
+		if err := json.Unmarshal(rawKey, &key); err != nil {
+			return fail(err)
+		}
+		if c, err = nextNonSpace(reader); err != nil || c != ':' {
+			return fail(fmt.Errorf("Malformed response"))
+		}
+		if c, err = nextNonSpace(reader); err != nil {
+			return fail(err)
+		}
+
+		if key == "secret_data" && c == '"' && spool == nil {
+			if spool, err = newSecretSpool(); err != nil {
+				return fail(err)
+			}
+			if err := spoolJSONString(reader, spool); err != nil {
+				return fail(err)
+			}
+			retMap[key] = ""
+			continue
+		}
+
+		rawValue, err := readJSONValue(reader, c)
+		if err != nil {
+			return fail(err)
+		}
+		var value interface{}
+		if err := json.Unmarshal(rawValue, &value); err != nil {
+			return fail(err)
+		}
+		retMap[key] = value
+	}
+
+	// only file Secrets stay spooled, anything else is small
+	secret_subtype_info, _ := retMap["secret_subtype_info"].(map[string]interface{})
+	if spool != nil && !isSecretFile(secret_subtype_info) {
+		value, err := spool.encoded()
+		if err != nil {
+			return fail(err)
+		}
+		retMap["secret_data"] = value
+		spool.Close()
+		spool = nil
+	}
+	return retMap, spool, nil
+}
+
+// postSecretAPI posts a GetSecret or CheckoutSecret request, streaming the
+// response so large file Secrets are not held in memory. Returns the HTTP
+// status, the response body and, for file Secrets, the spooled content. The
+// secret_data of file Secrets is left empty in the response body.
+func postSecretAPI(action string, jsonParams []byte) (int, string, *secretSpool, error) {
+	endpoint := GetEndPoint("", "1.0", action)
+	response, err := DoPostStream(endpoint,
+		GetCACertFile(),
+		AuthTokenKV(),
+		bytes.NewReader(jsonParams),
+		int64(len(jsonParams)),
+		"application/json")
+	if err != nil {
+		return 0, "", nil, err
+	}
+	defer response.Body.Close()
+
+	progress := newProgressReader(response.Body, "Downloading", response.ContentLength)
+	retMap, spool, err := decodeSecretResponse(progress)
+	if spool != nil {
+		progress.finish()
+	}
+	if err != nil {
+		return response.StatusCode, "", nil, err
+	}
+	if len(retMap) == 0 {
+		return response.StatusCode, "", spool, nil
+	}
+
+	retBytes, err := JSONMarshalIndent(retMap)
+	if err != nil {
+		spool.Close()
+		return response.StatusCode, "", nil, err
+	}
+	return response.StatusCode, strings.TrimSpace(string(retBytes)), spool, nil
+}
+
+// inlineSpooledSecret puts the spooled base64 content back in the response,
+// for callers which print the whole response
+func inlineSpooledSecret(retStr string, spool *secretSpool) (string, error) {
+	if spool == nil {
+		return retStr, nil
+	}
+	encoded, err := spool.encoded()
+	if err != nil {
+		return retStr, err
+	}
+	retMap := JsonStrToMap(retStr)
+	retMap["secret_data"] = encoded
+	retBytes, err := JSONMarshalIndent(retMap)
+	return strings.TrimSpace(string(retBytes)), err
+}
+
+// checkFileSecretDigest compares the SHA-256 of downloaded file Secret
+// content with the one recorded at upload, if any. Only meaningful for the
+// latest version, the tag follows the last upload.
+func checkFileSecretDigest(retMap map[string]interface{}, sum string) string {
+	tags, _ := retMap["tags"].(map[string]interface{})
+	expected, _ := tags[FileSecretSHA256Tag].(string)
+	switch {
+	case expected == "":
+		return ""
+	case strings.EqualFold(expected, sum):
+		return "SHA-256 matches the one recorded at upload"
+	}
+	return fmt.Sprintf("WARNING: SHA-256 does not match %s recorded at upload (%s)",
+		FileSecretSHA256Tag, expected)
+}
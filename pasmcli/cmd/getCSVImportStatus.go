@@ -32,6 +32,11 @@ var getCSVImportStatusCmd = &cobra.Command{
 	Short:	"Get CSV Import Status",
 	Run: func(cmd *cobra.Command, args []string) {
 	    params := map[string]interface{}{}
+
+	    // poll until the import is over
+	    if follow, _ := cmd.Flags().GetBool("follow"); follow {
+		followCSVImport(cmd)
+	    }
 	    
 	    // JSONify
 	    jsonParams, err := json.Marshal(params)
@@ -61,7 +66,7 @@ var getCSVImportStatusCmd = &cobra.Command{
 		   os.Exit(5)
 	       }
 
-	       fmt.Println("\n" + retStr + "\n")
+	       PrintResponse(cmd, retStr)
 
 	       // make a decision on what to exit with
 	       retMap := JsonStrToMap(retStr)
@@ -76,6 +81,13 @@ var getCSVImportStatusCmd = &cobra.Command{
 
 func init() {
     rootCmd.AddCommand(getCSVImportStatusCmd)
+    getCSVImportStatusCmd.Flags().BoolP("follow", "f", false,
+    	"Poll the status until the import is completed, failed or cancelled, printing the progress " +
+    	"and the failed rows as they appear. Ctrl-C offers to cancel the import")
+    getCSVImportStatusCmd.Flags().String("report", "",
+    	"CSV file to write the failed rows to, with --follow")
+    getCSVImportStatusCmd.Flags().Duration("timeout", DefaultCSVImportFollowTimeout,
+    	"How long to follow the import for, with --follow")
 
     // mark mandatory fields as required
 }
@@ -56,7 +56,7 @@ var getPlatformInfoCmd = &cobra.Command{
             retBytes := ret["data"].(*bytes.Buffer)
             retStr := retBytes.String()
 
-            fmt.Println("\n" + retStr + "\n")
+            PrintResponse(cmd, retStr)
 
             // make a decision on what to exit with
             retMap := JsonStrToMap(retStr)
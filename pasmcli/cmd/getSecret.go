@@ -18,9 +18,9 @@ package cmd
 
 import (
     // standard
+    "io"
     "os"
     "fmt"
-    "bytes"
     "encoding/json"
     // external
     "github.com/spf13/cobra"
@@ -35,7 +35,30 @@ var getSecretCmd = &cobra.Command{
         flags := cmd.Flags()
         params := map[string]interface{}{}
 
-        
+        fileOutput, err := getFileSecretOutput(flags)
+        if err != nil {
+            fmt.Println(err)
+            os.Exit(1)
+        }
+        valueSelector, err := getSecretValueSelector(flags)
+        if err != nil {
+            fmt.Println(err)
+            os.Exit(1)
+        }
+        if (valueSelector.isRequested() &&
+            (fileOutput.isRequested() || GetOutputFormat(cmd) != "")) {
+            fmt.Println("Cannot set field, jsonpath or raw along with output " +
+                        "or file output options")
+            os.Exit(1)
+        }
+        if (fileOutput.Stdout && GetOutputFormat(cmd) != "") {
+            fmt.Println("Cannot set both output and stdout")
+            os.Exit(1)
+        }
+        // keep stdout to the Secret value when it is piped
+        if (fileOutput.Stdout || valueSelector.isRequested()) {
+            InsecureBannerOutput = os.Stderr
+        }
 
         // box id
         boxid, _ := flags.GetString("boxid")
@@ -59,41 +82,83 @@ var getSecretCmd = &cobra.Command{
         }
 
         // now POST
-        endpoint := GetEndPoint("", "1.0", "GetSecret")
-        ret, err := DoPost(endpoint,
-                               GetCACertFile(),
-                               AuthTokenKV(),
-                               jsonParams,
-                               "application/json")
+        retStatus, retStr, spool, err := postSecretAPI("GetSecret", jsonParams)
         if err != nil {
             fmt.Printf("\nHTTP request failed: %s\n", err)
             os.Exit(4)
         } else {
-            // type assertion
-            retBytes := ret["data"].(*bytes.Buffer)
-            retStatus := ret["status"].(int)
-            retStr := retBytes.String()
-
             if (retStr == "" && retStatus == 404) {
                 fmt.Println("\nSecret not found\n")
                 os.Exit(5)
             }
 
-            fmt.Println("\n" + retStr + "\n")
-
-            // make a decision on what to exit with
             retMap := JsonStrToMap(retStr)
-            if _, present := retMap["error"]; present {
-                os.Exit(3)
-            } else {
+            secret_subtype_info, _ := retMap["secret_subtype_info"].(map[string]interface{})
+            _, present := retMap["error"]
+            if (valueSelector.isRequested() && !present) {
+                err := printSecretValue(valueSelector, retMap, spool)
+                spool.Close()
+                if err != nil {
+                    fmt.Fprintf(os.Stderr, "\n%v\n", err)
+                    os.Exit(5)
+                }
+                os.Exit(0)
+            }
+            if present || !fileOutput.isRequested() || spool == nil {
+                // print the whole response, file Secret content included
+                retStr, err = inlineSpooledSecret(retStr, spool)
+                spool.Close()
+                if err != nil {
+                    fmt.Printf("\nError reading Secret data - %v\n", err)
+                    os.Exit(4)
+                }
+                PrintResponse(cmd, retStr)
+                if fileOutput.isRequested() && !present {
+                    fmt.Fprintln(os.Stderr, "File output options are ignored, this is not a file Secret")
+                }
+                // make a decision on what to exit with
+                if present {
+                    os.Exit(3)
+                }
                 os.Exit(0)
             }
+
+            // write the content of the file Secret out
+            // messages go to stderr when the file Secret goes to stdout
+            var msgOut io.Writer = os.Stdout
+            if (fileOutput.Stdout) {
+                msgOut = os.Stderr
+            }
+            filename := getFilename(secret_subtype_info)
+            content, err := spool.content()
+            if err == nil {
+                var path, sum string
+                var size int64
+                path, size, sum, err = writeFileSecret(fileOutput, filename, content)
+                if err == nil {
+                    fmt.Fprintf(msgOut, "\nSuccessfully downloaded %s to %s\nSize: %d bytes\nSHA-256: %s\n",
+                                filename, path, size, sum)
+                    if !flags.Changed("version") {
+                        if check := checkFileSecretDigest(retMap, sum); check != "" {
+                            fmt.Fprintln(msgOut, check)
+                        }
+                    }
+                }
+            }
+            spool.Close()
+            if err != nil {
+                fmt.Fprintf(msgOut, "\nUnable to write file Secret %s - %v\n", filename, err)
+                os.Exit(4)
+            }
+            fmt.Fprintln(msgOut)
+            os.Exit(0)
         }
     },
 }
 
 func init() {
     rootCmd.AddCommand(getSecretCmd)
+    acceptSecretRef(getSecretCmd)
     getSecretCmd.Flags().StringP("boxid", "b", "",
                                  "Id or name of the Box where the Secret is")
     getSecretCmd.Flags().StringP("secretid", "s", "",
@@ -102,6 +167,9 @@ func init() {
                               "Version of the Secret to fetch. If not " +
                               "specified, fetches the current version")
 
+    addFileSecretOutputFlags(getSecretCmd)
+    addSecretValueFlags(getSecretCmd)
+
     // mark mandatory fields as required
     getSecretCmd.MarkFlagRequired("boxid")
     getSecretCmd.MarkFlagRequired("secretid")
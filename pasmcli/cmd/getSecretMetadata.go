@@ -73,7 +73,7 @@ var getSecretMetadataCmd = &cobra.Command{
                 os.Exit(5)
             }
 
-            fmt.Println("\n" + retStr + "\n")
+            PrintResponse(cmd, retStr)
 
             // make a decision on what to exit with
             retMap := JsonStrToMap(retStr)
@@ -88,6 +88,7 @@ var getSecretMetadataCmd = &cobra.Command{
 
 func init() {
     rootCmd.AddCommand(getSecretMetadataCmd)
+    acceptSecretRef(getSecretMetadataCmd)
     getSecretMetadataCmd.Flags().StringP("boxid", "b", "",
                                  "Id or name of the Box where the Secret is")
     getSecretMetadataCmd.Flags().StringP("secretid", "s", "",
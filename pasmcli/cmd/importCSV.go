@@ -34,19 +34,32 @@ var importCSVCmd = &cobra.Command{
 	    flags := cmd.Flags()
 	    params := map[string]interface{}{}
 
+	    // check the file locally only
+	    if validateOnly, _ := flags.GetBool("validate-only"); validateOnly {
+		runCSVValidation(cmd)
+	    }
+
 	    // csv file
 	    csv_file, _ := flags.GetString("csv_file")
 	    charCheck(len(csv_file))
-	    params["csv_file"] = csv_file
 
 	    // secret type
 	    secret_type, _ := flags.GetString("secret_type")
 	    charCheck(len(secret_type))
 	    params["secret_type"] = secret_type
+
+	    // files written by export-csv are encrypted
+	    plain_file, cleanup, err := plainCSVFile(flags, csv_file)
+	    if err != nil {
+		fmt.Printf("\n%v\n\n", err)
+		os.Exit(1)
+	    }
+	    params["csv_file"] = plain_file
 	    
 	    // JSONify
 	    jsonParams, err := json.Marshal(params)
 	    if (err != nil) {
+		cleanup()
 		fmt.Println("Error building JSON request: ", err)
 		os.Exit(1)
 	    }
@@ -58,6 +71,7 @@ var importCSVCmd = &cobra.Command{
 				   AuthTokenKV(),
 				   jsonParams,
 				   "application/json")
+	   cleanup()
 	   if err != nil {
 	       fmt.Printf("\nHTTP request failed: %s\n", err)
 	       os.Exit(4)
@@ -72,7 +86,7 @@ var importCSVCmd = &cobra.Command{
 		   os.Exit(5)
 	       }
 
-	       fmt.Println("\n" + retStr + "\n")
+	       PrintResponse(cmd, retStr)
 
 	       // make a decision on what to exit with
 	       retMap := JsonStrToMap(retStr)
@@ -80,6 +94,9 @@ var importCSVCmd = &cobra.Command{
 		   os.Exit(3)
 	       } else {
 		   fmt.Println("CSV file", csv_file, "accepted. Starting import...\n")
+		   if wait, _ := flags.GetBool("wait"); wait {
+			   followCSVImport(cmd)
+		   }
 		   os.Exit(0)
 	       }
            }
@@ -92,6 +109,17 @@ func init() {
     	"CSV file that the user wants to import")
     importCSVCmd.Flags().StringP("secret_type", "t", "", 
     	"Secret type corresponding to the secrets in the CSV which can be esxi, static, or SSH key endpoint")
+    importCSVCmd.Flags().Bool("validate-only", false,
+    	"Check the CSV file locally, as validate-csv does, without importing it")
+    importCSVCmd.Flags().String("sample", "",
+    	"Sample CSV file from download-sample-csv to take the columns from, with --validate-only")
+    importCSVCmd.Flags().Bool("wait", false,
+    	"Follow the import until it is completed, failed or cancelled, as get-csv-import-status --follow does")
+    importCSVCmd.Flags().String("report", "",
+    	"CSV file to write the failed rows to, with --wait")
+    importCSVCmd.Flags().Duration("timeout", DefaultCSVImportFollowTimeout,
+    	"How long to follow the import for, with --wait")
+    addCSVDecryptionFlags(importCSVCmd)
 
     // mark mandatory fields as required
     importCSVCmd.MarkFlagRequired("csv_file")
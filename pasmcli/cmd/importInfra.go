@@ -0,0 +1,521 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v3"
+)
+
+// statuses of the mapping report of existing Secrets, update when it's a
+// dry run
+const (
+	importStatusUpdate    = "update"
+	importStatusUpdated   = "updated"
+	importStatusUnchanged = "unchanged"
+)
+
+// formats read by import-infra
+const (
+	infraFormatVaultKV = "vault-kv"
+	infraFormatK8s     = "k8s"
+	infraFormatDotenv  = "dotenv"
+	infraFormatYAML    = "yaml"
+
+	// infraSourceTag records where an imported Secret comes from
+	infraSourceTag = "source"
+)
+
+var infraFormats = []string{infraFormatVaultKV, infraFormatK8s, infraFormatDotenv, infraFormatYAML}
+
+// infraSecret is a Secret read from an infrastructure file
+type infraSecret struct {
+	Source   string // file, and the path or key in it
+	Name     string
+	Type     string      // password, kv or file
+	Data     interface{} // string, map, or []byte for files
+	Filename string
+	Tags     map[string]interface{}
+}
+
+// infraFile reads the Secrets of a file. name, when given, names the
+// Secret of formats holding a single one.
+type infraFile struct {
+	Path    string
+	Content []byte
+	Name    string
+	Split   bool // one password Secret per dotenv key
+}
+
+// defaultName names the Secret of a file after it, without its extension
+func (f infraFile) defaultName() string {
+	if f.Name != "" {
+		return f.Name
+	}
+	base := strings.TrimPrefix(filepath.Base(f.Path), ".")
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// source returns the provenance of a Secret read from the file
+func (f infraFile) source(format string, within string) string {
+	source := format + ":" + filepath.Base(f.Path)
+	if within != "" {
+		source += "#" + within
+	}
+	return source
+}
+
+// isVaultKVResponse tells if value is the output of vault kv get -format=json
+func isVaultKVResponse(value map[string]interface{}) (map[string]interface{}, bool) {
+	outer, _ := value["data"].(map[string]interface{})
+	inner, isMap := outer["data"].(map[string]interface{})
+	_, hasMetadata := outer["metadata"]
+	return inner, isMap && hasMetadata
+}
+
+// parseVaultKV reads a HashiCorp Vault KV v2 dump: the output of vault kv
+// get -format=json for a single Secret, or an object of Secrets by path,
+// nested or not, each given as its key-value data or as kv get output
+func parseVaultKV(file infraFile) ([]infraSecret, error) {
+	var dump interface{}
+	if err := json.Unmarshal(file.Content, &dump); err != nil {
+		return nil, err
+	}
+	root, isMap := dump.(map[string]interface{})
+	if !isMap {
+		return nil, fmt.Errorf("expected a JSON object")
+	}
+	if data, isResponse := isVaultKVResponse(root); isResponse {
+		return []infraSecret{{Source: file.source(infraFormatVaultKV, ""),
+			Name: file.defaultName(), Type: "kv", Data: data}}, nil
+	}
+
+	secrets := []infraSecret{}
+	var walk func(path []string, value map[string]interface{})
+	walk = func(path []string, value map[string]interface{}) {
+		if data, isResponse := isVaultKVResponse(value); isResponse && len(path) != 0 {
+			secrets = append(secrets, infraSecret{Source: file.source(infraFormatVaultKV, strings.Join(path, "/")),
+				Name: strings.Join(path, "/"), Type: "kv", Data: data})
+			return
+		}
+		data := map[string]interface{}{}
+		keys := []string{}
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if nested, isMap := value[key].(map[string]interface{}); isMap {
+				walk(append(append([]string{}, path...), key), nested)
+			} else {
+				data[key] = value[key]
+			}
+		}
+		if len(data) != 0 && len(path) != 0 {
+			secrets = append(secrets, infraSecret{Source: file.source(infraFormatVaultKV, strings.Join(path, "/")),
+				Name: strings.Join(path, "/"), Type: "kv", Data: data})
+		}
+	}
+	walk([]string{}, root)
+	return secrets, nil
+}
+
+// k8sSecretInput is a Kubernetes Secret manifest as written by hand, or
+// a List of them
+type k8sSecretInput struct {
+	k8sSecret  `yaml:",inline"`
+	StringData map[string]string `yaml:"stringData"`
+	Items      []k8sSecretInput  `yaml:"items"`
+}
+
+// parseK8sSecrets reads Kubernetes Secret manifests, one or more YAML
+// documents or a List. Each Secret becomes a key-value Secret, but binary
+// values which become file Secrets named NAME-KEY.
+func parseK8sSecrets(file infraFile) ([]infraSecret, error) {
+	secrets := []infraSecret{}
+	var add func(manifest k8sSecretInput) error
+	add = func(manifest k8sSecretInput) error {
+		switch manifest.Kind {
+		case "List", "SecretList":
+			for _, item := range manifest.Items {
+				if err := add(item); err != nil {
+					return err
+				}
+			}
+			return nil
+		case "Secret":
+		default:
+			return nil
+		}
+		if manifest.Metadata.Name == "" {
+			return fmt.Errorf("a Secret has no name")
+		}
+		name := manifest.Metadata.Name
+		tags := map[string]interface{}{}
+		if manifest.Metadata.Namespace != "" {
+			tags["namespace"] = manifest.Metadata.Namespace
+		}
+		if manifest.Type != "" {
+			tags["k8s_type"] = manifest.Type
+		}
+		data := map[string]interface{}{}
+		keys := []string{}
+		for key := range manifest.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value, err := base64.StdEncoding.DecodeString(manifest.Data[key])
+			if err != nil {
+				return fmt.Errorf("key %s of Secret %s is not base64 - %v", key, name, err)
+			}
+			if utf8.Valid(value) && bytes.IndexByte(value, 0) < 0 {
+				data[key] = string(value)
+				continue
+			}
+			secrets = append(secrets, infraSecret{Source: file.source(infraFormatK8s, name+"/"+key),
+				Name: name + importNameSeparator + key, Type: "file", Data: value, Filename: key, Tags: tags})
+		}
+		for key, value := range manifest.StringData {
+			data[key] = value
+		}
+		if len(data) != 0 {
+			secrets = append(secrets, infraSecret{Source: file.source(infraFormatK8s, name),
+				Name: name, Type: "kv", Data: data, Tags: tags})
+		}
+		return nil
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(file.Content))
+	for {
+		manifest := k8sSecretInput{}
+		err := decoder.Decode(&manifest)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := add(manifest); err != nil {
+			return nil, err
+		}
+	}
+	return secrets, nil
+}
+
+// parseDotenv reads a .env file, as a single key-value Secret or, split,
+// as a password Secret per variable. Values may be quoted, and lines may
+// start with export.
+func parseDotenv(file infraFile) ([]infraSecret, error) {
+	data := map[string]interface{}{}
+	keys := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(file.Content))
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		separator := strings.Index(line, "=")
+		if separator <= 0 {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", number)
+		}
+		key := strings.TrimSpace(line[:separator])
+		value := strings.TrimSpace(line[separator+1:])
+		switch {
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", number, err)
+			}
+			value = unquoted
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		default:
+			if comment := strings.Index(value, " #"); comment >= 0 {
+				value = strings.TrimSpace(value[:comment])
+			}
+		}
+		if _, present := data[key]; !present {
+			keys = append(keys, key)
+		}
+		data[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if !file.Split {
+		if len(data) == 0 {
+			return []infraSecret{}, nil
+		}
+		return []infraSecret{{Source: file.source(infraFormatDotenv, ""),
+			Name: file.defaultName(), Type: "kv", Data: data}}, nil
+	}
+	secrets := []infraSecret{}
+	for _, key := range keys {
+		secrets = append(secrets, infraSecret{Source: file.source(infraFormatDotenv, key),
+			Name: key, Type: "password", Data: data[key]})
+	}
+	return secrets, nil
+}
+
+// parseInfraYAML reads a YAML file of variables, e.g. decrypted with
+// ansible-vault. Variables holding a value become password Secrets, those
+// holding a mapping or a list key-value Secrets.
+func parseInfraYAML(file infraFile) ([]infraSecret, error) {
+	variables := map[string]interface{}{}
+	if err := yaml.Unmarshal(file.Content, &variables); err != nil {
+		return nil, err
+	}
+	keys := []string{}
+	for key := range variables {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	secrets := []infraSecret{}
+	for _, key := range keys {
+		secret := infraSecret{Source: file.source(infraFormatYAML, key), Name: key}
+		switch value := normalizeManifestValue(variables[key]).(type) {
+		case map[string]interface{}:
+			secret.Type, secret.Data = "kv", value
+		case []interface{}:
+			secret.Type, secret.Data = "kv", map[string]interface{}{key: value}
+		case nil:
+			continue
+		default:
+			secret.Type, secret.Data = "password", fmt.Sprint(value)
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets, nil
+}
+
+// upsert creates a Secret, or puts its data as a new version when it
+// exists with different data
+func (p *importPlan) upsert(secret infraSecret, box string) {
+	mapping := importMapping{Source: secret.Source, Box: box,
+		Secret: p.uniqueName(box, importName(secret.Name, importUntitledSecret)), Type: secret.Type}
+	if !p.ensureBox(box, secret.Source) {
+		mapping.Status, mapping.Detail = importStatusFailed, "Box not available"
+		p.mappings = append(p.mappings, mapping)
+		return
+	}
+
+	data := secretVersion{Data: normalizeManifestValue(secret.Data)}
+	if content, isFile := secret.Data.([]byte); isFile {
+		sum := sha256.Sum256(content)
+		data = secretVersion{Size: int64(len(content)), Sum: hex.EncodeToString(sum[:])}
+		if !p.dryRun {
+			var err error
+			if data, err = attachmentVersion(content); err != nil {
+				mapping.Status, mapping.Detail = importStatusFailed, err.Error()
+				p.mappings = append(p.mappings, mapping)
+				p.failed = true
+				return
+			}
+			defer data.Spool.Close()
+		}
+	}
+
+	if !p.secrets[box][mapping.Secret] {
+		params := map[string]interface{}{}
+		params["secret_subtype_info"] = map[string]interface{}{"type": secret.Type}
+		if secret.Type == "file" {
+			filename, err := fileSecretName(secret.Filename)
+			if err != nil {
+				filename = "file"
+			}
+			params["secret_subtype_info"] = map[string]interface{}{
+				"type": "file",
+				"info": map[string]interface{}{"filename": filename},
+			}
+		}
+		tags := map[string]interface{}{infraSourceTag: secret.Source}
+		for key, value := range secret.Tags {
+			tags[key] = value
+		}
+		p.importSecret(mapping, params, tags, data)
+		return
+	}
+
+	params := map[string]interface{}{}
+	params["box_id"] = box
+	params["secret_id"] = mapping.Secret
+	metadata, _, err := postVaultAPI("GetSecretMetadata", params)
+	if err == nil {
+		boxID, hasBoxID := metadata["box_id"].(string)
+		secretID, hasSecretID := metadata["secret_id"].(string)
+		if !hasBoxID || !hasSecretID {
+			err = fmt.Errorf("no box_id or secret_id in the GetSecretMetadata response")
+		}
+		var existing secretVersion
+		if err == nil {
+			existing, err = fetchSecretVersion(boxID, secretID, newSyncStamp(metadata).Version)
+		}
+		if err == nil {
+			existing.Spool.Close()
+			if existing.isFile() == (secret.Type == "file") && existing.Sum == data.Sum &&
+				reflect.DeepEqual(normalizeManifestValue(existing.Data), data.Data) {
+				mapping.Status = importStatusUnchanged
+				p.mappings = append(p.mappings, mapping)
+				return
+			}
+		}
+	}
+	switch {
+	case err != nil:
+		mapping.Status, mapping.Detail = importStatusFailed, err.Error()
+		p.failed = true
+	case p.dryRun:
+		mapping.Status = importStatusUpdate
+	default:
+		params["box_id"] = metadata["box_id"]
+		params["secret_id"] = metadata["secret_id"]
+		if _, err := storeSecretVersion(false, params, nil, data); err != nil {
+			mapping.Status, mapping.Detail = importStatusFailed, err.Error()
+			p.failed = true
+		} else {
+			mapping.Status = importStatusUpdated
+		}
+	}
+	p.mappings = append(p.mappings, mapping)
+}
+
+// importInfraCmd represents the import-infra command
+var importInfraCmd = &cobra.Command{
+	Use:   "import-infra FILE...",
+	Short: "Import Secrets from Vault KV dumps, Kubernetes Secrets, .env or YAML files",
+	Long: `Create Secrets in the --box Box from infrastructure files of the --format:
+
+  vault-kv   HashiCorp Vault KV v2 JSON: the output of vault kv get -format=json,
+             or an object of Secrets by path, e.g. {"app/db": {"user": "x"}}.
+             A key-value Secret is created per path, named with / replaced by -.
+  k8s        Kubernetes Secret YAML, one or more documents or a List. A
+             key-value Secret is created per Secret with its decoded data,
+             binary values become file Secrets named NAME-KEY.
+  dotenv     .env file of KEY=VALUE lines, imported as a key-value Secret
+             named after the file, or with --split as a password Secret per
+             variable.
+  yaml       YAML variables, e.g. decrypted with ansible-vault. Variables
+             with a value become password Secrets, the others key-value
+             Secrets.
+
+Each Secret is tagged source=FORMAT:FILE#PATH. When a Secret already exists,
+its data is put as a new version if it changed, so import-infra can be run
+again after the files change. The Box is created if missing.
+
+The mapping report lists what was, or with --dry-run would be, created or
+updated.
+
+Examples:
+  pasmcli import-infra --format k8s --box payments secrets.yaml
+  pasmcli import-infra --format dotenv --box app --name app-env .env
+  pasmcli import-infra --format vault-kv --box migrated dump.json --dry-run`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+		format, _ := flags.GetString("format")
+		box, _ := flags.GetString("box")
+		name, _ := flags.GetString("name")
+		split, _ := flags.GetBool("split")
+		dryRun, _ := flags.GetBool("dry-run")
+
+		parsers := map[string]func(infraFile) ([]infraSecret, error){
+			infraFormatVaultKV: parseVaultKV,
+			infraFormatK8s:     parseK8sSecrets,
+			infraFormatDotenv:  parseDotenv,
+			infraFormatYAML:    parseInfraYAML,
+		}
+		parse, supported := parsers[format]
+		if !supported {
+			fmt.Printf("\nInvalid --format %q. Supported: %s\n\n", format, strings.Join(infraFormats, ", "))
+			os.Exit(1)
+		}
+		if name != "" && len(args) > 1 {
+			fmt.Printf("\n--name takes a single file\n\n")
+			os.Exit(1)
+		}
+		if split && format != infraFormatDotenv {
+			fmt.Printf("\n--split only applies to --format dotenv\n\n")
+			os.Exit(1)
+		}
+
+		secrets := []infraSecret{}
+		for _, path := range args {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				fmt.Printf("\nUnable to read %s - %v\n\n", path, err)
+				os.Exit(4)
+			}
+			read, err := parse(infraFile{Path: path, Content: content, Name: name, Split: split})
+			if err != nil {
+				fmt.Printf("\nInvalid %s file %s - %v\n\n", format, path, err)
+				os.Exit(1)
+			}
+			secrets = append(secrets, read...)
+		}
+
+		plan, err := newImportPlan(format, box, "", dryRun)
+		if err != nil {
+			fmt.Printf("\n%v\n\n", err)
+			os.Exit(3)
+		}
+		for _, secret := range secrets {
+			plan.upsert(secret, plan.box)
+		}
+
+		plan.printReport(cmd)
+		if plan.failed {
+			os.Exit(3)
+		}
+		os.Exit(0)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importInfraCmd)
+	importInfraCmd.Flags().String("format", "",
+		"Format of the files. Supported: "+strings.Join(infraFormats, ", "))
+	importInfraCmd.MarkFlagRequired("format")
+	importInfraCmd.Flags().StringP("box", "b", "",
+		"Box to import the Secrets into, created if missing")
+	importInfraCmd.MarkFlagRequired("box")
+	importInfraCmd.Flags().StringP("name", "n", "",
+		"Name of the Secret of a dotenv file or of a single vault kv get output. "+
+			"Default is the file name without its extension")
+	importInfraCmd.Flags().Bool("split", false,
+		"Import each variable of a dotenv file as a password Secret")
+	importInfraCmd.Flags().Bool("dry-run", false,
+		"Print the mapping report without creating or updating anything")
+}
@@ -0,0 +1,260 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+const vaultKVResponseSample = `{
+  "request_id": "0b6f2a8c",
+  "lease_duration": 0,
+  "data": {
+    "data": {"username": "admin", "password": "s3cret"},
+    "metadata": {"created_time": "2024-05-01T10:00:00Z", "version": 3}
+  }
+}`
+
+const vaultKVDumpSample = `{
+  "app": {
+    "api": {"data": {"data": {"key": "k1"}, "metadata": {"version": 1}}},
+    "db": {"user": "app", "port": 5432}
+  },
+  "top": "not in a Secret"
+}`
+
+const k8sSample = `apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+  namespace: prod
+type: Opaque
+data:
+  password: czNjcmV0
+  keystore: AAEC
+stringData:
+  user: admin
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  mode: fast
+---
+apiVersion: v1
+kind: List
+items:
+  - apiVersion: v1
+    kind: Secret
+    metadata:
+      name: tls
+    type: kubernetes.io/tls
+    data:
+      tls.key: a2V5
+`
+
+const dotenvSample = `# database
+export DB_USER=admin
+DB_PASS="p@ss\nword"
+TOKEN='raw $value'
+HOST=db.local # primary
+DB_USER=root
+`
+
+func TestParseInfraFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		parse   func(infraFile) ([]infraSecret, error)
+		file    infraFile
+		want    []infraSecret
+		wantErr string
+	}{
+		{
+			name:  "vault kv get output",
+			parse: parseVaultKV,
+			file:  infraFile{Path: "dumps/db.json", Content: []byte(vaultKVResponseSample)},
+			want: []infraSecret{
+				{Source: "vault-kv:db.json", Name: "db", Type: "kv",
+					Data: map[string]interface{}{"username": "admin", "password": "s3cret"}},
+			},
+		},
+		{
+			name:  "vault kv dump by path",
+			parse: parseVaultKV,
+			file:  infraFile{Path: "dump.json", Content: []byte(vaultKVDumpSample)},
+			want: []infraSecret{
+				{Source: "vault-kv:dump.json#app/api", Name: "app/api", Type: "kv",
+					Data: map[string]interface{}{"key": "k1"}},
+				{Source: "vault-kv:dump.json#app/db", Name: "app/db", Type: "kv",
+					Data: map[string]interface{}{"user": "app", "port": float64(5432)}},
+			},
+		},
+		{
+			name:    "vault kv not an object",
+			parse:   parseVaultKV,
+			file:    infraFile{Path: "dump.json", Content: []byte(`["a"]`)},
+			wantErr: "expected a JSON object",
+		},
+		{
+			name:  "kubernetes secrets",
+			parse: parseK8sSecrets,
+			file:  infraFile{Path: "secrets.yaml", Content: []byte(k8sSample)},
+			want: []infraSecret{
+				{Source: "k8s:secrets.yaml#db/keystore", Name: "db-keystore", Type: "file",
+					Data: []byte{0, 1, 2}, Filename: "keystore",
+					Tags: map[string]interface{}{"namespace": "prod", "k8s_type": "Opaque"}},
+				{Source: "k8s:secrets.yaml#db", Name: "db", Type: "kv",
+					Data: map[string]interface{}{"password": "s3cret", "user": "admin"},
+					Tags: map[string]interface{}{"namespace": "prod", "k8s_type": "Opaque"}},
+				{Source: "k8s:secrets.yaml#tls", Name: "tls", Type: "kv",
+					Data: map[string]interface{}{"tls.key": "key"},
+					Tags: map[string]interface{}{"k8s_type": "kubernetes.io/tls"}},
+			},
+		},
+		{
+			name:    "kubernetes secret without a name",
+			parse:   parseK8sSecrets,
+			file:    infraFile{Path: "secrets.yaml", Content: []byte("kind: Secret\ndata:\n  a: YQ==\n")},
+			wantErr: "a Secret has no name",
+		},
+		{
+			name:    "kubernetes value not base64",
+			parse:   parseK8sSecrets,
+			file:    infraFile{Path: "secrets.yaml", Content: []byte("kind: Secret\nmetadata:\n  name: db\ndata:\n  a: '*'\n")},
+			wantErr: "key a of Secret db is not base64 - illegal base64 data at input byte 0",
+		},
+		{
+			name:  "dotenv",
+			parse: parseDotenv,
+			file:  infraFile{Path: "config/app.env", Content: []byte(dotenvSample)},
+			want: []infraSecret{
+				{Source: "dotenv:app.env", Name: "app", Type: "kv",
+					Data: map[string]interface{}{"DB_USER": "root", "DB_PASS": "p@ss\nword",
+						"TOKEN": "raw $value", "HOST": "db.local"}},
+			},
+		},
+		{
+			name:  "dotenv split",
+			parse: parseDotenv,
+			file:  infraFile{Path: "config/app.env", Content: []byte(dotenvSample), Split: true},
+			want: []infraSecret{
+				{Source: "dotenv:app.env#DB_USER", Name: "DB_USER", Type: "password", Data: "root"},
+				{Source: "dotenv:app.env#DB_PASS", Name: "DB_PASS", Type: "password", Data: "p@ss\nword"},
+				{Source: "dotenv:app.env#TOKEN", Name: "TOKEN", Type: "password", Data: "raw $value"},
+				{Source: "dotenv:app.env#HOST", Name: "HOST", Type: "password", Data: "db.local"},
+			},
+		},
+		{
+			name:  "dotenv named",
+			parse: parseDotenv,
+			file:  infraFile{Path: ".env", Content: []byte("A=1\n"), Name: "web"},
+			want: []infraSecret{
+				{Source: "dotenv:.env", Name: "web", Type: "kv", Data: map[string]interface{}{"A": "1"}},
+			},
+		},
+		{
+			name:    "dotenv without a value",
+			parse:   parseDotenv,
+			file:    infraFile{Path: ".env", Content: []byte("A=1\nB\n")},
+			wantErr: "line 2: expected KEY=VALUE",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			secrets, err := test.parse(test.file)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Fatalf("error = %v, want %s", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(secrets, test.want) {
+				t.Errorf("secrets =\n%#v\nwant\n%#v", secrets, test.want)
+			}
+		})
+	}
+}
+
+func TestImportPlanUpsert(t *testing.T) {
+	vault := newTestVault(t, map[string]map[string]interface{}{
+		"apps": {"db": map[string]interface{}{"user": "admin", "port": 5432}},
+	})
+	upsert := func(secret infraSecret) string {
+		plan, err := newImportPlan("dotenv", "", "", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		plan.upsert(secret, "apps")
+		if len(plan.mappings) != 1 {
+			t.Fatalf("mappings = %+v, want 1", plan.mappings)
+		}
+		if plan.failed {
+			t.Fatalf("upsert failed: %+v", plan.mappings[0])
+		}
+		return plan.mappings[0].Status
+	}
+
+	tests := []struct {
+		name       string
+		secret     infraSecret
+		want       string
+		wantAction string
+	}{
+		{name: "same data",
+			secret: infraSecret{Source: "db.env", Name: "db", Type: "kv",
+				Data: map[string]interface{}{"user": "admin", "port": 5432}},
+			want: importStatusUnchanged},
+		{name: "different data",
+			secret: infraSecret{Source: "db.env", Name: "db", Type: "kv",
+				Data: map[string]interface{}{"user": "admin", "port": 6432}},
+			want: importStatusUpdated, wantAction: "PutSecretValue"},
+		{name: "new Secret",
+			secret: infraSecret{Source: "cache.env", Name: "cache", Type: "kv",
+				Data: map[string]interface{}{"url": "redis://cache"}},
+			want: importStatusCreated, wantAction: "CreateSecret"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			puts, creates := vault.count("PutSecretValue"), vault.count("CreateSecret")
+			if got := upsert(test.secret); got != test.want {
+				t.Errorf("upsert() = %s, want %s", got, test.want)
+			}
+			sent := map[string]int{
+				"PutSecretValue": vault.count("PutSecretValue") - puts,
+				"CreateSecret":   vault.count("CreateSecret") - creates,
+			}
+			for action, count := range sent {
+				want := 0
+				if action == test.wantAction {
+					want = 1
+				}
+				if count != want {
+					t.Errorf("%s sent %d times, want %d", action, count, want)
+				}
+			}
+			got := normalizeManifestValue(vault.boxes["apps"][test.secret.Name])
+			if want := normalizeManifestValue(test.secret.Data); !reflect.DeepEqual(got, want) {
+				t.Errorf("Secret %s holds %v, want %v", test.secret.Name, got, want)
+			}
+		})
+	}
+}
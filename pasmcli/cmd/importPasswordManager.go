@@ -0,0 +1,789 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// formats read by import
+const (
+	importFormatKeePass   = "keepass-xml"
+	importFormatBitwarden = "bitwarden-json"
+	importFormat1Password = "1password-csv"
+)
+
+// statuses of the mapping report, create when it's a dry run
+const (
+	importStatusCreate  = "create"
+	importStatusCreated = "created"
+	importStatusExists  = "exists"
+	importStatusSkipped = "skipped"
+	importStatusFailed  = "failed"
+)
+
+const (
+	importSourceTag      = "import_source"
+	importNameSeparator  = "-"
+	importDefaultBox     = "imported"
+	importUntitledSecret = "untitled"
+)
+
+var importFormats = []string{importFormatKeePass, importFormatBitwarden, importFormat1Password}
+
+// importEntry is an entry of a password manager, read from its export
+type importEntry struct {
+	Folder      []string // folder path, empty at the top level
+	Title       string
+	Username    string
+	Password    string
+	URL         string
+	OTP         string
+	Notes       string
+	Note        bool // a secure note, the notes are the Secret
+	Fields      map[string]string
+	Tags        []string
+	Attachments []importAttachment
+}
+
+type importAttachment struct {
+	Name    string
+	Content []byte
+}
+
+// importMapping is a line of the mapping report
+type importMapping struct {
+	Source string `json:"source"`
+	Box    string `json:"box"`
+	Secret string `json:"secret,omitempty"`
+	Type   string `json:"type"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// source names an entry, or one of its attachments, in the report
+func (e importEntry) source(attachment string) string {
+	source := strings.Join(append(append([]string{}, e.Folder...), e.Title), "/")
+	if attachment != "" {
+		source += " [" + attachment + "]"
+	}
+	return source
+}
+
+// secretData returns the data of the Secret of an entry and its type, a
+// password Secret when there's nothing but a password. Nil when empty.
+func (e importEntry) secretData() (interface{}, string) {
+	if e.Note {
+		if e.Notes == "" && len(e.Fields) == 0 {
+			return nil, ""
+		}
+		data := map[string]interface{}{}
+		for key, value := range e.Fields {
+			data[key] = value
+		}
+		if e.Notes != "" {
+			data["notes"] = e.Notes
+		}
+		return data, "kv"
+	}
+	if e.Password != "" && e.Username == "" && e.URL == "" && e.OTP == "" && len(e.Fields) == 0 {
+		return e.Password, "password"
+	}
+	data := map[string]interface{}{}
+	for key, value := range e.Fields {
+		data[key] = value
+	}
+	for key, value := range map[string]string{
+		"username": e.Username, "password": e.Password, "url": e.URL, "totp": e.OTP,
+	} {
+		if value != "" {
+			data[key] = value
+		}
+	}
+	if len(data) == 0 {
+		return nil, ""
+	}
+	return data, "kv"
+}
+
+// importName turns a title or folder into a Box or Secret name, without
+// the / of references
+func importName(name string, empty string) string {
+	name = strings.TrimSpace(strings.ReplaceAll(name, "/", importNameSeparator))
+	if name == "" {
+		return empty
+	}
+	return name
+}
+
+// splitImportTags splits the tags of an entry, separated by ; or ,
+func splitImportTags(tags string) []string {
+	split := []string{}
+	for _, tag := range strings.FieldsFunc(tags, func(r rune) bool { return r == ';' || r == ',' }) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			split = append(split, tag)
+		}
+	}
+	return split
+}
+
+// keepassFile is a KeePass 2 XML export
+type keepassFile struct {
+	Meta struct {
+		RecycleBinUUID string          `xml:"RecycleBinUUID"`
+		Binaries       []keepassBinary `xml:"Binaries>Binary"`
+	} `xml:"Meta"`
+	Groups []keepassGroup `xml:"Root>Group"`
+}
+
+type keepassBinary struct {
+	ID         string `xml:"ID,attr"`
+	Compressed string `xml:"Compressed,attr"`
+	Content    string `xml:",chardata"`
+}
+
+type keepassGroup struct {
+	UUID    string         `xml:"UUID"`
+	Name    string         `xml:"Name"`
+	Entries []keepassEntry `xml:"Entry"`
+	Groups  []keepassGroup `xml:"Group"`
+}
+
+type keepassEntry struct {
+	Tags    string `xml:"Tags"`
+	Strings []struct {
+		Key   string `xml:"Key"`
+		Value string `xml:"Value"`
+	} `xml:"String"`
+	Binaries []struct {
+		Key   string `xml:"Key"`
+		Value struct {
+			Ref     string `xml:"Ref,attr"`
+			Content string `xml:",chardata"`
+		} `xml:"Value"`
+	} `xml:"Binary"`
+}
+
+// decode returns the content of a KeePass binary, gzip compressed or not
+func (b keepassBinary) decode() ([]byte, error) {
+	content, err := base64.StdEncoding.DecodeString(strings.TrimSpace(b.Content))
+	if err != nil || !strings.EqualFold(b.Compressed, "True") {
+		return content, err
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
+
+// parseKeePassXML reads the entries of a KeePass XML export. The top group
+// is the database, its subgroups are the folders. The recycle bin and the
+// history of entries are left out.
+func parseKeePassXML(reader io.Reader) ([]importEntry, error) {
+	file := keepassFile{}
+	if err := xml.NewDecoder(reader).Decode(&file); err != nil {
+		return nil, err
+	}
+	binaries := map[string][]byte{}
+	for _, binary := range file.Meta.Binaries {
+		content, err := binary.decode()
+		if err != nil {
+			return nil, fmt.Errorf("attachment %s - %v", binary.ID, err)
+		}
+		binaries[binary.ID] = content
+	}
+
+	entries := []importEntry{}
+	var walk func(group keepassGroup, folder []string) error
+	walk = func(group keepassGroup, folder []string) error {
+		if group.UUID != "" && group.UUID == file.Meta.RecycleBinUUID {
+			return nil
+		}
+		for _, keepass := range group.Entries {
+			entry := importEntry{Folder: folder, Fields: map[string]string{},
+				Tags: splitImportTags(keepass.Tags)}
+			for _, field := range keepass.Strings {
+				switch field.Key {
+				case "Title":
+					entry.Title = field.Value
+				case "UserName":
+					entry.Username = field.Value
+				case "Password":
+					entry.Password = field.Value
+				case "URL":
+					entry.URL = field.Value
+				case "Notes":
+					entry.Notes = field.Value
+				case "otp", "TimeOtp-Secret-Base32":
+					entry.OTP = field.Value
+				default:
+					if field.Value != "" {
+						entry.Fields[field.Key] = field.Value
+					}
+				}
+			}
+			for _, binary := range keepass.Binaries {
+				content, found := binaries[binary.Value.Ref]
+				if binary.Value.Ref == "" {
+					decoded, err := keepassBinary{Content: binary.Value.Content}.decode()
+					if err != nil {
+						return fmt.Errorf("attachment %s of %s - %v", binary.Key, entry.source(""), err)
+					}
+					content, found = decoded, true
+				}
+				if !found {
+					return fmt.Errorf("attachment %s of %s not found", binary.Key, entry.source(""))
+				}
+				entry.Attachments = append(entry.Attachments,
+					importAttachment{Name: binary.Key, Content: content})
+			}
+			entries = append(entries, entry)
+		}
+		for _, subgroup := range group.Groups {
+			if err := walk(subgroup, append(append([]string{}, folder...), subgroup.Name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, group := range file.Groups {
+		if err := walk(group, []string{}); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// bitwardenExport is an unencrypted Bitwarden JSON export, of a personal
+// vault with folders or of an organization with collections
+type bitwardenExport struct {
+	Encrypted bool `json:"encrypted"`
+	Folders   []struct {
+		Id   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"folders"`
+	Collections []struct {
+		Id   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"collections"`
+	Items []struct {
+		Type          int      `json:"type"`
+		Name          string   `json:"name"`
+		Notes         string   `json:"notes"`
+		FolderId      string   `json:"folderId"`
+		CollectionIds []string `json:"collectionIds"`
+		DeletedDate   string   `json:"deletedDate"`
+		Fields        []struct {
+			Name  string      `json:"name"`
+			Value interface{} `json:"value"`
+		} `json:"fields"`
+		Login *struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			Totp     string `json:"totp"`
+			Uris     []struct {
+				Uri string `json:"uri"`
+			} `json:"uris"`
+		} `json:"login"`
+		Card     map[string]interface{} `json:"card"`
+		Identity map[string]interface{} `json:"identity"`
+		SSHKey   map[string]interface{} `json:"sshKey"`
+	} `json:"items"`
+}
+
+// Bitwarden item types
+const (
+	bitwardenLogin      = 1
+	bitwardenSecureNote = 2
+)
+
+// parseBitwardenJSON reads the items of a Bitwarden JSON export. Items are
+// foldered by their first collection, or else by their folder. Bitwarden
+// exports don't include attachments.
+func parseBitwardenJSON(reader io.Reader) ([]importEntry, error) {
+	export := bitwardenExport{}
+	if err := json.NewDecoder(reader).Decode(&export); err != nil {
+		return nil, err
+	}
+	if export.Encrypted {
+		return nil, fmt.Errorf("the export is encrypted. Export to unencrypted JSON instead.")
+	}
+	folders := map[string]string{}
+	for _, folder := range export.Folders {
+		folders[folder.Id] = folder.Name
+	}
+	for _, collection := range export.Collections {
+		folders[collection.Id] = collection.Name
+	}
+
+	entries := []importEntry{}
+	for _, item := range export.Items {
+		if item.DeletedDate != "" {
+			continue
+		}
+		entry := importEntry{Folder: []string{}, Title: item.Name, Notes: item.Notes,
+			Fields: map[string]string{}}
+		folderId := item.FolderId
+		if len(item.CollectionIds) != 0 {
+			folderId = item.CollectionIds[0]
+		}
+		if folder := folders[folderId]; folder != "" {
+			entry.Folder = strings.Split(folder, "/")
+		}
+		for _, field := range item.Fields {
+			if field.Value != nil && field.Name != "" {
+				entry.Fields[field.Name] = fmt.Sprint(field.Value)
+			}
+		}
+		switch {
+		case item.Type == bitwardenLogin && item.Login != nil:
+			entry.Username = item.Login.Username
+			entry.Password = item.Login.Password
+			entry.OTP = item.Login.Totp
+			for index, uri := range item.Login.Uris {
+				if index == 0 {
+					entry.URL = uri.Uri
+				} else if uri.Uri != "" {
+					entry.Fields[fmt.Sprintf("url%d", index+1)] = uri.Uri
+				}
+			}
+		case item.Type == bitwardenSecureNote:
+			entry.Note = true
+		default:
+			// cards, identities and SSH keys keep their fields
+			for _, typed := range []map[string]interface{}{item.Card, item.Identity, item.SSHKey} {
+				for key, value := range typed {
+					if value != nil && fmt.Sprint(value) != "" {
+						entry.Fields[key] = fmt.Sprint(value)
+					}
+				}
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// parseOnePasswordCSV reads a 1Password CSV export. Columns are matched by
+// their header, unknown columns are kept as fields. Archived items are left
+// out.
+func parseOnePasswordCSV(reader io.Reader) ([]importEntry, error) {
+	records := csv.NewReader(reader)
+	records.FieldsPerRecord = -1
+	header, err := records.Read()
+	if err != nil {
+		return nil, err
+	}
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	columns := map[string]int{}
+	for index, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = index
+	}
+	column := func(record []string, names ...string) (string, bool) {
+		for _, name := range names {
+			if index, present := columns[name]; present && index < len(record) {
+				return record[index], true
+			}
+		}
+		return "", false
+	}
+	if _, present := column(header, "title", "name"); !present {
+		return nil, fmt.Errorf("no Title column")
+	}
+	known := map[string]bool{}
+	for _, name := range []string{"title", "name", "url", "urls", "website", "username",
+		"password", "notes", "notesplain", "tags", "otpauth", "one-time password",
+		"vault", "folder", "type", "archived", "favorite"} {
+		known[name] = true
+	}
+
+	entries := []importEntry{}
+	for {
+		record, err := records.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if archived, _ := column(record, "archived"); strings.EqualFold(archived, "true") {
+			continue
+		}
+		entry := importEntry{Folder: []string{}, Fields: map[string]string{}}
+		entry.Title, _ = column(record, "title", "name")
+		entry.URL, _ = column(record, "url", "urls", "website")
+		entry.Username, _ = column(record, "username")
+		entry.Password, _ = column(record, "password")
+		entry.Notes, _ = column(record, "notes", "notesplain")
+		entry.OTP, _ = column(record, "otpauth", "one-time password")
+		tags, _ := column(record, "tags")
+		entry.Tags = splitImportTags(tags)
+		if folder, _ := column(record, "vault", "folder"); folder != "" {
+			entry.Folder = []string{folder}
+		}
+		itemType, _ := column(record, "type")
+		entry.Note = strings.EqualFold(itemType, "secure note") ||
+			(entry.Username == "" && entry.Password == "" && entry.URL == "")
+		for index, name := range header {
+			key := strings.ToLower(strings.TrimSpace(name))
+			if !known[key] && index < len(record) && record[index] != "" {
+				entry.Fields[strings.TrimSpace(name)] = record[index]
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// importPlan maps the entries to Boxes and Secrets, and creates them
+type importPlan struct {
+	format     string
+	box        string // all the entries go there when set
+	defaultBox string
+	dryRun     bool
+	mappings   []importMapping
+	failed     bool
+
+	boxes   map[string]bool            // existing or created
+	secrets map[string]map[string]bool // by Box, existing or created
+	names   map[string]map[string]bool // by Box, given during the import
+}
+
+func newImportPlan(format string, box string, defaultBox string, dryRun bool) (*importPlan, error) {
+	plan := &importPlan{format: format, box: importName(box, ""),
+		defaultBox: importName(defaultBox, importDefaultBox), dryRun: dryRun,
+		mappings: []importMapping{}, boxes: map[string]bool{},
+		secrets: map[string]map[string]bool{}, names: map[string]map[string]bool{}}
+	_, boxNames, err := listByName("ListBoxIds", map[string]interface{}{})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list the Boxes - %v", err)
+	}
+	for _, name := range boxNames {
+		plan.boxes[name] = true
+	}
+	return plan, nil
+}
+
+// printReport prints the mapping report, as a table by default
+func (p *importPlan) printReport(cmd *cobra.Command) {
+	format := GetOutputFormat(cmd)
+	if format == "" {
+		format = OutputFormatTable
+	}
+	report, err := JSONMarshalIndent(map[string]interface{}{"mappings": p.mappings})
+	if err != nil {
+		fmt.Println("Error building JSON output: ", err)
+		os.Exit(4)
+	}
+	if err := PrintFormatted(os.Stdout, format, "mappings", strings.TrimSpace(string(report))); err != nil {
+		fmt.Printf("\nError formatting output - %v\n", err)
+		os.Exit(4)
+	}
+}
+
+// boxFor returns the Box of an entry, from its folders
+func (p *importPlan) boxFor(entry importEntry) string {
+	if p.box != "" {
+		return p.box
+	}
+	names := []string{}
+	for _, folder := range entry.Folder {
+		if folder = importName(folder, ""); folder != "" {
+			names = append(names, folder)
+		}
+	}
+	if len(names) == 0 {
+		return p.defaultBox
+	}
+	return strings.Join(names, importNameSeparator)
+}
+
+// uniqueName returns name, or name-N when an earlier entry of the import
+// already took it in the Box
+func (p *importPlan) uniqueName(box string, name string) string {
+	if p.names[box] == nil {
+		p.names[box] = map[string]bool{}
+	}
+	unique := name
+	for count := 2; p.names[box][unique]; count++ {
+		unique = fmt.Sprintf("%s-%d", name, count)
+	}
+	p.names[box][unique] = true
+	return unique
+}
+
+// ensureBox lists the Secrets of a Box, creating the Box first when it
+// doesn't exist. Returns false when it failed.
+func (p *importPlan) ensureBox(box string, source string) bool {
+	if p.secrets[box] != nil {
+		return true
+	}
+	mapping := importMapping{Source: source, Box: box, Type: "box"}
+	if !p.boxes[box] {
+		mapping.Status = importStatusCreate
+		if !p.dryRun {
+			params := map[string]interface{}{}
+			params["name"] = box
+			params["description"] = "Imported from " + p.format
+			if _, _, err := postVaultAPI("CreateBox", params); err != nil {
+				mapping.Status, mapping.Detail = importStatusFailed, err.Error()
+				p.mappings = append(p.mappings, mapping)
+				p.failed = true
+				return false
+			}
+			mapping.Status = importStatusCreated
+		}
+		p.mappings = append(p.mappings, mapping)
+		p.boxes[box] = true
+		p.secrets[box] = map[string]bool{}
+		return true
+	}
+
+	params := map[string]interface{}{}
+	params["box_id"] = box
+	secrets, _, err := listByName("ListSecretIds", params)
+	if err != nil {
+		mapping.Status, mapping.Detail = importStatusFailed, fmt.Sprintf("Unable to list the Secrets - %v", err)
+		p.mappings = append(p.mappings, mapping)
+		p.failed = true
+		return false
+	}
+	p.secrets[box] = map[string]bool{}
+	for name := range secrets {
+		p.secrets[box][name] = true
+	}
+	return true
+}
+
+// importSecret creates a Secret, unless it exists or it's a dry run
+func (p *importPlan) importSecret(mapping importMapping, params map[string]interface{},
+	tags map[string]interface{}, data secretVersion) {
+
+	switch {
+	case p.secrets[mapping.Box][mapping.Secret]:
+		mapping.Status = importStatusExists
+	case p.dryRun:
+		mapping.Status = importStatusCreate
+	default:
+		params["box_id"] = mapping.Box
+		params["name"] = mapping.Secret
+		if _, err := storeSecretVersion(true, params, tags, data); err != nil {
+			mapping.Status, mapping.Detail = importStatusFailed, err.Error()
+			p.failed = true
+		} else {
+			mapping.Status = importStatusCreated
+			p.secrets[mapping.Box][mapping.Secret] = true
+		}
+	}
+	p.mappings = append(p.mappings, mapping)
+}
+
+// add maps an entry to a Secret, and each of its attachments to a
+// file Secret named after the Secret of the entry and the attachment
+func (p *importPlan) add(entry importEntry) {
+	box := p.boxFor(entry)
+	folder := strings.Join(entry.Folder, "/")
+	if folder == "" {
+		folder = "(no folder)"
+	}
+	if !p.ensureBox(box, folder) {
+		p.mappings = append(p.mappings, importMapping{Source: entry.source(""), Box: box,
+			Status: importStatusFailed, Detail: "Box not available"})
+		return
+	}
+	name := importName(entry.Title, importUntitledSecret)
+	tags := map[string]interface{}{importSourceTag: p.format}
+	for _, tag := range entry.Tags {
+		tags[tag] = "true"
+	}
+
+	data, secretType := entry.secretData()
+	if data == nil {
+		p.mappings = append(p.mappings, importMapping{Source: entry.source(""), Box: box,
+			Status: importStatusSkipped, Detail: "no data"})
+	} else {
+		params := map[string]interface{}{}
+		if entry.Notes != "" && !entry.Note {
+			params["desc"] = entry.Notes
+		}
+		params["secret_subtype_info"] = map[string]interface{}{"type": secretType}
+		mapping := importMapping{Source: entry.source(""), Box: box,
+			Secret: p.uniqueName(box, name), Type: secretType}
+		name = mapping.Secret
+		p.importSecret(mapping, params, tags, secretVersion{Data: data})
+	}
+
+	for _, attachment := range entry.Attachments {
+		mapping := importMapping{Source: entry.source(attachment.Name), Box: box,
+			Secret: p.uniqueName(box, name+importNameSeparator+importName(attachment.Name, "attachment")),
+			Type:   "file"}
+		filename, err := fileSecretName(attachment.Name)
+		if err != nil {
+			filename = "attachment"
+		}
+		params := map[string]interface{}{}
+		params["desc"] = "Attachment of " + name
+		params["secret_subtype_info"] = map[string]interface{}{
+			"type": "file",
+			"info": map[string]interface{}{"filename": filename},
+		}
+		fileTags := map[string]interface{}{}
+		for key, value := range tags {
+			fileTags[key] = value
+		}
+		if p.dryRun || p.secrets[box][mapping.Secret] {
+			p.importSecret(mapping, params, fileTags, secretVersion{})
+			continue
+		}
+		data, err := attachmentVersion(attachment.Content)
+		if err != nil {
+			mapping.Status, mapping.Detail = importStatusFailed, err.Error()
+			p.mappings = append(p.mappings, mapping)
+			p.failed = true
+			continue
+		}
+		p.importSecret(mapping, params, fileTags, data)
+		data.Spool.Close()
+	}
+}
+
+// attachmentVersion spools an attachment to store it as a file Secret
+func attachmentVersion(content []byte) (secretVersion, error) {
+	spool, err := newSecretSpool()
+	if err != nil {
+		return secretVersion{}, err
+	}
+	if _, err := io.WriteString(spool.file, base64.StdEncoding.EncodeToString(content)); err != nil {
+		spool.Close()
+		return secretVersion{}, err
+	}
+	sum := sha256.Sum256(content)
+	return secretVersion{Spool: spool, Size: int64(len(content)), Sum: hex.EncodeToString(sum[:])}, nil
+}
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import FILE",
+	Short: "Import the entries of a password manager export",
+	Long: `Create Boxes and Secrets from the export of a password manager, given with
+--from:
+
+  keepass-xml      KeePass 2 XML export, attachments included
+  bitwarden-json   unencrypted Bitwarden JSON export, of a personal vault or
+                   of an organization
+  1password-csv    1Password CSV export
+
+Folders become Boxes, nested folders joined with -, e.g. Servers-Linux. Items
+of a Bitwarden organization go to the Box of their first collection. Entries
+outside of any folder go to the --default-box Box. With --box, all the
+entries go to the same Box. Missing Boxes are created.
+
+Entries with only a password become password Secrets. The others become
+key-value Secrets, with the username, password, url, totp and custom fields
+as keys. Notes become the description of the Secret, except for secure
+notes which are stored under the notes key. Attachments become file
+Secrets named ENTRY-ATTACHMENT. Secrets are tagged with import_source, and
+with the tags of the entry.
+
+Entries with the same name in a Box are imported as NAME-2, NAME-3 and so on.
+Secrets which already exist are left as they are, so an import can be run
+again after a failure.
+
+The mapping report lists, for every entry, the Box and Secret it maps to and
+whether it was created. With --dry-run, nothing is created.
+
+Examples:
+  pasmcli import --from keepass-xml passwords.xml --dry-run
+  pasmcli import --from bitwarden-json bitwarden_export.json
+  pasmcli import --from 1password-csv export.csv --box team-secrets -o json`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+		format, _ := flags.GetString("from")
+		box, _ := flags.GetString("box")
+		defaultBox, _ := flags.GetString("default-box")
+		dryRun, _ := flags.GetBool("dry-run")
+
+		parsers := map[string]func(io.Reader) ([]importEntry, error){
+			importFormatKeePass:   parseKeePassXML,
+			importFormatBitwarden: parseBitwardenJSON,
+			importFormat1Password: parseOnePasswordCSV,
+		}
+		parse, supported := parsers[format]
+		if !supported {
+			fmt.Printf("\nInvalid --from %q. Supported: %s\n\n", format, strings.Join(importFormats, ", "))
+			os.Exit(1)
+		}
+		file, err := os.Open(args[0])
+		if err != nil {
+			fmt.Printf("\nUnable to open %s - %v\n\n", args[0], err)
+			os.Exit(4)
+		}
+		entries, err := parse(file)
+		file.Close()
+		if err != nil {
+			fmt.Printf("\nInvalid %s file %s - %v\n\n", format, args[0], err)
+			os.Exit(1)
+		}
+
+		plan, err := newImportPlan(format, box, defaultBox, dryRun)
+		if err != nil {
+			fmt.Printf("\n%v\n\n", err)
+			os.Exit(3)
+		}
+		sort.SliceStable(entries, func(i, j int) bool {
+			return plan.boxFor(entries[i]) < plan.boxFor(entries[j])
+		})
+		for _, entry := range entries {
+			plan.add(entry)
+		}
+
+		plan.printReport(cmd)
+		if plan.failed {
+			os.Exit(3)
+		}
+		os.Exit(0)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.Flags().String("from", "",
+		"Format of the export. Supported: "+strings.Join(importFormats, ", "))
+	importCmd.MarkFlagRequired("from")
+	importCmd.Flags().StringP("box", "b", "",
+		"Import all the entries into this Box instead of a Box per folder")
+	importCmd.Flags().String("default-box", importDefaultBox,
+		"Box of the entries outside of any folder")
+	importCmd.Flags().Bool("dry-run", false,
+		"Print the mapping report without creating anything")
+}
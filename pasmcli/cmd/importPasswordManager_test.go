@@ -0,0 +1,343 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const keepassSample = `<?xml version="1.0" encoding="utf-8" standalone="yes"?>
+<KeePassFile>
+	<Meta>
+		<RecycleBinUUID>cmVjeWNsZQ==</RecycleBinUUID>
+		<Binaries>
+			<Binary ID="0" Compressed="False">aGVsbG8=</Binary>
+		</Binaries>
+	</Meta>
+	<Root>
+		<Group>
+			<UUID>cm9vdA==</UUID>
+			<Name>Database</Name>
+			<Entry>
+				<Tags>web; prod</Tags>
+				<String><Key>Title</Key><Value>Mail</Value></String>
+				<String><Key>UserName</Key><Value>alice</Value></String>
+				<String><Key>Password</Key><Value>s3cret</Value></String>
+				<String><Key>URL</Key><Value>https://mail.example.com</Value></String>
+				<String><Key>otp</Key><Value>otpauth://totp/mail</Value></String>
+				<String><Key>PIN</Key><Value>1234</Value></String>
+				<String><Key>Empty</Key><Value></Value></String>
+				<History>
+					<Entry>
+						<String><Key>Title</Key><Value>Old mail</Value></String>
+					</Entry>
+				</History>
+			</Entry>
+			<Group>
+				<UUID>c2VydmVycw==</UUID>
+				<Name>Servers</Name>
+				<Entry>
+					<String><Key>Title</Key><Value>db</Value></String>
+					<String><Key>Password</Key><Value>pw</Value></String>
+					<String><Key>Notes</Key><Value>primary</Value></String>
+					<Binary><Key>id_rsa</Key><Value Ref="0" /></Binary>
+					<Binary><Key>note.txt</Key><Value>aGk=</Value></Binary>
+				</Entry>
+			</Group>
+			<Group>
+				<UUID>cmVjeWNsZQ==</UUID>
+				<Name>Recycle Bin</Name>
+				<Entry>
+					<String><Key>Title</Key><Value>deleted</Value></String>
+				</Entry>
+			</Group>
+		</Group>
+	</Root>
+</KeePassFile>
+`
+
+const bitwardenSample = `{
+  "encrypted": false,
+  "folders": [{"id": "f1", "name": "Work/Mail"}],
+  "collections": [{"id": "c1", "name": "Shared"}],
+  "items": [
+    {
+      "type": 1, "name": "Mail", "notes": "main account", "folderId": "f1",
+      "fields": [{"name": "PIN", "value": "1234"}, {"name": "", "value": "x"}, {"name": "unset", "value": null}],
+      "login": {
+        "username": "alice", "password": "s3cret", "totp": "otpauth://totp/mail",
+        "uris": [{"uri": "https://mail.example.com"}, {"uri": "https://webmail.example.com"}]
+      }
+    },
+    {"type": 2, "name": "Recovery codes", "notes": "1111 2222", "folderId": "f1", "collectionIds": ["c1"]},
+    {"type": 1, "name": "Old", "deletedDate": "2024-01-01T00:00:00Z", "login": {"password": "x"}},
+    {"type": 3, "name": "Visa", "card": {"number": "4111111111111111", "code": "123", "brand": null}}
+  ]
+}`
+
+const onePasswordSample = "\ufeffTitle,Url,Username,Password,OTPAuth,Notes,Type,Archived,Tags,Vault,Custom\n" +
+	"Mail,https://mail.example.com,alice,s3cret,otpauth://totp/mail,main account,Login,false,web;prod,Work,extra\n" +
+	"Recovery codes,,,,,1111 2222,Secure Note,false,,Personal,\n" +
+	"Old,https://old.example.com,bob,x,,,Login,true,,Work,\n" +
+	"Wifi,,,hunter2,,,Password,false,,,\n"
+
+func TestParsePasswordManagerExports(t *testing.T) {
+	tests := []struct {
+		name    string
+		parse   func(string) ([]importEntry, error)
+		content string
+		want    []importEntry
+		wantErr string
+	}{
+		{
+			name:    "keepass",
+			parse:   func(content string) ([]importEntry, error) { return parseKeePassXML(strings.NewReader(content)) },
+			content: keepassSample,
+			want: []importEntry{
+				{Folder: []string{}, Title: "Mail", Username: "alice", Password: "s3cret",
+					URL: "https://mail.example.com", OTP: "otpauth://totp/mail",
+					Fields: map[string]string{"PIN": "1234"}, Tags: []string{"web", "prod"}},
+				{Folder: []string{"Servers"}, Title: "db", Password: "pw", Notes: "primary",
+					Fields: map[string]string{}, Tags: []string{},
+					Attachments: []importAttachment{
+						{Name: "id_rsa", Content: []byte("hello")},
+						{Name: "note.txt", Content: []byte("hi")},
+					}},
+			},
+		},
+		{
+			name:    "keepass attachment not found",
+			parse:   func(content string) ([]importEntry, error) { return parseKeePassXML(strings.NewReader(content)) },
+			content: strings.Replace(keepassSample, `Ref="0"`, `Ref="1"`, 1),
+			wantErr: "attachment id_rsa of Servers/db not found",
+		},
+		{
+			name:    "bitwarden",
+			parse:   func(content string) ([]importEntry, error) { return parseBitwardenJSON(strings.NewReader(content)) },
+			content: bitwardenSample,
+			want: []importEntry{
+				{Folder: []string{"Work", "Mail"}, Title: "Mail", Username: "alice", Password: "s3cret",
+					URL: "https://mail.example.com", OTP: "otpauth://totp/mail", Notes: "main account",
+					Fields: map[string]string{"PIN": "1234", "url2": "https://webmail.example.com"}},
+				{Folder: []string{"Shared"}, Title: "Recovery codes", Notes: "1111 2222", Note: true,
+					Fields: map[string]string{}},
+				{Folder: []string{}, Title: "Visa",
+					Fields: map[string]string{"number": "4111111111111111", "code": "123"}},
+			},
+		},
+		{
+			name:    "bitwarden encrypted",
+			parse:   func(content string) ([]importEntry, error) { return parseBitwardenJSON(strings.NewReader(content)) },
+			content: `{"encrypted": true, "items": []}`,
+			wantErr: "the export is encrypted. Export to unencrypted JSON instead.",
+		},
+		{
+			name:    "1password",
+			parse:   func(content string) ([]importEntry, error) { return parseOnePasswordCSV(strings.NewReader(content)) },
+			content: onePasswordSample,
+			want: []importEntry{
+				{Folder: []string{"Work"}, Title: "Mail", Username: "alice", Password: "s3cret",
+					URL: "https://mail.example.com", OTP: "otpauth://totp/mail", Notes: "main account",
+					Fields: map[string]string{"Custom": "extra"}, Tags: []string{"web", "prod"}},
+				{Folder: []string{"Personal"}, Title: "Recovery codes", Notes: "1111 2222", Note: true,
+					Fields: map[string]string{}, Tags: []string{}},
+				{Folder: []string{}, Title: "Wifi", Password: "hunter2",
+					Fields: map[string]string{}, Tags: []string{}},
+			},
+		},
+		{
+			name:    "1password without title",
+			parse:   func(content string) ([]importEntry, error) { return parseOnePasswordCSV(strings.NewReader(content)) },
+			content: "Username,Password\nalice,s3cret\n",
+			wantErr: "no Title column",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entries, err := test.parse(test.content)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Fatalf("error = %v, want %s", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(entries, test.want) {
+				t.Errorf("entries =\n%#v\nwant\n%#v", entries, test.want)
+			}
+		})
+	}
+}
+
+// testVault is an in-memory Vault holding the Boxes and key-value Secrets
+// the import commands use, recording the actions it is sent
+type testVault struct {
+	mu      sync.Mutex
+	boxes   map[string]map[string]interface{} // Secret data by name, by Box
+	actions []string
+}
+
+// newTestVault serves a testVault with the given Boxes, and makes it the
+// Vault of the API requests until the test ends
+func newTestVault(t *testing.T, boxes map[string]map[string]interface{}) *testVault {
+	vault := &testVault{boxes: boxes}
+	server := httptest.NewTLSServer(http.HandlerFunc(vault.serve))
+	previous := useTokenInfo(tokenInfo{AccessToken: "test",
+		Server: strings.TrimPrefix(server.URL, "https://")})
+	banner := InsecureBannerOutput
+	InsecureBannerOutput = io.Discard
+	t.Cleanup(func() {
+		server.Close()
+		useTokenInfo(previous)
+		InsecureBannerOutput = banner
+	})
+	return vault
+}
+
+// count returns how many times the Vault was sent action
+func (v *testVault) count(action string) int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	count := 0
+	for _, sent := range v.actions {
+		if sent == action {
+			count++
+		}
+	}
+	return count
+}
+
+func (v *testVault) serve(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	action := path.Base(r.URL.Path)
+	v.actions = append(v.actions, action)
+	params := map[string]interface{}{}
+	json.NewDecoder(r.Body).Decode(&params)
+	box, _ := params["box_id"].(string)
+	secret, _ := params["secret_id"].(string)
+
+	var response interface{}
+	switch action {
+	case "ListBoxIds":
+		items := []interface{}{}
+		for name := range v.boxes {
+			items = append(items, map[string]interface{}{"box_id": name, "name": name})
+		}
+		response = map[string]interface{}{"box_ids": items}
+	case "ListSecretIds":
+		items := []interface{}{}
+		for name := range v.boxes[box] {
+			items = append(items, map[string]interface{}{"secret_id": name, "name": name})
+		}
+		response = map[string]interface{}{"secret_ids": items}
+	case "CreateBox":
+		name, _ := params["name"].(string)
+		v.boxes[name] = map[string]interface{}{}
+		response = map[string]interface{}{"box_id": name}
+	case "CreateSecret":
+		name, _ := params["name"].(string)
+		v.boxes[box][name] = params["secret_data"]
+		response = map[string]interface{}{"box_id": box, "secret_id": name}
+	case "PutSecretValue":
+		v.boxes[box][secret] = params["secret_data"]
+		response = map[string]interface{}{"version": 2}
+	case "GetSecretMetadata":
+		response = map[string]interface{}{"box_id": box, "secret_id": secret,
+			"name": secret, "current_version": 1}
+	case "GetSecret":
+		response = map[string]interface{}{"secret_data": v.boxes[box][secret]}
+	default:
+		response = map[string]interface{}{"error": "unsupported action " + action}
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func TestImportPlanSuffixesDuplicateNames(t *testing.T) {
+	plan := &importPlan{format: "keepass-xml", defaultBox: importDefaultBox, dryRun: true,
+		boxes: map[string]bool{}, secrets: map[string]map[string]bool{},
+		names: map[string]map[string]bool{}}
+	for _, entry := range []importEntry{
+		{Folder: []string{"Servers"}, Title: "db", Password: "pw1"},
+		{Folder: []string{"Servers"}, Title: "db", Password: "pw2",
+			Attachments: []importAttachment{{Name: "id_rsa", Content: []byte("key")}}},
+		{Folder: []string{"Servers"}, Title: "db", Password: "pw3"},
+		{Folder: []string{"Web"}, Title: "db", Password: "pw4"},
+	} {
+		plan.add(entry)
+	}
+
+	got := []string{}
+	for _, mapping := range plan.mappings {
+		got = append(got, mapping.Box+"/"+mapping.Secret)
+	}
+	want := []string{"Servers/", "Servers/db", "Servers/db-2", "Servers/db-2" + importNameSeparator + "id_rsa",
+		"Servers/db-3", "Web/", "Web/db"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mappings = %v, want %v", got, want)
+	}
+}
+
+func TestImportPlanSkipsExistingSecrets(t *testing.T) {
+	vault := newTestVault(t, map[string]map[string]interface{}{
+		"Servers": {"db": map[string]interface{}{"password": "old"}},
+	})
+	entries := []importEntry{
+		{Folder: []string{"Servers"}, Title: "db", Username: "admin", Password: "pw"},
+		{Folder: []string{"Servers"}, Title: "web", Username: "www", Password: "pw"},
+	}
+	run := func() []string {
+		plan, err := newImportPlan("bitwarden-json", "", "", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range entries {
+			plan.add(entry)
+		}
+		if plan.failed {
+			t.Fatalf("import failed: %+v", plan.mappings)
+		}
+		statuses := []string{}
+		for _, mapping := range plan.mappings {
+			statuses = append(statuses, mapping.Secret+" "+mapping.Status)
+		}
+		return statuses
+	}
+
+	if got, want := run(), []string{"db " + importStatusExists, "web " + importStatusCreated}; !reflect.DeepEqual(got, want) {
+		t.Errorf("first run = %v, want %v", got, want)
+	}
+	if got, want := run(), []string{"db " + importStatusExists, "web " + importStatusExists}; !reflect.DeepEqual(got, want) {
+		t.Errorf("re-run = %v, want %v", got, want)
+	}
+	if count := vault.count("CreateSecret"); count != 1 {
+		t.Errorf("CreateSecret sent %d times, want 1", count)
+	}
+	if password := vault.boxes["Servers"]["db"].(map[string]interface{})["password"]; password != "old" {
+		t.Errorf("existing Secret db overwritten, password = %v", password)
+	}
+}
@@ -64,6 +64,16 @@ var listBoxesCmd = &cobra.Command{
             params["next_token"] = nextToken
         }
 
+        // follow next_token
+        paging, err := GetListPaging(cmd)
+        if err != nil {
+            fmt.Println(err)
+            os.Exit(1)
+        }
+        if paging.IsRequested() {
+            RunListAllPages("ListBoxes", params, paging, "Boxes not found")
+        }
+
         // JSONify
         jsonParams, err := json.Marshal(params)
         if (err != nil) {
@@ -92,7 +102,7 @@ var listBoxesCmd = &cobra.Command{
                 os.Exit(5)
             }
 
-            fmt.Println("\n" + retStr + "\n")
+            PrintResponse(cmd, retStr)
 
             // make a decision on what to exit with
             retMap := JsonStrToMap(retStr)
@@ -121,4 +131,5 @@ func init() {
     listBoxesCmd.Flags().StringP("next-token", "n", "",
                                "Token from which subsequent Boxes would " +
                                "be listed")
+    AddListPagingFlags(listBoxesCmd)
 }
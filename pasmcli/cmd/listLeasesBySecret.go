@@ -95,7 +95,7 @@ var listLeasesBySecretCmd = &cobra.Command{
                 os.Exit(5)
             }
 
-            fmt.Println("\n" + retStr + "\n")
+            PrintResponse(cmd, retStr)
 
             // make a decision on what to exit with
             retMap := JsonStrToMap(retStr)
@@ -110,6 +110,7 @@ var listLeasesBySecretCmd = &cobra.Command{
 
 func init() {
     rootCmd.AddCommand(listLeasesBySecretCmd)
+    acceptSecretRef(listLeasesBySecretCmd)
     listLeasesBySecretCmd.Flags().StringP("boxid", "b", "",
                                          "Id of the Box under which " +
                                          "the Secret is")
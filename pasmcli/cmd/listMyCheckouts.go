@@ -59,6 +59,16 @@ var listMyCheckoutsCmd = &cobra.Command{
             params["next_token"] = nextToken
         }
 
+        // follow next_token
+        paging, err := GetListPaging(cmd)
+        if err != nil {
+            fmt.Println(err)
+            os.Exit(1)
+        }
+        if paging.IsRequested() {
+            RunListAllPages("ListMyCheckouts", params, paging, "No checkouts found")
+        }
+
         // JSONify
         jsonParams, err := json.Marshal(params)
         if (err != nil) {
@@ -87,7 +97,7 @@ var listMyCheckoutsCmd = &cobra.Command{
                 os.Exit(5)
             }
 
-            fmt.Println("\n" + retStr + "\n")
+            PrintResponse(cmd, retStr)
 
             // make a decision on what to exit with
             retMap := JsonStrToMap(retStr)
@@ -113,4 +123,5 @@ func init() {
     listMyCheckoutsCmd.Flags().StringP("next-token", "n", "",
                                  "Token from which subsequent items would " +
                                  "be listed")
+    AddListPagingFlags(listMyCheckoutsCmd)
 }
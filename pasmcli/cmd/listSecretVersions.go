@@ -74,7 +74,7 @@ var listSecretVersionsCmd = &cobra.Command{
                 os.Exit(5)
             }
 
-            fmt.Println("\n" + retStr + "\n")
+            PrintResponse(cmd, retStr)
 
             // make a decision on what to exit with
             retMap := JsonStrToMap(retStr)
@@ -89,6 +89,7 @@ var listSecretVersionsCmd = &cobra.Command{
 
 func init() {
     rootCmd.AddCommand(listSecretVersionsCmd)
+    acceptSecretRef(listSecretVersionsCmd)
     listSecretVersionsCmd.Flags().StringP("boxid", "b", "",
                                   "Id or name of the Box under which the " +
                                   "Secret is present")
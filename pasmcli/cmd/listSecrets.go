@@ -68,6 +68,16 @@ var listSecretsCmd = &cobra.Command{
             params["next_token"] = nextToken
         }
 
+        // follow next_token
+        paging, err := GetListPaging(cmd)
+        if err != nil {
+            fmt.Println(err)
+            os.Exit(1)
+        }
+        if paging.IsRequested() {
+            RunListAllPages("ListSecrets", params, paging, "Secrets not found")
+        }
+
         // JSONify
         jsonParams, err := json.Marshal(params)
         if (err != nil) {
@@ -96,7 +106,7 @@ var listSecretsCmd = &cobra.Command{
                 os.Exit(5)
             }
 
-            fmt.Println("\n" + retStr + "\n")
+            PrintResponse(cmd, retStr)
 
             // make a decision on what to exit with
             retMap := JsonStrToMap(retStr)
@@ -128,6 +138,7 @@ func init() {
     listSecretsCmd.Flags().StringP("next-token", "n", "",
                                   "Token from which subsequent Secrets would " +
                                   "be listed")
+    AddListPagingFlags(listSecretsCmd)
 
     // mark mandatory fields as required
     listSecretsCmd.MarkFlagRequired("boxid")
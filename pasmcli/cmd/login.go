@@ -119,7 +119,8 @@ func loginAPI(cmd *cobra.Command, args []string) {
 
 	// save access token to a file
 	tokenFile, _ := flags.GetString(loginOptionTokenFile)
-	tokenFile, err = SaveAccessToken(tokenFile, respData.Token, uri.Hostname(), cacert)
+	tokenFile, err = SaveAccessToken(tokenFile, respData.Token, uri.Hostname(), cacert,
+		respData.Expiration)
 	if err != nil {
 		fmt.Printf("\nError saving access token to %s - %v\n", tokenFile, err)
 		os.Exit(4)
@@ -0,0 +1,318 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// manifest is the desired state of Boxes, Secrets and Policies read by
+// plan and apply
+type manifest struct {
+	Boxes    []manifestBox    `yaml:"boxes"`
+	Policies []manifestPolicy `yaml:"policies"`
+}
+
+// manifestBox takes the create-box settings. Secrets are only pruned from
+// Boxes which list their secrets, even as an empty list.
+type manifestBox struct {
+	Name              string           `yaml:"name"`
+	Description       interface{}      `yaml:"description"`
+	Lease             interface{}      `yaml:"lease"`
+	Rotation          interface{}      `yaml:"rotation"`
+	MaxSecretVersions interface{}      `yaml:"max_secret_versions"`
+	ExclusiveCheckout interface{}      `yaml:"exclusive_checkout"`
+	SecretDuration    interface{}      `yaml:"secret_duration"`
+	Tags              interface{}      `yaml:"tags"`
+	Secrets           []manifestSecret `yaml:"secrets"`
+}
+
+// manifestSecret is a Secret shell. Its value is set when the Secret is
+// created, either generated, given as non-sensitive key-value data, or
+// copied from another Secret, and never compared afterwards.
+type manifestSecret struct {
+	Name              string                 `yaml:"name"`
+	Type              string                 `yaml:"type"`
+	Generate          *manifestGenerate      `yaml:"generate"`
+	Data              map[string]interface{} `yaml:"data"`
+	From              string                 `yaml:"from"`
+	Description       interface{}            `yaml:"description"`
+	Lease             interface{}            `yaml:"lease"`
+	Rotation          interface{}            `yaml:"rotation"`
+	ExclusiveCheckout interface{}            `yaml:"exclusive_checkout"`
+	ExpiresAt         interface{}            `yaml:"expires_at"`
+	Tags              interface{}            `yaml:"tags"`
+}
+
+// manifestGenerate asks for a generated password, or for key-value
+// Secrets, a generated value for each of keys
+type manifestGenerate struct {
+	Length int      `yaml:"length"`
+	Keys   []string `yaml:"keys"`
+}
+
+// manifestPolicy takes principals as the Policy APIs do, e.g.
+// {local_user: {username: alice}}, and resources as references like
+// BOX/SECRET or BOX/*, resolved to ids when applied
+type manifestPolicy struct {
+	Name        string        `yaml:"name"`
+	Description interface{}   `yaml:"description"`
+	Role        string        `yaml:"role"`
+	Principals  []interface{} `yaml:"principals"`
+	Resources   []string      `yaml:"resources"`
+	Tags        interface{}   `yaml:"tags"`
+}
+
+// settings returns the Box settings the manifest sets, keyed as the Box
+// APIs take them
+func (b manifestBox) settings() map[string]interface{} {
+	return manifestSettings(map[string]interface{}{
+		"description":         b.Description,
+		"lease":               b.Lease,
+		"rotation":            b.Rotation,
+		"max_secret_versions": b.MaxSecretVersions,
+		"exclusive_checkout":  b.ExclusiveCheckout,
+		"secret_duration":     b.SecretDuration,
+		"tags":                b.Tags,
+	})
+}
+
+// settings returns the Secret settings the manifest sets, keyed as the
+// Secret APIs take them
+func (s manifestSecret) settings() map[string]interface{} {
+	return manifestSettings(map[string]interface{}{
+		"desc":               s.Description,
+		"lease":              s.Lease,
+		"rotation":           s.Rotation,
+		"exclusive_checkout": s.ExclusiveCheckout,
+		"expires_at":         s.ExpiresAt,
+		"tags":               s.Tags,
+	})
+}
+
+// settings returns the Policy settings the manifest sets, but resources
+// which are resolved separately
+func (p manifestPolicy) settings() map[string]interface{} {
+	settings := manifestSettings(map[string]interface{}{
+		"desc": p.Description,
+		"role": p.Role,
+		"tags": p.Tags,
+	})
+	if p.Principals != nil {
+		settings["principals"] = normalizeManifestValue(p.Principals)
+	}
+	return settings
+}
+
+// manifestSettings drops the settings left out of the manifest, and
+// normalizes the others to compare them to decoded JSON
+func manifestSettings(all map[string]interface{}) map[string]interface{} {
+	settings := map[string]interface{}{}
+	for key, value := range all {
+		if value == nil || value == "" {
+			continue
+		}
+		settings[key] = normalizeManifestValue(value)
+	}
+	return settings
+}
+
+// normalizeManifestValue turns a value decoded from YAML into the value
+// the same JSON would decode to, e.g. int into float64
+func normalizeManifestValue(value interface{}) interface{} {
+	jsonValue, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(jsonValue, &normalized); err != nil {
+		return value
+	}
+	return normalized
+}
+
+// loadManifest reads and checks a manifest file
+func loadManifest(path string) (*manifest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	m := &manifest{}
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(m); err != nil {
+		return nil, fmt.Errorf("Invalid manifest %s - %v", path, err)
+	}
+
+	boxNames := map[string]bool{}
+	for _, box := range m.Boxes {
+		if box.Name == "" {
+			return nil, fmt.Errorf("Invalid manifest %s - a Box has no name", path)
+		}
+		if boxNames[box.Name] {
+			return nil, fmt.Errorf("Invalid manifest %s - Box %s is listed twice", path, box.Name)
+		}
+		boxNames[box.Name] = true
+
+		secretNames := map[string]bool{}
+		for _, secret := range box.Secrets {
+			name := box.Name + "/" + secret.Name
+			switch {
+			case secret.Name == "":
+				return nil, fmt.Errorf("Invalid manifest %s - a Secret of Box %s has no name",
+					path, box.Name)
+			case secretNames[secret.Name]:
+				return nil, fmt.Errorf("Invalid manifest %s - Secret %s is listed twice", path, name)
+			case secret.From != "" && (secret.Generate != nil || secret.Data != nil):
+				return nil, fmt.Errorf("Invalid manifest %s - Secret %s takes either from, "+
+					"or generate and data", path, name)
+			case secret.From == "" && secret.Generate == nil && secret.Data == nil:
+				return nil, fmt.Errorf("Invalid manifest %s - Secret %s needs generate, "+
+					"data or from", path, name)
+			case secret.From == "" && secret.secretType() == "password" && secret.Generate == nil:
+				return nil, fmt.Errorf("Invalid manifest %s - password Secret %s needs generate",
+					path, name)
+			case secret.From == "" && secret.secretType() != "password" && secret.secretType() != "kv":
+				return nil, fmt.Errorf("Invalid manifest %s - Secret %s has type %s. "+
+					"Supported: password, kv", path, name, secret.Type)
+			}
+			secretNames[secret.Name] = true
+		}
+	}
+
+	policyNames := map[string]bool{}
+	for _, policy := range m.Policies {
+		switch {
+		case policy.Name == "":
+			return nil, fmt.Errorf("Invalid manifest %s - a Policy has no name", path)
+		case policyNames[policy.Name]:
+			return nil, fmt.Errorf("Invalid manifest %s - Policy %s is listed twice", path, policy.Name)
+		case policy.Role == "":
+			return nil, fmt.Errorf("Invalid manifest %s - Policy %s has no role", path, policy.Name)
+		}
+		policyNames[policy.Name] = true
+	}
+	return m, nil
+}
+
+// secretType returns the type of Secret created, password when generated
+// without keys, kv otherwise
+func (s manifestSecret) secretType() string {
+	if s.Type != "" {
+		return s.Type
+	}
+	if s.Generate != nil && len(s.Generate.Keys) == 0 && s.Data == nil {
+		return "password"
+	}
+	return "kv"
+}
+
+// settingDiffers tells if a desired setting differs from the live one.
+// Objects are compared on the keys the manifest sets, as the Vault fills
+// in defaults for the others.
+func settingDiffers(desired interface{}, live interface{}) bool {
+	desiredMap, isMap := desired.(map[string]interface{})
+	if !isMap {
+		return !reflect.DeepEqual(desired, live)
+	}
+	liveMap, _ := live.(map[string]interface{})
+	for key, value := range desiredMap {
+		if settingDiffers(value, liveMap[key]) {
+			return true
+		}
+	}
+	return false
+}
+
+// changedSettings returns the sorted names of the desired settings which
+// differ from the live ones. Tags are compared whole, the Vault replaces
+// them all on update.
+func changedSettings(desired map[string]interface{}, live map[string]interface{}) []string {
+	changed := []string{}
+	for key, value := range desired {
+		differs := false
+		switch key {
+		case "tags":
+			liveTags, _ := live[key].(map[string]interface{})
+			desiredTags, _ := value.(map[string]interface{})
+			differs = len(liveTags) != len(desiredTags) || settingDiffers(desiredTags, liveTags)
+		case "principals":
+			differs = principalsDiffer(value, live[key])
+		default:
+			differs = settingDiffers(value, live[key])
+		}
+		if differs {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// principalsDiffer tells if the live Policy principals aren't exactly the
+// desired ones, in any order
+func principalsDiffer(desired interface{}, live interface{}) bool {
+	desiredList, _ := desired.([]interface{})
+	liveList, _ := live.([]interface{})
+	if len(desiredList) != len(liveList) {
+		return true
+	}
+	used := make([]bool, len(liveList))
+	for _, principal := range desiredList {
+		found := false
+		for index, livePrincipal := range liveList {
+			if !used[index] && !settingDiffers(principal, livePrincipal) {
+				used[index], found = true, true
+				break
+			}
+		}
+		if !found {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceSet flattens Policy resources into sorted BOXID/SECRETID strings
+func resourceSet(resources interface{}) []string {
+	set := []string{}
+	list, _ := resources.([]interface{})
+	for _, resource := range list {
+		resourceMap, _ := resource.(map[string]interface{})
+		boxId, _ := resourceMap["box_id"].(string)
+		secretIds, _ := resourceMap["secret_id"].([]interface{})
+		for _, secretId := range secretIds {
+			set = append(set, boxId+"/"+fmt.Sprint(secretId))
+		}
+	}
+	sort.Strings(set)
+	return set
+}
+
+// manifestKey joins the names of a Box and a Secret
+func manifestKey(names ...string) string {
+	return strings.Join(names, "/")
+}
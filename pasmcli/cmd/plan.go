@@ -0,0 +1,580 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	planActionCreate  = "create"
+	planActionUpdate  = "update"
+	planActionDelete  = "delete"
+	planActionReplace = "replace"
+)
+
+// planChange is one change needed to bring the Vault to the manifest
+type planChange struct {
+	Action string   `json:"action"`
+	Kind   string   `json:"kind"`
+	Name   string   `json:"name"`
+	Fields []string `json:"fields,omitempty"`
+	Detail string   `json:"detail,omitempty"`
+
+	apply func() error
+}
+
+// vaultPlan is the changes in the order they are applied: Boxes, Secrets
+// and Policies are created or updated, then Policies, Secrets and Boxes
+// pruned
+type vaultPlan struct {
+	changes []planChange
+	prunes  []planChange
+}
+
+func (p *vaultPlan) all() []planChange {
+	return append(append([]planChange{}, p.changes...), p.prunes...)
+}
+
+// updateRequest builds the update of the changed settings, with the
+// revision the changes were computed against
+func updateRequest(ids map[string]interface{}, revision interface{},
+	desired map[string]interface{}, changed []string) map[string]interface{} {
+
+	params := map[string]interface{}{}
+	for key, value := range ids {
+		params[key] = value
+	}
+	params["revision"] = revision
+	for _, key := range changed {
+		params[key] = desired[key]
+	}
+	return params
+}
+
+// planSecret adds the creation or update of a Secret in a Box which
+// exists, or is created, under boxName
+func (p *vaultPlan) planSecret(boxName string, secret manifestSecret,
+	live map[string]interface{}) {
+
+	name := manifestKey(boxName, secret.Name)
+	desired := secret.settings()
+	if live != nil {
+		changed := changedSettings(desired, live)
+		if len(changed) == 0 {
+			return
+		}
+		params := updateRequest(map[string]interface{}{
+			"box_id":    live["box_id"],
+			"secret_id": live["secret_id"],
+		}, live["revision"], desired, changed)
+		p.changes = append(p.changes, planChange{
+			Action: planActionUpdate, Kind: "secret", Name: name, Fields: changed,
+			apply: func() error {
+				_, _, err := postVaultAPI("UpdateSecret", params)
+				return err
+			},
+		})
+		return
+	}
+
+	change := planChange{Action: planActionCreate, Kind: "secret", Name: name}
+	if secret.From != "" {
+		change.Detail = "copied from " + secret.From
+		change.apply = func() error {
+			ref, err := parseSecretRefArg(secret.From)
+			if err != nil {
+				return err
+			}
+			_, secretId, _, err := copySecret(secretCopy{
+				Source:     currentVaultSession(),
+				Target:     currentVaultSession(),
+				BoxId:      ref.Box,
+				SecretId:   ref.Secret,
+				Version:    ref.Version,
+				TargetBox:  boxName,
+				TargetName: secret.Name,
+			})
+			if err != nil || len(desired) == 0 {
+				return err
+			}
+			params := map[string]interface{}{}
+			params["box_id"] = boxName
+			params["secret_id"] = secretId
+			copied, _, err := postVaultAPI("GetSecretMetadata", params)
+			if err != nil {
+				return err
+			}
+			changed := changedSettings(desired, copied)
+			if len(changed) == 0 {
+				return nil
+			}
+			_, _, err = postVaultAPI("UpdateSecret",
+				updateRequest(params, copied["revision"], desired, changed))
+			return err
+		}
+	} else {
+		secretType := secret.secretType()
+		change.Detail = secretType
+		if secret.Generate != nil {
+			change.Detail = "generated " + secretType
+		}
+		change.apply = func() error {
+			params := map[string]interface{}{}
+			for key, value := range desired {
+				params[key] = value
+			}
+			params["box_id"] = boxName
+			params["name"] = secret.Name
+			params["secret_subtype_info"] = map[string]interface{}{"type": secretType}
+			length := 0
+			if secret.Generate != nil {
+				length = secret.Generate.Length
+			}
+			if secretType == "password" {
+				password, err := generatePassword(length)
+				if err != nil {
+					return err
+				}
+				params["secret_data"] = password
+			} else {
+				data := map[string]interface{}{}
+				for key, value := range secret.Data {
+					data[key] = value
+				}
+				if secret.Generate != nil {
+					for _, key := range secret.Generate.Keys {
+						password, err := generatePassword(length)
+						if err != nil {
+							return err
+						}
+						data[key] = password
+					}
+				}
+				params["secret_data"] = data
+			}
+			_, _, err := postVaultAPI("CreateSecret", params)
+			return err
+		}
+	}
+	p.changes = append(p.changes, change)
+}
+
+// planPolicy adds the creation or update of a Policy. Resources are
+// resolved when applied, as their Boxes and Secrets may not exist yet.
+// The role of a Policy cannot be updated, a Policy whose role changes is
+// replaced: deleted, then created again with its other settings kept.
+func (p *vaultPlan) planPolicy(policy manifestPolicy, live map[string]interface{}) {
+	desired := policy.settings()
+	request := func(params map[string]interface{}) error {
+		if policy.Resources != nil {
+			resources, err := policyResources(policy.Resources)
+			if err != nil {
+				return err
+			}
+			params["resources"] = resources
+		}
+		return nil
+	}
+
+	if live == nil {
+		p.changes = append(p.changes, planChange{
+			Action: planActionCreate, Kind: "policy", Name: policy.Name,
+			apply: func() error {
+				params := map[string]interface{}{}
+				for key, value := range desired {
+					params[key] = value
+				}
+				params["name"] = policy.Name
+				if err := request(params); err != nil {
+					return err
+				}
+				_, _, err := postVaultAPI("CreatePolicy", params)
+				return err
+			},
+		})
+		return
+	}
+
+	changed := changedSettings(desired, live)
+	if policy.Resources != nil {
+		resources, err := policyResources(policy.Resources)
+		if err != nil || !reflect.DeepEqual(resourceSet(normalizeManifestValue(resources)),
+			resourceSet(live["resources"])) {
+			changed = append(changed, "resources")
+		}
+	}
+	if len(changed) == 0 {
+		return
+	}
+	for _, key := range changed {
+		if key != "role" {
+			continue
+		}
+		p.changes = append(p.changes, planChange{
+			Action: planActionReplace, Kind: "policy", Name: policy.Name, Fields: changed,
+			Detail: "the role cannot be updated",
+			apply: func() error {
+				params := map[string]interface{}{}
+				for _, key := range []string{"desc", "principals", "resources", "tags"} {
+					if value, ok := live[key]; ok {
+						params[key] = value
+					}
+				}
+				for key, value := range desired {
+					params[key] = value
+				}
+				params["name"] = policy.Name
+				if err := request(params); err != nil {
+					return err
+				}
+				if _, _, err := postVaultAPI("DeletePolicy",
+					map[string]interface{}{"policy_id": live["policy_id"]}); err != nil {
+					return err
+				}
+				_, _, err := postVaultAPI("CreatePolicy", params)
+				return err
+			},
+		})
+		return
+	}
+	params := updateRequest(map[string]interface{}{"policy_id": live["policy_id"]},
+		live["revision"], desired, changed)
+	p.changes = append(p.changes, planChange{
+		Action: planActionUpdate, Kind: "policy", Name: policy.Name, Fields: changed,
+		apply: func() error {
+			if err := request(params); err != nil {
+				return err
+			}
+			_, _, err := postVaultAPI("UpdatePolicy", params)
+			return err
+		},
+	})
+}
+
+// prune adds the deletion of an item
+func (p *vaultPlan) prune(kind string, name string, action string, params map[string]interface{}) {
+	p.prunes = append(p.prunes, planChange{
+		Action: planActionDelete, Kind: kind, Name: name,
+		apply: func() error {
+			_, _, err := postVaultAPI(action, params)
+			return err
+		},
+	})
+}
+
+// listByName lists items, keyed by name
+func listByName(action string, params map[string]interface{}) (map[string]map[string]interface{}, []string, error) {
+	items := map[string]map[string]interface{}{}
+	names := []string{}
+	_, _, err := ListAllPages(action, params, ListPaging{All: true},
+		func(item interface{}) error {
+			itemMap, _ := item.(map[string]interface{})
+			if name, _ := itemMap["name"].(string); name != "" {
+				items[name] = itemMap
+				names = append(names, name)
+			}
+			return nil
+		})
+	if err == errListNotFound {
+		err = nil
+	}
+	return items, names, err
+}
+
+// computePlan compares the manifest to the live Vault
+func computePlan(m *manifest, prune bool) (*vaultPlan, error) {
+	plan := &vaultPlan{}
+	boxes, boxNames, err := listByName("ListBoxIds", map[string]interface{}{})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list Boxes - %v", err)
+	}
+
+	boxPrunes := &vaultPlan{}
+	secretPrunes := &vaultPlan{}
+	for _, box := range m.Boxes {
+		name := box.Name
+		desired := box.settings()
+		listed, exists := boxes[name]
+		if !exists {
+			plan.changes = append(plan.changes, planChange{
+				Action: planActionCreate, Kind: "box", Name: name,
+				apply: func() error {
+					params := map[string]interface{}{}
+					for key, value := range desired {
+						params[key] = value
+					}
+					params["name"] = name
+					_, _, err := postVaultAPI("CreateBox", params)
+					return err
+				},
+			})
+			for _, secret := range box.Secrets {
+				plan.planSecret(box.Name, secret, nil)
+			}
+			continue
+		}
+
+		params := map[string]interface{}{}
+		params["box_id"] = listed["box_id"]
+		live, _, err := postVaultAPI("GetBox", params)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to get Box %s - %v", box.Name, err)
+		}
+		if changed := changedSettings(desired, live); len(changed) != 0 {
+			update := updateRequest(params, live["revision"], desired, changed)
+			plan.changes = append(plan.changes, planChange{
+				Action: planActionUpdate, Kind: "box", Name: box.Name, Fields: changed,
+				apply: func() error {
+					_, _, err := postVaultAPI("UpdateBox", update)
+					return err
+				},
+			})
+		}
+
+		secrets, secretNames, err := listByName("ListSecretIds", params)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to list the Secrets of Box %s - %v", box.Name, err)
+		}
+		declared := map[string]bool{}
+		for _, secret := range box.Secrets {
+			declared[secret.Name] = true
+			var liveSecret map[string]interface{}
+			if listedSecret, exists := secrets[secret.Name]; exists {
+				params := map[string]interface{}{}
+				params["box_id"] = listed["box_id"]
+				params["secret_id"] = listedSecret["secret_id"]
+				liveSecret, _, err = postVaultAPI("GetSecretMetadata", params)
+				if err != nil {
+					return nil, fmt.Errorf("Unable to get Secret %s - %v",
+						manifestKey(box.Name, secret.Name), err)
+				}
+			}
+			plan.planSecret(box.Name, secret, liveSecret)
+		}
+		if prune && box.Secrets != nil {
+			for _, name := range secretNames {
+				if !declared[name] {
+					secretPrunes.prune("secret", manifestKey(box.Name, name), "DeleteSecret",
+						map[string]interface{}{
+							"box_id":    listed["box_id"],
+							"secret_id": secrets[name]["secret_id"],
+						})
+				}
+			}
+		}
+	}
+
+	declaredBoxes := map[string]bool{}
+	for _, box := range m.Boxes {
+		declaredBoxes[box.Name] = true
+	}
+	if prune {
+		for _, name := range boxNames {
+			if !declaredBoxes[name] {
+				boxPrunes.prune("box", name, "DeleteBox",
+					map[string]interface{}{"box_id": boxes[name]["box_id"]})
+			}
+		}
+	}
+
+	policies, policyNames, err := listByName("ListPolicies", map[string]interface{}{})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list Policies - %v", err)
+	}
+	declaredPolicies := map[string]bool{}
+	for _, policy := range m.Policies {
+		declaredPolicies[policy.Name] = true
+		var live map[string]interface{}
+		if listed, exists := policies[policy.Name]; exists {
+			params := map[string]interface{}{}
+			params["policy_id"] = listed["policy_id"]
+			live, _, err = postVaultAPI("GetPolicy", params)
+			if err != nil {
+				return nil, fmt.Errorf("Unable to get Policy %s - %v", policy.Name, err)
+			}
+		}
+		plan.planPolicy(policy, live)
+	}
+	if prune {
+		for _, name := range policyNames {
+			if !declaredPolicies[name] {
+				plan.prune("policy", name, "DeletePolicy",
+					map[string]interface{}{"policy_id": policies[name]["policy_id"]})
+			}
+		}
+	}
+
+	plan.prunes = append(plan.prunes, secretPrunes.prunes...)
+	plan.prunes = append(plan.prunes, boxPrunes.prunes...)
+	return plan, nil
+}
+
+// describeChange returns the plan line of a change
+func describeChange(change planChange) string {
+	symbol := map[string]string{
+		planActionCreate:  "+",
+		planActionUpdate:  "~",
+		planActionDelete:  "-",
+		planActionReplace: "-/+",
+	}[change.Action]
+	line := fmt.Sprintf("%s %s %s", symbol, change.Kind, change.Name)
+	if len(change.Fields) != 0 {
+		line += ": " + strings.Join(change.Fields, ", ")
+	}
+	if change.Detail != "" {
+		line += " (" + change.Detail + ")"
+	}
+	return line
+}
+
+// loadPlan reads the manifest given with --file and compares it to the
+// Vault, exiting on errors
+func loadPlan(cmd *cobra.Command) *vaultPlan {
+	file, _ := cmd.Flags().GetString("file")
+	prune, _ := cmd.Flags().GetBool("prune")
+	m, err := loadManifest(file)
+	if err != nil {
+		fmt.Printf("\n%v\n\n", err)
+		os.Exit(1)
+	}
+	plan, err := computePlan(m, prune)
+	if err != nil {
+		fmt.Printf("\n%v\n\n", err)
+		os.Exit(3)
+	}
+	return plan
+}
+
+// planCmd represents the plan command
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Show the changes apply would make to match a manifest",
+	Long: `Compare a YAML manifest of Boxes, Secrets and Policies to the Vault, and
+show what apply would create (+), update (~), replace (-/+) and, with
+--prune, delete (-).
+Nothing is changed. A manifest looks like:
+
+  boxes:
+  - name: payments
+    description: Payments team Secrets
+    rotation: {duration: 30d}
+    exclusive_checkout: true
+    tags: {owner: payments}
+    secrets:
+    - name: db-password
+      generate: {length: 24}
+    - name: api
+      type: kv
+      data: {user: payments}
+      generate: {keys: [token]}
+    - name: tls
+      from: pasm://shared/payments-tls
+  policies:
+  - name: payments-readers
+    role: Secret Reader
+    principals:
+    - local_user: {username: alice}
+    resources: [payments/*]
+
+Boxes take the create-box settings, Policies the create-policy ones. A
+Policy whose role changes is replaced, as the role cannot be updated: it is
+deleted and created again.
+Secret values are only set when a Secret is created: generated, from
+non-sensitive data, or copied from another Secret. Settings left out of the
+manifest are left as they are.
+
+With --prune, Boxes and Policies missing from the manifest are deleted, and
+so are Secrets missing from Boxes which list their secrets.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		plan := loadPlan(cmd)
+		changes := plan.all()
+		if GetOutputFormat(cmd) != "" {
+			report, err := JSONMarshalIndent(map[string]interface{}{"changes": changes})
+			if err != nil {
+				fmt.Println("Error building JSON output: ", err)
+				os.Exit(4)
+			}
+			PrintResponse(cmd, strings.TrimSpace(string(report)))
+			os.Exit(0)
+		}
+
+		if len(changes) == 0 {
+			fmt.Printf("\nNo changes, the Vault matches the manifest\n\n")
+			os.Exit(0)
+		}
+		counts := map[string]int{}
+		fmt.Println()
+		for _, change := range changes {
+			counts[change.Action] += 1
+			fmt.Println(describeChange(change))
+		}
+		fmt.Printf("\nPlan: %d to create, %d to update, %d to replace, %d to delete\n\n",
+			counts[planActionCreate], counts[planActionUpdate], counts[planActionReplace],
+			counts[planActionDelete])
+		os.Exit(0)
+	},
+}
+
+// applyCmd represents the apply command
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Create, update and prune Boxes, Secrets and Policies to match a manifest",
+	Long: `Make the changes plan shows, in dependency order: Boxes, then their
+Secrets, then Policies are created or updated, and with --prune Policies,
+Secrets and Boxes are deleted last. Updates carry the revision the changes
+were computed against, so an item changed in the meantime fails to update
+rather than being overwritten. See plan for the manifest format.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		plan := loadPlan(cmd)
+		changes := plan.all()
+		if len(changes) == 0 {
+			fmt.Printf("\nNo changes, the Vault matches the manifest\n\n")
+			os.Exit(0)
+		}
+
+		fmt.Println()
+		for index, change := range changes {
+			if err := change.apply(); err != nil {
+				fmt.Printf("\nUnable to %s %s %s - %v\n", change.Action, change.Kind, change.Name, err)
+				fmt.Printf("%d of %d changes applied\n\n", index, len(changes))
+				os.Exit(3)
+			}
+			fmt.Println(describeChange(change))
+		}
+		fmt.Printf("\nApplied %d changes\n\n", len(changes))
+		os.Exit(0)
+	},
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{planCmd, applyCmd} {
+		rootCmd.AddCommand(cmd)
+		cmd.Flags().StringP("file", "f", "",
+			"YAML manifest of the Boxes, Secrets and Policies")
+		cmd.Flags().Bool("prune", false,
+			"Delete the Boxes, Secrets and Policies missing from the manifest")
+
+		// mark mandatory fields as required
+		cmd.MarkFlagRequired("file")
+	}
+}
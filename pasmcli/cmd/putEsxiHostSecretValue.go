@@ -116,7 +116,7 @@ var putEsxiHostSecretValueCmd = &cobra.Command{
                 os.Exit(5)
             }
 
-            fmt.Println("\n" + retStr + "\n")
+            PrintResponse(cmd, retStr)
 
             // make a decision on what to exit with
             retMap := JsonStrToMap(retStr)
@@ -131,6 +131,7 @@ var putEsxiHostSecretValueCmd = &cobra.Command{
 
 func init() {
     rootCmd.AddCommand(putEsxiHostSecretValueCmd)
+    acceptSecretRef(putEsxiHostSecretValueCmd)
     putEsxiHostSecretValueCmd.Flags().StringP("boxid", "b", "",
                                     "Id or name of the Box")
     putEsxiHostSecretValueCmd.Flags().StringP("secretid", "s", "",
@@ -20,8 +20,6 @@ import (
     // standard
     "os"
     "fmt"
-    "bytes"
-    "encoding/json"
     "strings"
     // external
     "github.com/spf13/cobra"
@@ -49,73 +47,58 @@ var putFileSecretCmd = &cobra.Command{
 
         // file
         filename, _ := flags.GetString("filename")
-        fi, err := os.Stat(filename)
+        size, err := checkFileSecretSize(flags, filename)
         if err != nil {
             fmt.Println(err)
             os.Exit(1)
         }
 
-        // 5 MB
-        max_filesize_bytes := int64(1000 * 1000 * 5)
-        if fi.Size() > max_filesize_bytes {
-            fmt.Println("File size cannot be greater than 5 MB")
-            os.Exit(1)
-        }
-        b64file, err := B64File(filename)
+        // only the base name is stored with the Secret
+        secretFilename, err := fileSecretName(filename)
         if err != nil {
             fmt.Println(err)
-            os.Exit(4)
-        }
-
-        if len(b64file) == 0 {
-            fmt.Println("Error. Empty file provided: %s", filename)
             os.Exit(1)
         }
 
-        params["secret_data"] = b64file
-
         params["secret_subtype_info"] = map[string]interface{}{
             "type": "file",
             "info": map[string]interface{} {
-                "filename": filename,
+                "filename": secretFilename,
             },
         }
 
-        // JSONify
-        jsonParams, err := json.Marshal(params)
-        if (err != nil) {
-            fmt.Println("Error building JSON request: ", err)
-            os.Exit(1)
-        }
-
-        // now POST
-        endpoint := GetEndPoint("", "1.0", "PutSecretValue")
-        ret, err := DoPost(endpoint,
-                               GetCACertFile(),
-                               AuthTokenKV(),
-                               jsonParams,
-                               "application/json")
+        // now POST, streaming the file content
+        retStatus, retStr, sum, err := postFileSecret("PutSecretValue", params, nil,
+                                                      filename, size)
         if err != nil {
             fmt.Printf("\nHTTP request failed: %s\n", err)
             os.Exit(4)
         } else {
-            // type assertion
-            retBytes := ret["data"].(*bytes.Buffer)
-            retStatus := ret["status"].(int)
-            retStr := strings.ReplaceAll(retBytes.String(), "\\", "")
+            retStr = strings.ReplaceAll(retStr, "\\", "")
 
             if (retStr == "" && retStatus == 404) {
                 fmt.Println("\nAction denied\n")
                 os.Exit(5)
             }
 
-            fmt.Println("\n" + retStr + "\n")
+            PrintResponse(cmd, retStr)
 
             // make a decision on what to exit with
             retMap := JsonStrToMap(retStr)
             if _, present := retMap["error"]; present {
                 os.Exit(3)
             } else {
+                fmt.Fprintf(os.Stderr, "Uploaded %s\nSize: %d bytes\nSHA-256: %s\n",
+                            secretFilename, size, sum)
+                // PutSecretValue doesn't take tags, tag the Secret separately.
+                // The upload succeeded already, so a failure is only a warning
+                err := recordFileSecretDigest(boxid, secretid, sum)
+                if err != nil {
+                    fmt.Fprintf(os.Stderr, "\nWarning: unable to save SHA-256 in tag %s - %v\n\n",
+                                FileSecretSHA256Tag, err)
+                    os.Exit(0)
+                }
+                fmt.Fprintf(os.Stderr, "SHA-256 saved in tag %s\n\n", FileSecretSHA256Tag)
                 os.Exit(0)
             }
         }
@@ -124,6 +107,7 @@ var putFileSecretCmd = &cobra.Command{
 
 func init() {
     rootCmd.AddCommand(putFileSecretCmd)
+    acceptSecretRef(putFileSecretCmd)
     putFileSecretCmd.Flags().StringP("boxid", "b", "",
                                     "Id or name of the Box")
     putFileSecretCmd.Flags().StringP("secretid", "s", "",
@@ -131,6 +115,8 @@ func init() {
     putFileSecretCmd.Flags().StringP("filename", "f", "",
                                     "File to store as Secret data")
 
+    addFileSecretUploadFlags(putFileSecretCmd)
+
     // mark mandatory fields as required
     putFileSecretCmd.MarkFlagRequired("boxid")
     putFileSecretCmd.MarkFlagRequired("secretid")
@@ -130,7 +130,7 @@ var putSSHKeySecretValueCmd = &cobra.Command{
                 os.Exit(5)
             }
 
-            fmt.Println("\n" + retStr + "\n")
+            PrintResponse(cmd, retStr)
 
             // make a decision on what to exit with
             retMap := JsonStrToMap(retStr)
@@ -145,6 +145,7 @@ var putSSHKeySecretValueCmd = &cobra.Command{
 
 func init() {
     rootCmd.AddCommand(putSSHKeySecretValueCmd)
+    acceptSecretRef(putSSHKeySecretValueCmd)
     putSSHKeySecretValueCmd.Flags().StringP("boxid", "b", "",
                                     "Id or name of the Box")
     putSSHKeySecretValueCmd.Flags().StringP("secretid", "s", "",
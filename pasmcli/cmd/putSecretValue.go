@@ -196,7 +196,7 @@ var putSecretValueCmd = &cobra.Command{
                 os.Exit(5)
             }
 
-            fmt.Println("\n" + retStr + "\n")
+            PrintResponse(cmd, retStr)
 
             // make a decision on what to exit with
             retMap := JsonStrToMap(retStr)
@@ -211,6 +211,7 @@ var putSecretValueCmd = &cobra.Command{
 
 func init() {
     rootCmd.AddCommand(putSecretValueCmd)
+    acceptSecretRef(putSecretValueCmd)
     putSecretValueCmd.Flags().StringP("boxid", "b", "",
                                     "Id or name of the Box")
     putSecretValueCmd.Flags().StringP("secretid", "s", "",
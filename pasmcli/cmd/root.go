@@ -45,6 +45,7 @@ Create and manage Secrets.`,
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	AddOutputFlags(rootCmd)
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
 	}
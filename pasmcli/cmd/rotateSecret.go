@@ -98,7 +98,7 @@ var rotateSecretCmd = &cobra.Command{
                     fmt.Printf("\nSecret rotation failure: %v\n\n", retMap["error"])
                     os.Exit(3)
                 } else {
-                    fmt.Println("\n" + retStr + "\n")
+                    PrintResponse(cmd, retStr)
                     fmt.Println("\nUnknown error\n")
                     os.Exit(100)
                 }
@@ -109,6 +109,7 @@ var rotateSecretCmd = &cobra.Command{
 
 func init() {
     rootCmd.AddCommand(rotateSecretCmd)
+    acceptSecretRef(rotateSecretCmd)
     rotateSecretCmd.Flags().StringP("boxid", "b", "",
                                 "Id or name of the Box under which the " +
                                 "Secret is")
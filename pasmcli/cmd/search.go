@@ -0,0 +1,318 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+// searchFields are the Secret fields a query is matched against
+var searchFields = []string{"name", "description", "tags", "type"}
+
+// searchMatch is a Secret found by search
+type searchMatch struct {
+	BoxId     string   `json:"box_id"`
+	Box       string   `json:"box"`
+	SecretId  string   `json:"secret_id"`
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	UpdatedAt string   `json:"updated_at"`
+	Matched   []string `json:"matched,omitempty"`
+}
+
+// nestedString returns the string at a dotted path of keys, "" if there's
+// none
+func nestedString(value map[string]interface{}, path string) string {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		value, _ = value[key].(map[string]interface{})
+	}
+	str, _ := value[keys[len(keys)-1]].(string)
+	return str
+}
+
+// secretType returns the most specific type of a listed Secret
+func secretType(secret map[string]interface{}) string {
+	for _, path := range []string{"secret_subtype_info.type", "secret_config.type", "secret_type"} {
+		if typeName := nestedString(secret, path); typeName != "" {
+			return typeName
+		}
+	}
+	return ""
+}
+
+// searchFieldValues returns the values of field in a listed Secret. Tags are
+// returned as KEY=VALUE, so that a tag selector matches them.
+func searchFieldValues(secret map[string]interface{}, field string) []string {
+	values := []string{}
+	switch field {
+	case "name":
+		name, _ := secret["name"].(string)
+		values = append(values, name)
+	case "description":
+		for _, key := range []string{"desc", "description"} {
+			if description, _ := secret[key].(string); description != "" {
+				values = append(values, description)
+			}
+		}
+	case "tags":
+		tags, _ := secret["tags"].(map[string]interface{})
+		for key, value := range tags {
+			values = append(values, key+"="+fmt.Sprint(value))
+		}
+	case "type":
+		values = append(values, secretType(secret))
+		if secretTypeName, _ := secret["secret_type"].(string); secretTypeName != "" {
+			values = append(values, secretTypeName)
+		}
+	}
+	return values
+}
+
+// searchMatcher builds the function telling if a field value matches query
+func searchMatcher(query string, useRegex bool, caseSensitive bool) (func(string) bool, error) {
+	if useRegex {
+		if !caseSensitive {
+			query = "(?i)" + query
+		}
+		expression, err := regexp.Compile(query)
+		if err != nil {
+			return nil, err
+		}
+		return expression.MatchString, nil
+	}
+	if !caseSensitive {
+		query = strings.ToLower(query)
+	}
+	return func(value string) bool {
+		if !caseSensitive {
+			value = strings.ToLower(value)
+		}
+		return strings.Contains(value, query)
+	}, nil
+}
+
+// searchCmd represents the search command
+var searchCmd = &cobra.Command{
+	Use:   "search [query]",
+	Short: "Find Secrets across all Boxes",
+	Long: `Find Secrets in all the Boxes the user can access. The query is matched,
+as a case insensitive substring or with --regex as a regular expression,
+against the name, description, tags and type of each Secret. Tags are
+matched as KEY=VALUE, so a query like owner=payments finds Secrets with that
+tag. --tag selects Secrets with a tag, with or without a query.
+
+Boxes are listed concurrently, at most --concurrency at a time. Matches are
+printed as a table unless another --output format is given.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+		useRegex, _ := flags.GetBool("regex")
+		caseSensitive, _ := flags.GetBool("case-sensitive")
+		fields, _ := flags.GetStringSlice("field")
+		tagSelectors, _ := flags.GetStringArray("tag")
+		boxFilters, _ := flags.GetStringArray("boxid")
+		concurrency, _ := flags.GetInt("concurrency")
+
+		if len(args) == 0 && len(tagSelectors) == 0 {
+			fmt.Println("Please provide a query or at least one --tag")
+			os.Exit(1)
+		}
+		if concurrency < 1 {
+			fmt.Println("--concurrency must be at least 1")
+			os.Exit(1)
+		}
+		supportedFields := map[string]bool{}
+		for _, field := range searchFields {
+			supportedFields[field] = true
+		}
+		for _, field := range fields {
+			if !supportedFields[field] {
+				fmt.Printf("\nInvalid --field %s. Supported: %s\n\n", field,
+					strings.Join(searchFields, ", "))
+				os.Exit(1)
+			}
+		}
+
+		var matches func(string) bool
+		if len(args) == 1 {
+			var err error
+			matches, err = searchMatcher(args[0], useRegex, caseSensitive)
+			if err != nil {
+				fmt.Printf("\nInvalid regular expression %s - %v\n\n", args[0], err)
+				os.Exit(1)
+			}
+		}
+
+		selectedBoxes := map[string]bool{}
+		for _, box := range boxFilters {
+			selectedBoxes[box] = true
+		}
+		boxes := []map[string]interface{}{}
+		_, _, err := ListAllPages("ListBoxes", map[string]interface{}{}, ListPaging{All: true},
+			func(item interface{}) error {
+				box, isMap := item.(map[string]interface{})
+				if !isMap {
+					return nil
+				}
+				boxId, _ := box["box_id"].(string)
+				boxName, _ := box["name"].(string)
+				if len(boxFilters) == 0 || selectedBoxes[boxId] || selectedBoxes[boxName] {
+					boxes = append(boxes, box)
+				}
+				return nil
+			})
+		if err != nil {
+			ExitOnListError(err, "Boxes not found")
+		}
+
+		// tags are read from the metadata of Secrets listed without them
+		searchTags := false
+		for _, field := range fields {
+			searchTags = searchTags || field == "tags"
+		}
+		limiter := newRateLimiter(0)
+
+		found := []searchMatch{}
+		var foundLock sync.Mutex
+		var failures []string
+		search := func(box map[string]interface{}) {
+			boxId, _ := box["box_id"].(string)
+			boxName, _ := box["name"].(string)
+			params := map[string]interface{}{}
+			params["box_id"] = boxId
+			_, _, err := ListAllPages("ListSecrets", params, ListPaging{All: true},
+				func(item interface{}) error {
+					secret, isMap := item.(map[string]interface{})
+					if !isMap {
+						return nil
+					}
+					if len(tagSelectors) != 0 || (matches != nil && searchTags) {
+						if _, err := listedSecretTags(secret, limiter); err != nil {
+							return err
+						}
+					}
+					tags, _ := secret["tags"].(map[string]interface{})
+					if !matchesTags(tags, tagSelectors) {
+						return nil
+					}
+					matched := []string{}
+					if matches != nil {
+						for _, field := range fields {
+							for _, value := range searchFieldValues(secret, field) {
+								if matches(value) {
+									matched = append(matched, field)
+									break
+								}
+							}
+						}
+						if len(matched) == 0 {
+							return nil
+						}
+					}
+
+					match := searchMatch{BoxId: boxId, Box: boxName, Matched: matched}
+					match.SecretId, _ = secret["secret_id"].(string)
+					match.Name, _ = secret["name"].(string)
+					match.Type = secretType(secret)
+					match.UpdatedAt, _ = secret["updated_at"].(string)
+					foundLock.Lock()
+					found = append(found, match)
+					foundLock.Unlock()
+					return nil
+				})
+			if err != nil && err != errListNotFound {
+				foundLock.Lock()
+				failures = append(failures, fmt.Sprintf("Box %s - %v", boxName, err))
+				foundLock.Unlock()
+			}
+		}
+
+		boxQueue := make(chan map[string]interface{})
+		var workers sync.WaitGroup
+		for worker := 0; worker < concurrency; worker += 1 {
+			workers.Add(1)
+			go func() {
+				defer workers.Done()
+				for box := range boxQueue {
+					search(box)
+				}
+			}()
+		}
+		for _, box := range boxes {
+			boxQueue <- box
+		}
+		close(boxQueue)
+		workers.Wait()
+
+		sort.Slice(found, func(i, j int) bool {
+			if found[i].Box != found[j].Box {
+				return found[i].Box < found[j].Box
+			}
+			return found[i].Name < found[j].Name
+		})
+		for _, failure := range failures {
+			fmt.Fprintf(os.Stderr, "Unable to list the Secrets of %s\n", failure)
+		}
+
+		format := GetOutputFormat(cmd)
+		if format == "" {
+			format = OutputFormatTable
+		}
+		report, err := JSONMarshalIndent(map[string]interface{}{"matches": found})
+		if err != nil {
+			fmt.Println("Error building JSON output: ", err)
+			os.Exit(4)
+		}
+		if err := PrintFormatted(os.Stdout, format, "matches", strings.TrimSpace(string(report))); err != nil {
+			fmt.Printf("\nError formatting output - %v\n", err)
+			os.Exit(4)
+		}
+		if len(failures) != 0 {
+			os.Exit(3)
+		}
+		if len(found) == 0 {
+			os.Exit(5)
+		}
+		os.Exit(0)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+	searchCmd.Flags().BoolP("regex", "r", false,
+		"Match the query as a regular expression")
+	searchCmd.Flags().Bool("case-sensitive", false,
+		"Match the query case sensitively")
+	searchCmd.Flags().StringSlice("field", searchFields,
+		"Secret fields to match the query against, comma separated. "+
+			"Supported: "+strings.Join(searchFields, ", "))
+	searchCmd.Flags().StringArrayP("tag", "t", []string{},
+		"Find only the Secrets with this tag, given as KEY or KEY=VALUE. "+
+			"This option is repeatable, all the tags must match.")
+	searchCmd.Flags().StringArrayP("boxid", "b", []string{},
+		"Search only this Box, given by id or name. This option is repeatable.")
+	searchCmd.Flags().Int("concurrency", 4,
+		"Number of Boxes searched at the same time")
+}
@@ -0,0 +1,305 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	SecretRefScheme = "pasm://"
+	SecretRefSyntax = "pasm://BOX/SECRET[#KEY][@VERSION]"
+)
+
+// secretRef identifies a Box, a Secret in it, and optionally a key in the
+// Secret data and a version, e.g. pasm://db-box/postgres#password@3. The
+// scheme is optional, and names containing /, # or @ are percent-encoded.
+type secretRef struct {
+	Box     string
+	Secret  string // empty when only a Box is referenced
+	Key     string
+	Version int // 0 when not given
+}
+
+func (r secretRef) String() string {
+	ref := SecretRefScheme + url.PathEscape(r.Box)
+	if r.Secret != "" {
+		ref += "/" + url.PathEscape(r.Secret)
+	}
+	if r.Key != "" {
+		ref += "#" + url.PathEscape(r.Key)
+	}
+	if r.Version != 0 {
+		ref += "@" + strconv.Itoa(r.Version)
+	}
+	return ref
+}
+
+// isSecretRef tells if arg is a reference rather than a plain id, i.e. it
+// starts with pasm:// or is exactly BOX/SECRET
+func isSecretRef(arg string) bool {
+	arg = strings.TrimSpace(arg)
+	if len(arg) >= len(SecretRefScheme) &&
+		strings.EqualFold(arg[:len(SecretRefScheme)], SecretRefScheme) {
+		return true
+	}
+	parts := strings.Split(arg, "/")
+	return len(parts) == 2 && parts[0] != "" && parts[1] != "" &&
+		!strings.Contains(arg, ",")
+}
+
+// parseSecretRef parses pasm://BOX/SECRET#KEY@VERSION, where everything
+// but BOX is optional
+func parseSecretRef(ref string) (secretRef, error) {
+	var parsed secretRef
+	invalid := func(reason string) (secretRef, error) {
+		return secretRef{}, fmt.Errorf("Invalid reference %q - %s. Expected %s",
+			ref, reason, SecretRefSyntax)
+	}
+
+	rest := strings.TrimSpace(ref)
+	if scheme := strings.Index(rest, "://"); scheme != -1 {
+		if !strings.EqualFold(rest[:scheme+3], SecretRefScheme) {
+			return invalid(fmt.Sprintf("unsupported scheme %q", rest[:scheme]))
+		}
+		rest = rest[scheme+3:]
+	}
+
+	// @VERSION comes last, after the key if there is one
+	if at := strings.LastIndex(rest, "@"); at != -1 {
+		version, err := strconv.Atoi(rest[at+1:])
+		if err != nil || version < 1 {
+			return invalid(fmt.Sprintf("version %q is not a positive number, "+
+				"percent-encode @ in names as %%40", rest[at+1:]))
+		}
+		parsed.Version = version
+		rest = rest[:at]
+	}
+
+	if hash := strings.Index(rest, "#"); hash != -1 {
+		key, err := url.PathUnescape(rest[hash+1:])
+		if err != nil || key == "" {
+			return invalid("empty or badly encoded key")
+		}
+		parsed.Key = key
+		rest = rest[:hash]
+	}
+
+	parts := strings.Split(rest, "/")
+	if len(parts) > 2 {
+		return invalid("too many / separators, percent-encode / in names as %2F")
+	}
+	for i, part := range parts {
+		name, err := url.PathUnescape(part)
+		if err != nil || name == "" {
+			return invalid("empty or badly encoded name")
+		}
+		if i == 0 {
+			parsed.Box = name
+		} else {
+			parsed.Secret = name
+		}
+	}
+	return parsed, nil
+}
+
+// parseSecretRefArg parses a reference which must name a Secret
+func parseSecretRefArg(ref string) (secretRef, error) {
+	parsed, err := parseSecretRef(ref)
+	if err == nil && parsed.Secret == "" {
+		err = fmt.Errorf("Invalid reference %q - no Secret given. Expected %s",
+			ref, SecretRefSyntax)
+	}
+	return parsed, err
+}
+
+// setRefFlag sets a flag from the reference, unless it was given explicitly
+// with a different value
+func setRefFlag(cmd *cobra.Command, name string, value string) error {
+	flag := cmd.Flags().Lookup(name)
+	if flag.Changed && flag.Value.String() != value {
+		return fmt.Errorf("--%s %s conflicts with the reference", name, flag.Value.String())
+	}
+	return cmd.Flags().Set(name, value)
+}
+
+// applySecretRef fills in --boxid, --secretid, --version and --field from
+// a reference given as the only argument
+func applySecretRef(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+	ref, err := parseSecretRefArg(args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := setRefFlag(cmd, "boxid", ref.Box); err != nil {
+		return err
+	}
+	if err := setRefFlag(cmd, "secretid", ref.Secret); err != nil {
+		return err
+	}
+	if ref.Key != "" {
+		if cmd.Flags().Lookup(secretValueOptionField) == nil {
+			return fmt.Errorf("%s does not take a key in the reference", cmd.Name())
+		}
+		if err := setRefFlag(cmd, secretValueOptionField, ref.Key); err != nil {
+			return err
+		}
+	}
+	if ref.Version != 0 {
+		if cmd.Flags().Lookup("version") == nil {
+			return fmt.Errorf("%s does not take a version in the reference", cmd.Name())
+		}
+		if err := setRefFlag(cmd, "version", strconv.Itoa(ref.Version)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// acceptSecretRef lets cmd take a Secret reference as its argument, in
+// place of --boxid, --secretid and --version. Runs before the required
+// flags are checked, so the reference satisfies them.
+func acceptSecretRef(cmd *cobra.Command) {
+	cmd.Use += " [" + SecretRefSyntax + "]"
+	cmd.Args = cobra.MaximumNArgs(1)
+	cmd.PreRun = func(cmd *cobra.Command, args []string) {
+		if err := applySecretRef(cmd, args); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+}
+
+// secretRefResolver turns Box and Secret names into ids, for the APIs which
+// only take ids. Lookups are cached.
+type secretRefResolver struct {
+	boxIds    map[string]string
+	secretIds map[string]string
+}
+
+func newSecretRefResolver() *secretRefResolver {
+	return &secretRefResolver{
+		boxIds:    map[string]string{},
+		secretIds: map[string]string{},
+	}
+}
+
+func (r *secretRefResolver) boxId(box string) (string, error) {
+	if id, cached := r.boxIds[box]; cached {
+		return id, nil
+	}
+	params := map[string]interface{}{}
+	params["box_id"] = box
+	retMap, _, err := postVaultAPI("GetBox", params)
+	if err != nil {
+		return "", fmt.Errorf("Unable to resolve Box %q - %v", box, err)
+	}
+	id, _ := retMap["box_id"].(string)
+	if id == "" {
+		return "", fmt.Errorf("Box %q not found", box)
+	}
+	r.boxIds[box] = id
+	return id, nil
+}
+
+func (r *secretRefResolver) secretId(boxId string, secret string) (string, error) {
+	cacheKey := boxId + "/" + secret
+	if id, cached := r.secretIds[cacheKey]; cached {
+		return id, nil
+	}
+	params := map[string]interface{}{}
+	params["box_id"] = boxId
+	params["secret_id"] = secret
+	retMap, _, err := postVaultAPI("GetSecretMetadata", params)
+	if err != nil {
+		return "", fmt.Errorf("Unable to resolve Secret %q - %v", secret, err)
+	}
+	id, _ := retMap["secret_id"].(string)
+	if id == "" {
+		return "", fmt.Errorf("Secret %q not found", secret)
+	}
+	r.secretIds[cacheKey] = id
+	return id, nil
+}
+
+// policyResources builds Policy resources from --resource values. Each is
+// either the legacy "BOXID,SECRETID,..." list of ids, or a reference like
+// pasm://BOX/SECRET or BOX/* whose names are resolved to ids. Each legacy
+// value is a resource of its own, as before, while references to Secrets of
+// the same Box are grouped into one resource.
+func policyResources(resourceArray []string) ([]interface{}, error) {
+	resolver := newSecretRefResolver()
+	resources := []interface{}{}
+	byBox := map[string]map[string]interface{}{}
+	add := func(boxId string, secretId string) {
+		resource, present := byBox[boxId]
+		if !present {
+			resource = map[string]interface{}{"box_id": boxId, "secret_id": []string{}}
+			byBox[boxId] = resource
+			resources = append(resources, resource)
+		}
+		resource["secret_id"] = append(resource["secret_id"].([]string), secretId)
+	}
+
+	for _, resourceArg := range resourceArray {
+		if !isSecretRef(resourceArg) {
+			resource := strings.Split(resourceArg, ",")
+			// remove trailing/leading whitespace
+			for index := range resource {
+				resource[index] = strings.TrimSpace(resource[index])
+			}
+			if len(resource) < 2 {
+				return nil, fmt.Errorf("Invalid resource argument: %s", resourceArg)
+			}
+			resources = append(resources, map[string]interface{}{
+				"box_id":    resource[0],
+				"secret_id": resource[1:],
+			})
+			continue
+		}
+
+		ref, err := parseSecretRefArg(resourceArg)
+		if err != nil {
+			return nil, err
+		}
+		if ref.Key != "" || ref.Version != 0 {
+			return nil, fmt.Errorf("Invalid resource %q - Policies apply to whole "+
+				"Secrets, remove the key and version", resourceArg)
+		}
+		boxId, err := resolver.boxId(ref.Box)
+		if err != nil {
+			return nil, err
+		}
+		secretId := ref.Secret
+		if secretId != "*" {
+			if secretId, err = resolver.secretId(boxId, ref.Secret); err != nil {
+				return nil, err
+			}
+		}
+		add(boxId, secretId)
+	}
+	return resources, nil
+}
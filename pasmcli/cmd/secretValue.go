@@ -0,0 +1,176 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	secretValueOptionField    = "field"
+	secretValueOptionJSONPath = "jsonpath"
+	secretValueOptionRaw      = "raw"
+)
+
+// secretValueSelector picks the single value of a Secret to print, for
+// scripts which only need e.g. the password
+type secretValueSelector struct {
+	Path []interface{} // string keys and int indexes
+	Raw  bool
+	desc string
+}
+
+func addSecretValueFlags(cmd *cobra.Command) {
+	cmd.Flags().String(secretValueOptionField, "",
+		"Print only this field of a key-value Secret")
+	cmd.Flags().String(secretValueOptionJSONPath, "",
+		"Print only the value at this path in the Secret data, e.g. .db.host or .hosts[0]")
+	cmd.Flags().Bool(secretValueOptionRaw, false,
+		"Print only the Secret value, without any decoration")
+}
+
+func getSecretValueSelector(flags *pflag.FlagSet) (secretValueSelector, error) {
+	var sel secretValueSelector
+	sel.Raw, _ = flags.GetBool(secretValueOptionRaw)
+	field, _ := flags.GetString(secretValueOptionField)
+	jsonPath, _ := flags.GetString(secretValueOptionJSONPath)
+
+	switch {
+	case flags.Changed(secretValueOptionField) && flags.Changed(secretValueOptionJSONPath):
+		return sel, fmt.Errorf("Specify only one of --%s and --%s",
+			secretValueOptionField, secretValueOptionJSONPath)
+	case flags.Changed(secretValueOptionField):
+		if field == "" {
+			return sel, fmt.Errorf("--%s cannot be empty", secretValueOptionField)
+		}
+		sel.Path = []interface{}{field}
+		sel.desc = fmt.Sprintf("Field %q", field)
+	case flags.Changed(secretValueOptionJSONPath):
+		path, err := ParseJSONPath(jsonPath)
+		if err != nil {
+			return sel, fmt.Errorf("Invalid --%s - %v", secretValueOptionJSONPath, err)
+		}
+		sel.Path = path
+		sel.desc = fmt.Sprintf("Path %q", jsonPath)
+	}
+	return sel, nil
+}
+
+// isRequested tells if a single value was asked for
+func (sel secretValueSelector) isRequested() bool {
+	return sel.Raw || sel.Path != nil
+}
+
+// secretData returns the Secret data to apply the path to. String and file
+// Secrets are decoded from JSON, only if a path was given.
+func (sel secretValueSelector) secretData(retMap map[string]interface{},
+	spool *secretSpool) (interface{}, error) {
+
+	var data interface{}
+	var raw []byte
+	kind := "Secret value"
+	if spool != nil {
+		content, err := spool.content()
+		if err == nil {
+			raw, err = io.ReadAll(content)
+		}
+		if err != nil {
+			return nil, err
+		}
+		kind = "file Secret content"
+	} else {
+		secretData, present := retMap["secret_data"]
+		if !present {
+			return nil, fmt.Errorf("No Secret data in the response")
+		}
+		str, isString := secretData.(string)
+		if !isString {
+			return secretData, nil
+		}
+		raw = []byte(str)
+	}
+
+	if len(sel.Path) == 0 {
+		return string(raw), nil
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("%s is not JSON, it has no fields", kind)
+	}
+	return data, nil
+}
+
+// lookup walks the path into data. A path with wildcards gives an array of
+// all the matches.
+func (sel secretValueSelector) lookup(data interface{}) (interface{}, error) {
+	matches := LookupJSONPath(data, sel.Path)
+	switch {
+	case HasJSONPathWildcard(sel.Path):
+		return matches, nil
+	case len(matches) == 0:
+		return nil, fmt.Errorf("%s not found in the Secret", sel.desc)
+	}
+	return matches[0], nil
+}
+
+// printSecretValue prints the selected value alone on standard output, with
+// no trailing newline. Strings are printed as is, anything else as compact
+// JSON.
+func printSecretValue(sel secretValueSelector,
+	retMap map[string]interface{},
+	spool *secretSpool) error {
+
+	// file content is streamed as is, it can be large
+	if spool != nil && len(sel.Path) == 0 {
+		content, err := spool.content()
+		if err == nil {
+			_, err = io.Copy(os.Stdout, content)
+		}
+		return err
+	}
+
+	data, err := sel.secretData(retMap, spool)
+	if err != nil {
+		return err
+	}
+	value, err := sel.lookup(data)
+	if err != nil {
+		return err
+	}
+
+	var out string
+	if str, isString := value.(string); isString {
+		out = str
+	} else {
+		buffer := &bytes.Buffer{}
+		encoder := json.NewEncoder(buffer)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(value); err != nil {
+			return err
+		}
+		out = strings.TrimSuffix(buffer.String(), "\n")
+	}
+	_, err = io.WriteString(os.Stdout, out)
+	return err
+}
@@ -78,7 +78,7 @@ var setSecretVersionCmd = &cobra.Command{
                 os.Exit(5)
             }
 
-            fmt.Println("\n" + retStr + "\n")
+            PrintResponse(cmd, retStr)
 
             // make a decision on what to exit with
             retMap := JsonStrToMap(retStr)
@@ -93,6 +93,7 @@ var setSecretVersionCmd = &cobra.Command{
 
 func init() {
     rootCmd.AddCommand(setSecretVersionCmd)
+    acceptSecretRef(setSecretVersionCmd)
     setSecretVersionCmd.Flags().StringP("boxid", "b", "",
                                  "Id or name of the Box where the Secret is")
     setSecretVersionCmd.Flags().StringP("secretid", "s", "",
@@ -0,0 +1,822 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// syncedSecretFields are the Secret settings sync keeps the same on both
+// Vaults, the type and configuration are only set when it creates the copy
+var syncedSecretFields = []string{
+	"desc", "lease", "rotation", "exclusive_checkout", "expires_at", "tags",
+}
+
+// statuses of the drift report, the dry-run ones tell what sync would do
+const (
+	syncCreated   = "created"
+	syncRecreated = "recreated"
+	syncUpdated   = "updated"
+	syncDeleted   = "deleted"
+	syncFailed    = "failed"
+	syncMissing   = "missing"
+	syncChanged   = "changed"
+	syncRemoved   = "removed"
+)
+
+// syncState is the checkpoint kept between runs for a pair of Vaults
+type syncState struct {
+	From  string                   `json:"from"`
+	To    string                   `json:"to"`
+	Boxes map[string]*syncBoxState `json:"boxes"` // by source Box id
+}
+
+// syncBoxState maps a source Box and its Secrets to their copies
+type syncBoxState struct {
+	Name        string                      `json:"name"`
+	TargetBoxId string                      `json:"target_box_id"`
+	Secrets     map[string]*syncSecretState `json:"secrets"` // by source Secret id
+	SyncedAt    string                      `json:"synced_at"`
+}
+
+// syncSecretState is what both copies of a Secret looked like after they
+// were last synced
+type syncSecretState struct {
+	Name           string    `json:"name"`
+	TargetSecretId string    `json:"target_secret_id"`
+	Source         syncStamp `json:"source"`
+	Target         syncStamp `json:"target"`
+}
+
+// syncStamp tells if a Secret changed since it was synced, from its
+// current version and metadata revision and timestamp
+type syncStamp struct {
+	Version   int    `json:"version"`
+	Revision  int    `json:"revision"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// syncChange is a line of the drift report
+type syncChange struct {
+	Box    string `json:"box"`
+	Secret string `json:"secret,omitempty"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// vaultSync syncs Boxes from one Vault to another
+type vaultSync struct {
+	source      vaultSession
+	target      vaultSession
+	state       *syncState
+	dryRun      bool
+	keepDeleted bool
+	changes     []syncChange
+	failed      bool
+	inSync      int
+}
+
+func newSyncStamp(metadata map[string]interface{}) syncStamp {
+	stamp := syncStamp{}
+	if value, isNumber := metadata["current_version"].(float64); isNumber {
+		stamp.Version = int(value)
+	}
+	if value, isNumber := metadata["revision"].(float64); isNumber {
+		stamp.Revision = int(value)
+	}
+	stamp.UpdatedAt, _ = metadata["updated_at"].(string)
+	return stamp
+}
+
+// syncedSecretSettings returns the settings of a Secret which sync carries
+// over, keyed as UpdateSecret takes them
+func syncedSecretSettings(metadata map[string]interface{}) map[string]interface{} {
+	settings := map[string]interface{}{}
+	for _, field := range syncedSecretFields {
+		if value, present := metadata[field]; present && value != nil {
+			settings[field] = value
+		}
+	}
+	if description, present := metadata["description"]; present && settings["desc"] == nil {
+		settings["desc"] = description
+	}
+	if settings["tags"] == nil {
+		settings["tags"] = map[string]interface{}{}
+	}
+	return settings
+}
+
+// syncedBoxSettings returns the settings of a Box which sync carries over
+func syncedBoxSettings(box map[string]interface{}) map[string]interface{} {
+	settings := map[string]interface{}{}
+	for _, field := range clonedBoxFields {
+		if value, present := box[field]; present && value != nil {
+			settings[field] = value
+		}
+	}
+	return settings
+}
+
+// resolveSyncTokenFile finds the token file of a profile, given as a path
+// or as the NAME of pasmcli.data/NAME_token.txt
+func resolveSyncTokenFile(profile string) (string, error) {
+	if _, err := os.Stat(profile); err == nil || strings.ContainsAny(profile, `/\`) {
+		return profile, nil
+	}
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, profile+"_token.txt"), nil
+}
+
+// loadSyncState reads the checkpoint, or starts a new one for these Vaults
+func loadSyncState(path string, from string, to string) (*syncState, error) {
+	state := &syncState{From: from, To: to, Boxes: map[string]*syncBoxState{}}
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, state); err != nil {
+		return nil, fmt.Errorf("Invalid sync state %s - %v", path, err)
+	}
+	if state.From != from || state.To != to {
+		return nil, fmt.Errorf("Sync state %s is for %s to %s, not %s to %s",
+			path, state.From, state.To, from, to)
+	}
+	if state.Boxes == nil {
+		state.Boxes = map[string]*syncBoxState{}
+	}
+	return state, nil
+}
+
+// saveSyncState replaces the checkpoint, so that a run killed half way
+// leaves the previous one
+func saveSyncState(path string, state *syncState) error {
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// lockSync makes sure a single sync runs with a checkpoint. A lock left by
+// a sync which is no longer running is taken over. Returns the function
+// removing the lock.
+func lockSync(statePath string) (func(), error) {
+	lockPath := statePath + ".lock"
+	file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if os.IsExist(err) && staleSyncLock(lockPath) {
+		os.Remove(lockPath)
+		file, err = os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	}
+	if os.IsExist(err) {
+		return nil, fmt.Errorf("Another sync holds %s. Remove it if no sync is running.", lockPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(file, "%d %s\n", os.Getpid(), time.Now().UTC().Format(time.RFC3339))
+	file.Close()
+	return func() { os.Remove(lockPath) }, nil
+}
+
+// staleSyncLock tells if the process recorded in a lock file is gone
+func staleSyncLock(lockPath string) bool {
+	content, err := os.ReadFile(lockPath)
+	if err != nil {
+		return false
+	}
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 {
+		// being written by the sync which just created it
+		return false
+	}
+	pid, err := strconv.Atoi(fields[0])
+	return err == nil && pid > 0 && !processAlive(pid)
+}
+
+func (s *vaultSync) report(box string, secret string, status string, detail string) {
+	s.changes = append(s.changes, syncChange{Box: box, Secret: secret, Status: status, Detail: detail})
+	if status == syncFailed {
+		s.failed = true
+	}
+}
+
+// findCopy returns the listed item with the id of the copy, or when it
+// wasn't synced yet, the item with the same name
+func findCopy(items map[string]map[string]interface{}, idKey string,
+	id string, name string) map[string]interface{} {
+
+	if id == "" {
+		return items[name]
+	}
+	for _, item := range items {
+		if itemId, _ := item[idKey].(string); itemId == id {
+			return item
+		}
+	}
+	return nil
+}
+
+// syncBox syncs a Box given by id or name, then its Secrets
+func (s *vaultSync) syncBox(boxArg string) {
+	var sourceBox map[string]interface{}
+	sourceSecrets := []map[string]interface{}{}
+	err := s.source.do(func() error {
+		params := map[string]interface{}{}
+		params["box_id"] = boxArg
+		var err error
+		if sourceBox, _, err = postVaultAPI("GetBox", params); err != nil {
+			return fmt.Errorf("Unable to get Box - %v", err)
+		}
+		params["box_id"] = sourceBox["box_id"]
+		ids := []string{}
+		_, _, err = ListAllPages("ListSecretIds", params, ListPaging{All: true},
+			func(item interface{}) error {
+				itemMap, _ := item.(map[string]interface{})
+				if secretId, _ := itemMap["secret_id"].(string); secretId != "" {
+					ids = append(ids, secretId)
+				}
+				return nil
+			})
+		if err != nil && err != errListNotFound {
+			return fmt.Errorf("Unable to list the Secrets - %v", err)
+		}
+		for _, secretId := range ids {
+			params["secret_id"] = secretId
+			metadata, _, err := postVaultAPI("GetSecretMetadata", params)
+			if err != nil {
+				return fmt.Errorf("Unable to get Secret %s - %v", secretId, err)
+			}
+			sourceSecrets = append(sourceSecrets, metadata)
+		}
+		return nil
+	})
+	if err != nil {
+		s.report(boxArg, "", syncFailed, err.Error())
+		return
+	}
+	sourceBoxId, _ := sourceBox["box_id"].(string)
+	boxName, _ := sourceBox["name"].(string)
+	boxState := s.state.Boxes[sourceBoxId]
+	if boxState == nil {
+		boxState = &syncBoxState{Secrets: map[string]*syncSecretState{}}
+		if !s.dryRun {
+			s.state.Boxes[sourceBoxId] = boxState
+		}
+	}
+	boxState.Name = boxName
+
+	targetBoxId, ok := s.syncBoxSettings(boxName, sourceBox, boxState)
+	if !ok {
+		return
+	}
+	targetSecrets := map[string]map[string]interface{}{}
+	if targetBoxId != "" {
+		err := s.target.do(func() error {
+			params := map[string]interface{}{}
+			params["box_id"] = targetBoxId
+			var err error
+			targetSecrets, _, err = listByName("ListSecretIds", params)
+			return err
+		})
+		if err != nil {
+			s.report(boxName, "", syncFailed, fmt.Sprintf("Unable to list the target Secrets - %v", err))
+			return
+		}
+	}
+
+	sort.Slice(sourceSecrets, func(i, j int) bool {
+		iName, _ := sourceSecrets[i]["name"].(string)
+		jName, _ := sourceSecrets[j]["name"].(string)
+		return iName < jName
+	})
+	listed := map[string]bool{}
+	for _, metadata := range sourceSecrets {
+		secretId, _ := metadata["secret_id"].(string)
+		listed[secretId] = true
+		s.syncSecret(boxName, targetBoxId, targetSecrets, metadata, boxState)
+	}
+
+	// Secrets synced before and deleted from the source since
+	deleted := []string{}
+	for secretId := range boxState.Secrets {
+		if !listed[secretId] {
+			deleted = append(deleted, secretId)
+		}
+	}
+	sort.Slice(deleted, func(i, j int) bool {
+		return boxState.Secrets[deleted[i]].Name < boxState.Secrets[deleted[j]].Name
+	})
+	for _, secretId := range deleted {
+		secretState := boxState.Secrets[secretId]
+		if s.dryRun || s.keepDeleted {
+			s.report(boxName, secretState.Name, syncRemoved, "deleted from the source")
+			continue
+		}
+		err := s.target.do(func() error {
+			if findCopy(targetSecrets, "secret_id", secretState.TargetSecretId, "") == nil {
+				return nil
+			}
+			params := map[string]interface{}{}
+			params["box_id"] = targetBoxId
+			params["secret_id"] = secretState.TargetSecretId
+			_, _, err := postVaultAPI("DeleteSecret", params)
+			return err
+		})
+		if err != nil {
+			s.report(boxName, secretState.Name, syncFailed, fmt.Sprintf("Unable to delete - %v", err))
+			continue
+		}
+		delete(boxState.Secrets, secretId)
+		s.report(boxName, secretState.Name, syncDeleted, "")
+	}
+	boxState.SyncedAt = time.Now().UTC().Format(time.RFC3339)
+}
+
+// syncBoxSettings finds or creates the copy of a Box and updates its
+// settings. Returns the id of the copy.
+func (s *vaultSync) syncBoxSettings(boxName string, sourceBox map[string]interface{},
+	boxState *syncBoxState) (string, bool) {
+
+	desired := syncedBoxSettings(sourceBox)
+	targetBoxId := ""
+	err := s.target.do(func() error {
+		boxes, _, err := listByName("ListBoxIds", map[string]interface{}{})
+		if err != nil {
+			return fmt.Errorf("Unable to list the target Boxes - %v", err)
+		}
+		targetBox := findCopy(boxes, "box_id", boxState.TargetBoxId, boxName)
+		if targetBox != nil {
+			params := map[string]interface{}{}
+			params["box_id"] = targetBox["box_id"]
+			if targetBox, _, err = postVaultAPI("GetBox", params); err != nil {
+				return fmt.Errorf("Unable to get the target Box - %v", err)
+			}
+		}
+
+		if targetBox == nil {
+			if s.dryRun {
+				s.report(boxName, "", syncMissing, "")
+				return nil
+			}
+			params := map[string]interface{}{}
+			for key, value := range desired {
+				params[key] = value
+			}
+			params["name"] = boxName
+			created, _, err := postVaultAPI("CreateBox", params)
+			if err != nil {
+				return fmt.Errorf("Unable to create the target Box - %v", err)
+			}
+			targetBoxId, _ = created["box_id"].(string)
+			if targetBoxId == "" {
+				return fmt.Errorf("no box_id in the CreateBox response")
+			}
+			boxState.TargetBoxId = targetBoxId
+			s.report(boxName, "", syncCreated, "")
+			return nil
+		}
+
+		targetBoxId, _ = targetBox["box_id"].(string)
+		boxState.TargetBoxId = targetBoxId
+		changed := changedSettings(desired, targetBox)
+		if len(changed) == 0 {
+			return nil
+		}
+		detail := "settings: " + strings.Join(changed, ", ")
+		if s.dryRun {
+			s.report(boxName, "", syncChanged, detail)
+			return nil
+		}
+		ids := map[string]interface{}{"box_id": targetBoxId}
+		if _, _, err := postVaultAPI("UpdateBox",
+			updateRequest(ids, targetBox["revision"], desired, changed)); err != nil {
+			return fmt.Errorf("Unable to update the target Box - %v", err)
+		}
+		s.report(boxName, "", syncUpdated, detail)
+		return nil
+	})
+	if err != nil {
+		s.report(boxName, "", syncFailed, err.Error())
+		return "", false
+	}
+	return targetBoxId, true
+}
+
+// syncSecret brings the copy of a Secret in line with the source one
+func (s *vaultSync) syncSecret(boxName string, targetBoxId string,
+	targetSecrets map[string]map[string]interface{},
+	metadata map[string]interface{}, boxState *syncBoxState) {
+
+	sourceBoxId, _ := metadata["box_id"].(string)
+	secretId, _ := metadata["secret_id"].(string)
+	name, _ := metadata["name"].(string)
+	sourceStamp := newSyncStamp(metadata)
+	secretState := boxState.Secrets[secretId]
+
+	targetSecretId := ""
+	if secretState != nil {
+		targetSecretId = secretState.TargetSecretId
+	}
+	var targetMetadata map[string]interface{}
+	if listed := findCopy(targetSecrets, "secret_id", targetSecretId, name); listed != nil {
+		err := s.target.do(func() error {
+			params := map[string]interface{}{}
+			params["box_id"] = targetBoxId
+			params["secret_id"] = listed["secret_id"]
+			var err error
+			targetMetadata, _, err = postVaultAPI("GetSecretMetadata", params)
+			return err
+		})
+		if err != nil {
+			s.report(boxName, name, syncFailed, fmt.Sprintf("Unable to get the copy - %v", err))
+			return
+		}
+	}
+
+	if targetMetadata == nil {
+		status, detail := syncCreated, ""
+		if secretState != nil {
+			status, detail = syncRecreated, "deleted from the target"
+		}
+		if s.dryRun {
+			s.report(boxName, name, syncMissing, detail)
+			return
+		}
+		_, targetSecretId, _, err := copySecret(secretCopy{
+			Source:    s.source,
+			Target:    s.target,
+			BoxId:     sourceBoxId,
+			SecretId:  secretId,
+			TargetBox: targetBoxId,
+		})
+		if err != nil {
+			s.report(boxName, name, syncFailed, err.Error())
+			return
+		}
+		s.checkpoint(boxState, secretId, name, sourceStamp, targetBoxId, targetSecretId)
+		s.report(boxName, name, status, detail)
+		return
+	}
+
+	targetSecretId, _ = targetMetadata["secret_id"].(string)
+	targetStamp := newSyncStamp(targetMetadata)
+	if secretState != nil && secretState.Source == sourceStamp && secretState.Target == targetStamp {
+		s.inSync++
+		return
+	}
+
+	details := []string{}
+	if secretState != nil && secretState.Target != targetStamp {
+		details = append(details, "target drifted")
+	}
+	valueDiffers, err := s.valuesDiffer(sourceBoxId, secretId, sourceStamp.Version,
+		targetBoxId, targetSecretId, targetStamp.Version)
+	if err != nil {
+		s.report(boxName, name, syncFailed, err.Error())
+		return
+	}
+	if valueDiffers {
+		details = append(details, "value")
+	}
+	desired := syncedSecretSettings(metadata)
+	changed := changedSettings(desired, syncedSecretSettings(targetMetadata))
+	if len(changed) != 0 {
+		details = append(details, "settings: "+strings.Join(changed, ", "))
+	}
+
+	if !valueDiffers && len(changed) == 0 {
+		if !s.dryRun {
+			s.checkpoint(boxState, secretId, name, sourceStamp, targetBoxId, targetSecretId)
+		}
+		s.inSync++
+		return
+	}
+	if s.dryRun {
+		s.report(boxName, name, syncChanged, strings.Join(details, "; "))
+		return
+	}
+
+	err = s.update(sourceBoxId, secretId, sourceStamp.Version, targetBoxId, targetSecretId,
+		valueDiffers, desired, changed)
+	if err != nil {
+		s.report(boxName, name, syncFailed, err.Error())
+		return
+	}
+	s.checkpoint(boxState, secretId, name, sourceStamp, targetBoxId, targetSecretId)
+	s.report(boxName, name, syncUpdated, strings.Join(details, "; "))
+}
+
+// valuesDiffer compares the current versions of a Secret and its copy
+func (s *vaultSync) valuesDiffer(sourceBoxId string, secretId string, sourceVersion int,
+	targetBoxId string, targetSecretId string, targetVersion int) (bool, error) {
+
+	var sourceData, targetData secretVersion
+	err := s.source.do(func() error {
+		var err error
+		sourceData, err = fetchSecretVersion(sourceBoxId, secretId, sourceVersion)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	defer sourceData.Spool.Close()
+	err = s.target.do(func() error {
+		var err error
+		targetData, err = fetchSecretVersion(targetBoxId, targetSecretId, targetVersion)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	defer targetData.Spool.Close()
+	return sourceData.isFile() != targetData.isFile() || sourceData.Sum != targetData.Sum ||
+		!reflect.DeepEqual(sourceData.Data, targetData.Data), nil
+}
+
+// update adds the current source value to the copy as a new version, then
+// updates the settings which changed
+func (s *vaultSync) update(sourceBoxId string, secretId string, sourceVersion int,
+	targetBoxId string, targetSecretId string,
+	value bool, desired map[string]interface{}, changed []string) error {
+
+	if value {
+		var data secretVersion
+		err := s.source.do(func() error {
+			var err error
+			data, err = fetchSecretVersion(sourceBoxId, secretId, sourceVersion)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		defer data.Spool.Close()
+		err = s.target.do(func() error {
+			params := map[string]interface{}{}
+			params["box_id"] = targetBoxId
+			params["secret_id"] = targetSecretId
+			_, err := storeSecretVersion(false, params, nil, data)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("Unable to put the new value - %v", err)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	return s.target.do(func() error {
+		ids := map[string]interface{}{}
+		ids["box_id"] = targetBoxId
+		ids["secret_id"] = targetSecretId
+		current, _, err := postVaultAPI("GetSecretMetadata", ids)
+		if err != nil {
+			return err
+		}
+		if _, _, err := postVaultAPI("UpdateSecret",
+			updateRequest(ids, current["revision"], desired, changed)); err != nil {
+			return fmt.Errorf("Unable to update the settings - %v", err)
+		}
+		return nil
+	})
+}
+
+// checkpoint records both copies of a Secret as synced, reading back the
+// copy changed by the sync
+func (s *vaultSync) checkpoint(boxState *syncBoxState, secretId string, name string,
+	sourceStamp syncStamp, targetBoxId string, targetSecretId string) {
+
+	secretState := &syncSecretState{Name: name, TargetSecretId: targetSecretId, Source: sourceStamp}
+	s.target.do(func() error {
+		params := map[string]interface{}{}
+		params["box_id"] = targetBoxId
+		params["secret_id"] = targetSecretId
+		if metadata, _, err := postVaultAPI("GetSecretMetadata", params); err == nil {
+			secretState.Target = newSyncStamp(metadata)
+		}
+		return nil
+	})
+	boxState.Secrets[secretId] = secretState
+}
+
+// run syncs the Boxes once and saves the checkpoint
+func (s *vaultSync) run(boxes []string, statePath string) error {
+	s.changes, s.failed, s.inSync = []syncChange{}, false, 0
+	for _, box := range boxes {
+		s.syncBox(box)
+	}
+	if s.dryRun {
+		return nil
+	}
+	return saveSyncState(statePath, s.state)
+}
+
+// printSyncReport prints the drift report of a run
+func printSyncReport(cmd *cobra.Command, s *vaultSync) {
+	format := GetOutputFormat(cmd)
+	if format == "" {
+		format = OutputFormatTable
+	}
+	now := time.Now().Format(time.RFC3339)
+	response := map[string]interface{}{"drift": s.changes}
+	if format == OutputFormatTable {
+		if len(s.changes) == 0 {
+			fmt.Printf("%s  No drift, %d Secrets in sync\n", now, s.inSync)
+			return
+		}
+		fmt.Printf("%s  %d changes, %d Secrets in sync\n", now, len(s.changes), s.inSync)
+	} else {
+		response["synced_at"] = now
+		response["in_sync"] = s.inSync
+	}
+	report, err := JSONMarshalIndent(response)
+	if err != nil {
+		fmt.Println("Error building JSON output: ", err)
+		os.Exit(4)
+	}
+	if err := PrintFormatted(os.Stdout, format, "drift", strings.TrimSpace(string(report))); err != nil {
+		fmt.Printf("\nError formatting output - %v\n", err)
+		os.Exit(4)
+	}
+}
+
+// syncCmd represents the sync command
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Mirror Boxes from one Vault to another",
+	Long: `Mirror the Boxes given with --box, and their Secrets, from the Vault of the
+--from profile to the Vault of the --to profile. A profile is a token file
+written by login --token-file, given by path or as the NAME of
+pasmcli.data/NAME_token.txt under your home or profile directory.
+
+The Box and its Secrets are created on the target Vault when missing. New
+Secrets are copied with their type, settings, tags and current value. When
+the current version or the metadata of a Secret changes on either Vault,
+the current value is put as a new version of the copy if it differs, and
+the changed settings and tags are updated. Secrets deleted from the source
+are deleted from the target, unless --keep-deleted is given. Changes made to
+the copies on the target Vault are overwritten.
+
+The report lists what was created, updated, deleted or failed. With
+--dry-run, nothing is changed and the report lists the Secrets missing,
+changed or removed.
+
+Sync is idempotent: the versions and timestamps of the synced Secrets are
+kept in a checkpoint file, and Secrets unchanged since are skipped. A lock
+file next to the checkpoint stops concurrent runs, e.g. from cron. With
+--interval, the sync runs again after each interval until interrupted.
+
+Exit codes: 0 when synced, 3 when a Box or Secret failed, 4 when the
+checkpoint or lock file cannot be used.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+		from, _ := flags.GetString("from")
+		to, _ := flags.GetString("to")
+		boxes, _ := flags.GetStringArray("box")
+		interval, _ := flags.GetDuration("interval")
+		statePath, _ := flags.GetString("state")
+		dryRun, _ := flags.GetBool("dry-run")
+		keepDeleted, _ := flags.GetBool("keep-deleted")
+		if len(boxes) == 0 {
+			fmt.Printf("\nGive the Boxes to sync with --box\n\n")
+			os.Exit(1)
+		}
+		if interval < 0 {
+			fmt.Printf("\nInvalid --interval %v\n\n", interval)
+			os.Exit(1)
+		}
+
+		sessions := []vaultSession{}
+		for _, profile := range []string{from, to} {
+			tokenFile, err := resolveSyncTokenFile(profile)
+			if err == nil {
+				var token tokenInfo
+				if token, err = loadTokenInfo(tokenFile); err == nil {
+					sessions = append(sessions, vaultSession{token: token})
+				}
+			}
+			if err != nil {
+				fmt.Printf("\nError getting Server information of profile %s. %v\n\n", profile, err)
+				os.Exit(1)
+			}
+		}
+		source, target := sessions[0], sessions[1]
+		if source.token.Server == target.token.Server {
+			fmt.Printf("\nProfiles %s and %s are the same Vault\n\n", from, to)
+			os.Exit(1)
+		}
+
+		if statePath == "" {
+			dataDir, err := GetDataDir()
+			if err != nil {
+				fmt.Printf("\nUnable to find the pasmcli data directory - %v\n\n", err)
+				os.Exit(4)
+			}
+			sum := sha256.Sum256([]byte(source.token.Server + "\n" + target.token.Server))
+			statePath = filepath.Join(dataDir, "sync_"+hex.EncodeToString(sum[:6])+".json")
+		}
+		unlock, err := lockSync(statePath)
+		if err != nil {
+			fmt.Printf("\n%v\n\n", err)
+			os.Exit(4)
+		}
+		interrupted := make(chan os.Signal, 1)
+		signal.Notify(interrupted, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-interrupted
+			unlock()
+			os.Exit(130)
+		}()
+
+		state, err := loadSyncState(statePath, source.token.Server, target.token.Server)
+		if err != nil {
+			unlock()
+			fmt.Printf("\n%v\n\n", err)
+			os.Exit(4)
+		}
+		s := &vaultSync{source: source, target: target, state: state,
+			dryRun: dryRun, keepDeleted: keepDeleted}
+		for {
+			if err := s.run(boxes, statePath); err != nil {
+				unlock()
+				fmt.Printf("\nUnable to save the sync state %s - %v\n\n", statePath, err)
+				os.Exit(4)
+			}
+			printSyncReport(cmd, s)
+			if interval == 0 {
+				break
+			}
+			time.Sleep(interval)
+		}
+		unlock()
+		if s.failed {
+			os.Exit(3)
+		}
+		os.Exit(0)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+	syncCmd.Flags().String("from", "",
+		"Profile of the source Vault, a token file or the NAME of pasmcli.data/NAME_token.txt")
+	syncCmd.MarkFlagRequired("from")
+	syncCmd.Flags().String("to", "",
+		"Profile of the target Vault, a token file or the NAME of pasmcli.data/NAME_token.txt")
+	syncCmd.MarkFlagRequired("to")
+	syncCmd.Flags().StringArrayP("box", "b", []string{},
+		"Box to sync, given by id or name on the source Vault. This option is repeatable.")
+	syncCmd.Flags().Duration("interval", 0,
+		"Sync again after this interval, e.g. 5m, until interrupted. Default is to sync once.")
+	syncCmd.Flags().String("state", "",
+		"Checkpoint file. Default is a sync_*.json file for the two Vaults in pasmcli.data/")
+	syncCmd.Flags().Bool("dry-run", false,
+		"Report the drift without changing the target Vault")
+	syncCmd.Flags().Bool("keep-deleted", false,
+		"Do not delete from the target the Secrets deleted from the source")
+}
@@ -96,7 +96,7 @@ var tagBoxCmd = &cobra.Command{
                 fmt.Println("\nTagging successful\n")
                 os.Exit(0)
             } else {
-                fmt.Println("\n" + retStr + "\n")
+                PrintResponse(cmd, retStr)
                 // make a decision on what to exit with
                 retMap := JsonStrToMap(retStr)
                 if _, present := retMap["error"]; present {
@@ -100,7 +100,7 @@ var tagSecretCmd = &cobra.Command{
                 fmt.Println("\nTagging successful\n")
                 os.Exit(0)
             } else {
-                fmt.Println("\n" + retStr + "\n")
+                PrintResponse(cmd, retStr)
                 // make a decision on what to exit with
                 retMap := JsonStrToMap(retStr)
                 if _, present := retMap["error"]; present {
@@ -116,6 +116,7 @@ var tagSecretCmd = &cobra.Command{
 
 func init() {
     rootCmd.AddCommand(tagSecretCmd)
+    acceptSecretRef(tagSecretCmd)
     tagSecretCmd.Flags().StringP("boxid", "b", "",
                                 "Id or name of the Box under which the " +
                                 "Secret is")
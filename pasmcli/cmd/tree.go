@@ -0,0 +1,379 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+// treeVersion is a Secret version node of the tree
+type treeVersion struct {
+	Version   int    `json:"version"`
+	CreatedAt string `json:"created_at,omitempty"`
+	Current   bool   `json:"current"`
+}
+
+// treeSecret is a Secret node of the tree
+type treeSecret struct {
+	SecretId          string        `json:"secret_id"`
+	Name              string        `json:"name"`
+	Type              string        `json:"type"`
+	ExpiresAt         string        `json:"expires_at,omitempty"`
+	Rotation          string        `json:"rotation,omitempty"`
+	ExclusiveCheckout interface{}   `json:"exclusive_checkout"`
+	Leases            int           `json:"leases"`
+	Versions          []treeVersion `json:"versions,omitempty"`
+}
+
+// treeBox is a Box node of the tree
+type treeBox struct {
+	BoxId             string       `json:"box_id"`
+	Name              string       `json:"name"`
+	Rotation          string       `json:"rotation,omitempty"`
+	ExclusiveCheckout interface{}  `json:"exclusive_checkout"`
+	Leases            int          `json:"leases"`
+	Secrets           []treeSecret `json:"secrets"`
+}
+
+// rotationSchedule describes the rotation settings of a Box or Secret
+func rotationSchedule(item map[string]interface{}) string {
+	rotation, _ := item["rotation"].(map[string]interface{})
+	duration, _ := rotation["duration"].(string)
+	return duration
+}
+
+// treeAnnotations returns the bracketed annotations of a tree node
+func treeAnnotations(annotations ...string) string {
+	present := []string{}
+	for _, annotation := range annotations {
+		if annotation != "" {
+			present = append(present, annotation)
+		}
+	}
+	if len(present) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(present, ", ") + "]"
+}
+
+// treeLeases describes an active lease count, nothing when there's none
+func treeLeases(count int) string {
+	switch count {
+	case 0:
+		return ""
+	case 1:
+		return "1 lease"
+	}
+	return fmt.Sprintf("%d leases", count)
+}
+
+// treeExclusiveCheckout describes the exclusive checkout flag, nothing
+// when it's unset or disabled
+func treeExclusiveCheckout(exclusiveCheckout interface{}) string {
+	if enabled, _ := exclusiveCheckout.(bool); enabled {
+		return "exclusive checkout"
+	}
+	return ""
+}
+
+// runConcurrently calls work for each index below count, from at most
+// concurrency goroutines, and returns when all calls have returned
+func runConcurrently(count int, concurrency int, work func(index int)) {
+	indexes := make(chan int)
+	var workers sync.WaitGroup
+	for worker := 0; worker < concurrency; worker += 1 {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for index := range indexes {
+				work(index)
+			}
+		}()
+	}
+	for index := 0; index < count; index += 1 {
+		indexes <- index
+	}
+	close(indexes)
+	workers.Wait()
+}
+
+// printTree renders the Boxes as an indented tree
+func printTree(out io.Writer, boxes []treeBox) {
+	for _, box := range boxes {
+		rotation := ""
+		if box.Rotation != "" {
+			rotation = "rotation " + box.Rotation
+		}
+		fmt.Fprintf(out, "%s (%s)%s\n", box.Name, box.BoxId, treeAnnotations(
+			treeExclusiveCheckout(box.ExclusiveCheckout), rotation, treeLeases(box.Leases)))
+
+		for secretIndex, secret := range box.Secrets {
+			branch, indent := "├── ", "│   "
+			if secretIndex == len(box.Secrets)-1 {
+				branch, indent = "└── ", "    "
+			}
+			expires, rotation := "", ""
+			if secret.ExpiresAt != "" {
+				expires = "expires " + secret.ExpiresAt
+			}
+			if secret.Rotation != "" {
+				rotation = "rotation " + secret.Rotation
+			}
+			fmt.Fprintf(out, "%s%s (%s)%s\n", branch, secret.Name, secret.SecretId,
+				treeAnnotations(secret.Type, expires, rotation,
+					treeExclusiveCheckout(secret.ExclusiveCheckout), treeLeases(secret.Leases)))
+
+			for versionIndex, version := range secret.Versions {
+				versionBranch := "├── "
+				if versionIndex == len(secret.Versions)-1 {
+					versionBranch = "└── "
+				}
+				current := ""
+				if version.Current {
+					current = "current"
+				}
+				fmt.Fprintf(out, "%s%sv%d%s\n", indent, versionBranch, version.Version,
+					treeAnnotations(version.CreatedAt, current))
+			}
+		}
+	}
+}
+
+// treeCmd represents the tree command
+var treeCmd = &cobra.Command{
+	Use:   "tree",
+	Short: "Show the Boxes, Secrets and Secret versions as a tree",
+	Long: `Show the Boxes, their Secrets and the versions of each Secret as a tree.
+Boxes are annotated with their exclusive checkout flag, rotation schedule
+and active lease count. Secrets are annotated with their type, expiry,
+rotation schedule, exclusive checkout flag and active lease count.
+
+Boxes and Secrets are fetched concurrently, at most --concurrency requests
+at a time. Use --output json for the tree as a JSON document.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		flags := cmd.Flags()
+		boxFilters, _ := flags.GetStringArray("boxid")
+		tagSelectors, _ := flags.GetStringArray("tag")
+		noVersions, _ := flags.GetBool("no-versions")
+		concurrency, _ := flags.GetInt("concurrency")
+		if concurrency < 1 {
+			fmt.Println("--concurrency must be at least 1")
+			os.Exit(1)
+		}
+
+		selectedBoxes := map[string]bool{}
+		for _, box := range boxFilters {
+			selectedBoxes[box] = true
+		}
+		boxes := []treeBox{}
+		params := map[string]interface{}{}
+		if flags.Changed("prefix") {
+			prefix, _ := flags.GetString("prefix")
+			params["prefix"] = prefix
+		}
+		_, _, err := ListAllPages("ListBoxIds", params, ListPaging{All: true},
+			func(item interface{}) error {
+				box, _ := item.(map[string]interface{})
+				boxId, _ := box["box_id"].(string)
+				boxName, _ := box["name"].(string)
+				if len(boxFilters) == 0 || selectedBoxes[boxId] || selectedBoxes[boxName] {
+					boxes = append(boxes, treeBox{BoxId: boxId, Name: boxName})
+				}
+				return nil
+			})
+		if err != nil {
+			ExitOnListError(err, "Boxes not found")
+		}
+
+		// active leases, counted per Secret
+		leases := map[string]int{}
+		_, _, err = ListAllPages("ListLeases", map[string]interface{}{}, ListPaging{All: true},
+			func(item interface{}) error {
+				lease, _ := item.(map[string]interface{})
+				boxId, _ := lease["box_id"].(string)
+				secretId, _ := lease["secret_id"].(string)
+				leases[boxId+"/"+secretId] += 1
+				return nil
+			})
+		if err != nil && err != errListNotFound {
+			fmt.Printf("\nUnable to list Leases - %v\n\n", err)
+			os.Exit(3)
+		}
+
+		// Box settings and Secret ids
+		errors := make([]error, len(boxes))
+		runConcurrently(len(boxes), concurrency, func(index int) {
+			box := &boxes[index]
+			params := map[string]interface{}{}
+			params["box_id"] = box.BoxId
+			retMap, _, err := postVaultAPI("GetBox", params)
+			if err != nil {
+				errors[index] = fmt.Errorf("Unable to get Box %s - %v", box.Name, err)
+				return
+			}
+			box.Rotation = rotationSchedule(retMap)
+			box.ExclusiveCheckout = retMap["exclusive_checkout"]
+
+			box.Secrets = []treeSecret{}
+			_, _, err = ListAllPages("ListSecretIds", params, ListPaging{All: true},
+				func(item interface{}) error {
+					secret, _ := item.(map[string]interface{})
+					secretId, _ := secret["secret_id"].(string)
+					name, _ := secret["name"].(string)
+					box.Secrets = append(box.Secrets, treeSecret{SecretId: secretId, Name: name})
+					return nil
+				})
+			if err != nil && err != errListNotFound {
+				errors[index] = fmt.Errorf("Unable to list the Secrets of Box %s - %v", box.Name, err)
+			}
+		})
+		for _, err := range errors {
+			if err != nil {
+				fmt.Printf("\n%v\n\n", err)
+				os.Exit(3)
+			}
+		}
+
+		// Secret metadata and versions
+		secrets := []*treeSecret{}
+		secretBoxes := []string{}
+		for boxIndex := range boxes {
+			for secretIndex := range boxes[boxIndex].Secrets {
+				secrets = append(secrets, &boxes[boxIndex].Secrets[secretIndex])
+				secretBoxes = append(secretBoxes, boxes[boxIndex].BoxId)
+			}
+		}
+		matched := make([]bool, len(secrets))
+		errors = make([]error, len(secrets))
+		runConcurrently(len(secrets), concurrency, func(index int) {
+			secret := secrets[index]
+			params := map[string]interface{}{}
+			params["box_id"] = secretBoxes[index]
+			params["secret_id"] = secret.SecretId
+			metadata, _, err := postVaultAPI("GetSecretMetadata", params)
+			if err != nil {
+				errors[index] = fmt.Errorf("Unable to get Secret %s - %v", secret.Name, err)
+				return
+			}
+			tags, _ := metadata["tags"].(map[string]interface{})
+			if matched[index] = matchesTags(tags, tagSelectors); !matched[index] {
+				return
+			}
+			secret.Type = secretType(metadata)
+			secret.ExpiresAt, _ = metadata["expires_at"].(string)
+			secret.Rotation = rotationSchedule(metadata)
+			secret.ExclusiveCheckout = metadata["exclusive_checkout"]
+			secret.Leases = leases[secretBoxes[index]+"/"+secret.SecretId]
+			if noVersions {
+				return
+			}
+
+			retMap, _, err := postVaultAPI("ListSecretVersions", params)
+			if err != nil {
+				errors[index] = fmt.Errorf("Unable to list versions of Secret %s - %v",
+					secret.Name, err)
+				return
+			}
+			current, _ := metadata["current_version"].(float64)
+			items, _ := retMap["versions"].([]interface{})
+			for _, item := range items {
+				itemMap, _ := item.(map[string]interface{})
+				version, isNumber := itemMap["version"].(float64)
+				if !isNumber {
+					continue
+				}
+				createdAt, _ := itemMap["created_at"].(string)
+				secret.Versions = append(secret.Versions, treeVersion{
+					Version:   int(version),
+					CreatedAt: createdAt,
+					Current:   version == current,
+				})
+			}
+			sort.Slice(secret.Versions, func(i, j int) bool {
+				return secret.Versions[i].Version < secret.Versions[j].Version
+			})
+		})
+		for _, err := range errors {
+			if err != nil {
+				fmt.Printf("\n%v\n\n", err)
+				os.Exit(3)
+			}
+		}
+
+		// leave out the Secrets, and with --tag the Boxes, not selected
+		secretIndex := 0
+		selected := []treeBox{}
+		for _, box := range boxes {
+			kept := []treeSecret{}
+			for _, secret := range box.Secrets {
+				if matched[secretIndex] {
+					kept = append(kept, secret)
+					box.Leases += secret.Leases
+				}
+				secretIndex += 1
+			}
+			box.Secrets = kept
+			if len(tagSelectors) == 0 || len(kept) != 0 {
+				selected = append(selected, box)
+			}
+		}
+		sort.Slice(selected, func(i, j int) bool {
+			return selected[i].Name < selected[j].Name
+		})
+		for _, box := range selected {
+			sort.Slice(box.Secrets, func(i, j int) bool {
+				return box.Secrets[i].Name < box.Secrets[j].Name
+			})
+		}
+
+		if format := GetOutputFormat(cmd); format != "" {
+			report, err := JSONMarshalIndent(map[string]interface{}{"boxes": selected})
+			if err != nil {
+				fmt.Println("Error building JSON output: ", err)
+				os.Exit(4)
+			}
+			PrintResponse(cmd, strings.TrimSpace(string(report)))
+		} else {
+			fmt.Println()
+			printTree(os.Stdout, selected)
+			fmt.Println()
+		}
+		os.Exit(0)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(treeCmd)
+	treeCmd.Flags().StringArrayP("boxid", "b", []string{},
+		"Show only this Box, given by id or name. This option is repeatable.")
+	treeCmd.Flags().StringP("prefix", "p", "",
+		"Show only the Boxes whose name starts with this string")
+	treeCmd.Flags().StringArrayP("tag", "t", []string{},
+		"Show only the Secrets with this tag, given as KEY or KEY=VALUE. "+
+			"This option is repeatable, all the tags must match.")
+	treeCmd.Flags().Bool("no-versions", false,
+		"Leave Secret versions out of the tree")
+	treeCmd.Flags().Int("concurrency", 4,
+		"Number of requests made at the same time")
+}
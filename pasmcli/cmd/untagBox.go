@@ -82,7 +82,7 @@ var untagBoxCmd = &cobra.Command{
                 fmt.Println("\nUntag successful\n")
                 os.Exit(0)
             } else {
-                fmt.Println("\n" + retStr + "\n")
+                PrintResponse(cmd, retStr)
                 // make a decision on what to exit with
                 retMap := JsonStrToMap(retStr)
                 if _, present := retMap["error"]; present {
@@ -86,7 +86,7 @@ var untagSecretCmd = &cobra.Command{
                 fmt.Println("\nUntag successful\n")
                 os.Exit(0)
             } else {
-                fmt.Println("\n" + retStr + "\n")
+                PrintResponse(cmd, retStr)
                 // make a decision on what to exit with
                 retMap := JsonStrToMap(retStr)
                 if _, present := retMap["error"]; present {
@@ -102,6 +102,7 @@ var untagSecretCmd = &cobra.Command{
 
 func init() {
     rootCmd.AddCommand(untagSecretCmd)
+    acceptSecretRef(untagSecretCmd)
     untagSecretCmd.Flags().StringP("boxid", "b", "",
                                 "Id or name of the Box under which the " +
                                 "Secret is")
@@ -249,7 +249,7 @@ var updateSecretCmd = &cobra.Command{
                 os.Exit(5)
             }
 
-            fmt.Println("\n" + retStr + "\n")
+            PrintResponse(cmd, retStr)
 
             // make a decision on what to exit with
             retMap := JsonStrToMap(retStr)
@@ -264,6 +264,7 @@ var updateSecretCmd = &cobra.Command{
 
 func init() {
     rootCmd.AddCommand(updateSecretCmd)
+    acceptSecretRef(updateSecretCmd)
     updateSecretCmd.Flags().StringP("boxid", "b", "",
                                     "Id or name of the Box")
     updateSecretCmd.Flags().StringP("secretid", "s", "",
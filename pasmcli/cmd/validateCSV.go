@@ -0,0 +1,380 @@
+/*
+ Copyright 2020-2025 Entrust Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+https://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// secret types of import-csv
+const (
+	csvSecretTypeESXi   = "esxi"
+	csvSecretTypeStatic = "static"
+	csvSecretTypeSSHKey = "SSH key endpoint"
+)
+
+// csvSchema is the column layout of the sample CSV of a secret type
+type csvSchema struct {
+	Columns  []string
+	Required []string // columns which need a value on every line
+}
+
+// csvRequiredColumns are the columns of the sample CSV of each secret type
+// which need a value on every line, when the sample has them. The columns
+// themselves come from the sample.
+var csvRequiredColumns = map[string][]string{
+	csvSecretTypeESXi:   {"box_name", "secret_name"},
+	csvSecretTypeStatic: {"box_name", "secret_name"},
+	csvSecretTypeSSHKey: {"box_name", "secret_name", "host", "user", "keyfile"},
+}
+
+// iso8601DurationRegexp matches durations like P30D, PT12H or P1Y2M3DT4H5M6S
+var iso8601DurationRegexp = regexp.MustCompile(
+	`^P(\d+Y)?(\d+M)?(\d+W)?(\d+D)?(T(\d+H)?(\d+M)?(\d+(\.\d+)?S)?)?$`)
+
+// csvProblem is an error found in a CSV file, at a line of it
+type csvProblem struct {
+	Line   int    `json:"line"`
+	Column string `json:"column,omitempty"`
+	Error  string `json:"error"`
+}
+
+// isISO8601Duration tells if value is an ISO 8601 duration
+func isISO8601Duration(value string) bool {
+	return iso8601DurationRegexp.MatchString(value) && value != "P" &&
+		!strings.HasSuffix(value, "T")
+}
+
+// checkCSVValue returns why value is not valid for the column, or ""
+func checkCSVValue(column string, value string) string {
+	switch strings.ReplaceAll(strings.ToLower(column), "_", "-") {
+	case "lease-duration", "rotation-duration", "secret-duration":
+		if !isISO8601Duration(value) {
+			return "not an ISO 8601 duration, e.g. P30D or PT12H"
+		}
+	case "expires-at":
+		if value == "never" {
+			return ""
+		}
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			if _, err := time.Parse("2006-01-02", value); err != nil {
+				return "not an RFC 3339 date, e.g. 2026-12-31T00:00:00Z"
+			}
+		}
+	case "exclusive-checkout", "rotation-on-checkin", "rotation-force", "lease-renewable":
+		switch strings.ToLower(value) {
+		case "enable", "disable":
+			return ""
+		}
+		if _, err := strconv.ParseBool(value); err != nil {
+			return "not a boolean, e.g. true, false, enable or disable"
+		}
+	case "port":
+		if port, err := strconv.Atoi(value); err != nil || port < 1 || port > 65535 {
+			return "not a port number"
+		}
+	case "keyfile", "key-file", "private-key":
+		// the private key itself, or the path of a file holding it
+		if strings.HasPrefix(value, "-----BEGIN") {
+			return ""
+		}
+		file, err := os.Open(value)
+		if err == nil {
+			_, err = file.Read(make([]byte, 1))
+			file.Close()
+		}
+		if err != nil {
+			return fmt.Sprintf("key file not readable - %v", err)
+		}
+	}
+	return ""
+}
+
+// parseCSVSampleSchema reads the header of a sample CSV, keeping the
+// required columns of the secret type it has
+func parseCSVSampleSchema(sample io.Reader, required []string) (csvSchema, error) {
+	header, err := csv.NewReader(sample).Read()
+	if err != nil {
+		return csvSchema{}, err
+	}
+	schema := csvSchema{Columns: []string{}, Required: []string{}}
+	present := map[string]bool{}
+	for _, column := range header {
+		column = strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))
+		schema.Columns = append(schema.Columns, column)
+		present[column] = true
+	}
+	for _, column := range required {
+		if present[column] {
+			schema.Required = append(schema.Required, column)
+		}
+	}
+	return schema, nil
+}
+
+// loadCSVSchema returns the layout of the sample CSV of a secret type, read
+// from the sample file when given, or else downloaded from the Vault as
+// download-sample-csv does
+func loadCSVSchema(secretType string, sample string) (csvSchema, error) {
+	required := csvRequiredColumns[secretType]
+	if sample != "" {
+		file, err := os.Open(sample)
+		if err != nil {
+			return csvSchema{}, err
+		}
+		defer file.Close()
+		schema, err := parseCSVSampleSchema(file, required)
+		if err != nil {
+			return csvSchema{}, fmt.Errorf("Unable to read the header of %s - %v", sample, err)
+		}
+		return schema, nil
+	}
+
+	endpoint := GetEndPoint2("", "1.0",
+		"GetSampleCSV/?secret_type="+strings.ReplaceAll(secretType, " ", "%20"))
+	content, err := DoGetRaw(endpoint, GetCACertFile(), AuthTokenKV())
+	if err != nil {
+		return csvSchema{}, fmt.Errorf("Unable to download the sample CSV of %s - %v", secretType, err)
+	}
+	schema, err := parseCSVSampleSchema(bytes.NewReader(content), required)
+	if err != nil {
+		return csvSchema{}, fmt.Errorf("Unable to read the header of the sample CSV of %s - %v", secretType, err)
+	}
+	return schema, nil
+}
+
+// validateCSVFile checks a CSV file against the schema, without sending
+// anything to the Vault. Returns the problems found and the number of
+// Secrets the file holds.
+func validateCSVFile(path string, schema csvSchema) ([]csvProblem, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	problems := []csvProblem{}
+	header, err := reader.Read()
+	if err == io.EOF {
+		return append(problems, csvProblem{Line: 1, Error: "empty file, expected a header"}), 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// header
+	known := map[string]bool{}
+	for _, column := range schema.Columns {
+		known[column] = true
+	}
+	index := map[string]int{}
+	for position, column := range header {
+		column = strings.TrimSpace(column)
+		if position == 0 {
+			column = strings.TrimPrefix(column, "\ufeff")
+		}
+		header[position] = column
+		if _, duplicate := index[column]; duplicate {
+			problems = append(problems, csvProblem{Line: 1, Column: column, Error: "duplicate column"})
+			continue
+		}
+		index[column] = position
+		if !known[column] {
+			problems = append(problems, csvProblem{Line: 1, Column: column,
+				Error: "unknown column, expected one of " + strings.Join(schema.Columns, ", ")})
+		}
+	}
+	required := map[string]bool{}
+	for _, column := range schema.Required {
+		required[column] = true
+		if _, present := index[column]; !present {
+			problems = append(problems, csvProblem{Line: 1, Column: column, Error: "missing required column"})
+		}
+	}
+
+	// Secrets
+	secrets := 0
+	names := map[string]int{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			line := 0
+			if parseErr, isParseErr := err.(*csv.ParseError); isParseErr {
+				line, err = parseErr.StartLine, parseErr.Err
+			}
+			problems = append(problems, csvProblem{Line: line, Error: err.Error()})
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		secrets++
+		if len(record) != len(header) {
+			problems = append(problems, csvProblem{Line: line,
+				Error: fmt.Sprintf("%d fields, the header has %d", len(record), len(header))})
+		}
+		for position, column := range header {
+			if !known[column] || index[column] != position {
+				continue
+			}
+			value := ""
+			if position < len(record) {
+				value = strings.TrimSpace(record[position])
+			}
+			if value == "" {
+				if required[column] {
+					problems = append(problems, csvProblem{Line: line, Column: column, Error: "missing value"})
+				}
+				continue
+			}
+			if reason := checkCSVValue(column, value); reason != "" {
+				problems = append(problems, csvProblem{Line: line, Column: column, Error: reason})
+			}
+		}
+
+		boxPosition, hasBox := index["box_name"]
+		namePosition, hasName := index["secret_name"]
+		if hasBox && hasName && boxPosition < len(record) && namePosition < len(record) {
+			name := strings.TrimSpace(record[boxPosition]) + "/" + strings.TrimSpace(record[namePosition])
+			if first, duplicate := names[name]; duplicate {
+				problems = append(problems, csvProblem{Line: line, Column: "secret_name",
+					Error: fmt.Sprintf("duplicate Secret %s, first on line %d", name, first)})
+			} else if name != "/" {
+				names[name] = line
+			}
+		}
+	}
+	return problems, secrets, nil
+}
+
+// runCSVValidation validates the csv_file flag of cmd for its secret_type
+// flag, prints the problems and exits with 1 when there are any
+func runCSVValidation(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	csvFile, _ := flags.GetString("csv_file")
+	secretType, _ := flags.GetString("secret_type")
+	sample, _ := flags.GetString("sample")
+
+	if _, supported := csvRequiredColumns[secretType]; !supported {
+		fmt.Printf("\nInvalid secret type %q. Supported: %s, %s, %s\n\n", secretType,
+			csvSecretTypeESXi, csvSecretTypeStatic, csvSecretTypeSSHKey)
+		os.Exit(1)
+	}
+	schema, err := loadCSVSchema(secretType, sample)
+	if err != nil {
+		fmt.Printf("\n%v\n\n", err)
+		os.Exit(4)
+	}
+
+	plainFile, cleanup, err := plainCSVFile(flags, csvFile)
+	if err != nil {
+		fmt.Printf("\n%v\n\n", err)
+		os.Exit(1)
+	}
+	problems, secrets, err := validateCSVFile(plainFile, schema)
+	cleanup()
+	if err != nil {
+		fmt.Printf("\nUnable to read %s - %v\n\n", csvFile, err)
+		os.Exit(4)
+	}
+	if len(problems) == 0 {
+		fmt.Printf("\nCSV file %s is valid: %d Secrets of type %s\n\n", csvFile, secrets, secretType)
+		os.Exit(0)
+	}
+
+	format := GetOutputFormat(cmd)
+	if format == "" {
+		format = OutputFormatTable
+	}
+	report, err := JSONMarshalIndent(map[string]interface{}{"csv_errors": problems})
+	if err != nil {
+		fmt.Println("Error building JSON output: ", err)
+		os.Exit(4)
+	}
+	if err := PrintFormatted(os.Stdout, format, "csv_errors", strings.TrimSpace(string(report))); err != nil {
+		fmt.Printf("\nError formatting output - %v\n", err)
+		os.Exit(4)
+	}
+	if format == OutputFormatTable {
+		fmt.Printf("\n%d problems in CSV file %s, nothing was imported\n\n", len(problems), csvFile)
+	}
+	os.Exit(1)
+}
+
+// validateCSVCmd represents the validate-csv command
+var validateCSVCmd = &cobra.Command{
+	Use:   "validate-csv",
+	Short: "Check a CSV file of Secrets before importing it",
+	Long: `Check a CSV file locally before import-csv sends it to the Vault. The file
+itself is not sent. It is checked against the columns of the sample CSV of
+the --secret_type, downloaded from the Vault as download-sample-csv does,
+or read from the --sample file:
+
+  - the header has the required columns and no unknown ones
+  - required values are set on every line
+  - durations are ISO 8601, e.g. P30D or PT12H
+  - expires_at is an RFC 3339 date
+  - exclusive-checkout and rotation flags are booleans
+  - port is a port number
+  - keyfile is a private key, or the path of a readable file
+  - no Secret name appears twice in the same Box
+
+Errors are reported with their line numbers. Files written by export-csv
+are decrypted with --identity or --passphrase-file.
+
+Exits with 1 when the file has errors.
+
+Examples:
+  pasmcli validate-csv -c secrets.csv -t static
+  pasmcli validate-csv -c servers.csv -t "SSH key endpoint" --sample sample.csv`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runCSVValidation(cmd)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCSVCmd)
+	validateCSVCmd.Flags().StringP("csv_file", "c", "",
+		"CSV file to check")
+	validateCSVCmd.Flags().StringP("secret_type", "t", "",
+		"Secret type corresponding to the secrets in the CSV which can be esxi, static, or SSH key endpoint")
+	validateCSVCmd.Flags().String("sample", "",
+		"Sample CSV file from download-sample-csv to take the columns from, "+
+			"instead of downloading it")
+	addCSVDecryptionFlags(validateCSVCmd)
+
+	// mark mandatory fields as required
+	validateCSVCmd.MarkFlagRequired("csv_file")
+	validateCSVCmd.MarkFlagRequired("secret_type")
+}